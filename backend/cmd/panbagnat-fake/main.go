@@ -0,0 +1,57 @@
+// Command panbagnat-fake serves Pan-Bagnat's user directory from a fixture file
+// so the ADM backend can run without a live Pan-Bagnat core.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"adm-backend/internal/panbagnat/fake"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "listen address")
+	fixturePath := flag.String("fixture", "users.example.yaml", "JSON or YAML fixture listing the users to serve")
+	tokens := flag.String("tokens", "", "comma-separated bearer tokens to accept (any bearer token when empty)")
+	pageSize := flag.Int("page-size", 0, "maximum users per page (defaults to 200)")
+	latency := flag.Duration("latency", 0, "delay added to every answer")
+	errorRate := flag.Float64("error-rate", 0, "probability in [0, 1] of answering with -error-status")
+	errorStatus := flag.Int("error-status", http.StatusServiceUnavailable, "status used for injected errors")
+	flag.Parse()
+
+	fixture, err := fake.LoadFixture(*fixturePath)
+	if err != nil {
+		log.Fatalf("load fixture: %v", err)
+	}
+
+	srv := fake.NewServer(fixture, fake.Options{
+		Tokens:      splitTokens(*tokens),
+		MaxPageSize: *pageSize,
+		Latency:     *latency,
+		ErrorRate:   *errorRate,
+		ErrorStatus: *errorStatus,
+	})
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("[panbagnat-fake] serving %d users from %s on %s", len(fixture.Users), *fixturePath, *addr)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("listen: %v", err)
+	}
+}
+
+func splitTokens(raw string) []string {
+	var tokens []string
+	for _, part := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			tokens = append(tokens, trimmed)
+		}
+	}
+	return tokens
+}
@@ -15,8 +15,13 @@ import (
 
 	"adm-backend/internal/api"
 	"adm-backend/internal/db"
+	"adm-backend/internal/db/migrate"
+	"adm-backend/internal/docgen"
+	"adm-backend/internal/jobs"
 	"adm-backend/internal/panbagnat"
+	"adm-backend/internal/roster"
 	"adm-backend/internal/server"
+	"adm-backend/internal/storage"
 	"adm-backend/internal/store"
 )
 
@@ -37,25 +42,122 @@ func main() {
 	}
 	defer dbConn.Close()
 
+	migrator, err := migrate.New(dbConn)
+	if err != nil {
+		log.Fatalf("load migrations: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(ctx, migrator, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		log.Fatalf("database migration failed: %v", err)
+	}
+	for _, m := range applied {
+		log.Printf("[adm-backend] applied migration %d_%s", m.Version, m.Name)
+	}
+
 	sessionStore := store.NewSessionStore(dbConn)
-	panClient := panbagnat.NewClient(os.Getenv("PAN_BAGNAT_API_BASE_URL"))
-	serviceToken := os.Getenv("PAN_BAGNAT_SERVICE_TOKEN")
-	adminHandler := &api.AdminHandler{
+	studentSessionStore := store.NewStudentSessionStore(dbConn)
+	timelineStore := store.NewTimelineStore(dbConn)
+	categoryStore := store.NewCategoryStore(dbConn)
+	panBagnatConfig := panbagnat.Config{BaseURL: os.Getenv("PAN_BAGNAT_API_BASE_URL")}
+	if v := os.Getenv("PAN_BAGNAT_RATE_LIMIT"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed == 0 {
+			log.Fatalf("invalid PAN_BAGNAT_RATE_LIMIT %q", v)
+		}
+		panBagnatConfig.RateLimit = parsed
+	}
+	rosterSyncer := &roster.Syncer{
 		Sessions:     sessionStore,
-		Client:       panClient,
-		ServiceToken: serviceToken,
+		Client:       panbagnat.New(panBagnatConfig),
+		ServiceToken: os.Getenv("PAN_BAGNAT_SERVICE_TOKEN"),
+	}
+	adminHandler := &api.AdminHandler{
+		Sessions:   sessionStore,
+		Students:   studentSessionStore,
+		Categories: categoryStore,
+		Timeline:   timelineStore,
+		Roster:     rosterSyncer,
+	}
+	storageBackend, err := storage.New(storage.Config{
+		Driver:        os.Getenv("STORAGE_DRIVER"),
+		Root:          envOr("STORAGE_FS_ROOT", "data/uploads"),
+		PublicBaseURL: os.Getenv("STORAGE_PUBLIC_BASE_URL"),
+		SigningSecret: os.Getenv("STORAGE_SIGNING_SECRET"),
+		S3: storage.S3Config{
+			Endpoint:        os.Getenv("STORAGE_S3_ENDPOINT"),
+			Region:          os.Getenv("STORAGE_S3_REGION"),
+			Bucket:          os.Getenv("STORAGE_S3_BUCKET"),
+			AccessKeyID:     os.Getenv("STORAGE_S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("STORAGE_S3_SECRET_ACCESS_KEY"),
+			UsePathStyle:    os.Getenv("STORAGE_S3_PATH_STYLE") == "true",
+		},
+	})
+	if err != nil {
+		log.Fatalf("storage configuration failed: %v", err)
+	}
+
+	adminHandler.Storage = storageBackend
+	adminHandler.Documents = &docgen.Generator{
+		Students: studentSessionStore,
+		Storage:  storageBackend,
+		Issuer:   envOr("DOCUMENT_ISSUER", "42"),
+	}
+
+	studentHandler := &api.StudentHandler{
+		Students:   studentSessionStore,
+		Categories: categoryStore,
+		Timeline:   timelineStore,
+		Storage:    storageBackend,
+	}
+
+	authenticator, err := server.NewAuthenticator(server.AuthConfig{
+		HMACSecret: []byte(os.Getenv("PAN_BAGNAT_JWT_SECRET")),
+		JWKSFile:   os.Getenv("PAN_BAGNAT_JWKS_FILE"),
+	})
+	if err != nil {
+		log.Fatalf("auth configuration failed: %v", err)
 	}
 
 	allowedOrigins := parseAllowedOrigins(os.Getenv("CORS_ORIGIN"))
 
 	addr := ":" + strconv.Itoa(port)
-	router := server.NewRouter(adminHandler, allowedOrigins)
+	lifecycle := &jobs.SessionLifecycle{Sessions: sessionStore, Students: studentSessionStore, Roster: rosterSyncer}
+	cleanup := &jobs.StorageCleanup{Queue: store.NewStorageCleanupStore(dbConn), Storage: storageBackend}
+	jobsHandler := &api.JobsHandler{Lifecycle: lifecycle, Cleanup: cleanup}
+
+	var filesHandler *api.FilesHandler
+	if fs, ok := storageBackend.(*storage.Filesystem); ok {
+		filesHandler = &api.FilesHandler{Storage: fs}
+	}
+
+	router := server.NewRouter(adminHandler, studentHandler, jobsHandler, filesHandler, authenticator, allowedOrigins)
 	handler := server.WithBasePath(router, os.Getenv("BASE_PATH"))
 	httpServer := server.NewHTTPServer(addr, handler)
 
 	shutdownCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	if os.Getenv("JOBS_DISABLED") != "true" {
+		interval := time.Minute
+		if v := os.Getenv("JOBS_INTERVAL"); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed <= 0 {
+				log.Fatalf("invalid JOBS_INTERVAL %q", v)
+			}
+			interval = parsed
+		}
+		scheduler := jobs.NewScheduler(dbConn, interval, lifecycle.Job(), cleanup.Job())
+		go scheduler.Run(shutdownCtx)
+	}
+
 	go func() {
 		log.Printf("[adm-backend] listening on %s", addr)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -74,6 +176,68 @@ func main() {
 	}
 }
 
+// runMigrateCommand implements `adm-server migrate up|down [steps]|status`.
+func runMigrateCommand(ctx context.Context, migrator *migrate.Runner, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		for _, m := range applied {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = parsed
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no applied migrations")
+		}
+		for _, m := range reverted {
+			fmt.Printf("reverted %d_%s\n", m.Version, m.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt.Valid {
+				applied = "applied " + st.AppliedAt.Time.UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up, down or status)", args[0])
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 func parseAllowedOrigins(raw string) []string {
 	if raw == "" {
 		return []string{
@@ -7,9 +7,11 @@ require (
 	github.com/go-chi/cors v1.2.1
 	github.com/lib/pq v1.10.9
 	github.com/oklog/ulid/v2 v2.1.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 replace github.com/go-chi/chi/v5 => ./vendor/github.com/go-chi/chi/v5
 replace github.com/go-chi/cors => ./vendor/github.com/go-chi/cors
 replace github.com/lib/pq => ./vendor/github.com/lib/pq
 replace github.com/oklog/ulid/v2 => ./vendor/github.com/oklog/ulid/v2
+replace gopkg.in/yaml.v3 => ./vendor/gopkg.in/yaml.v3
@@ -4,34 +4,43 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"adm-backend/internal/docgen"
+	"adm-backend/internal/eligibility"
 	"adm-backend/internal/ids"
-	"adm-backend/internal/panbagnat"
+	"adm-backend/internal/roster"
+	"adm-backend/internal/storage"
 	"adm-backend/internal/store"
+	"adm-backend/internal/workflow"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type AdminHandler struct {
-	Sessions     *store.SessionStore
-	Client       *panbagnat.Client
-	ServiceToken string
+	Sessions   *store.SessionStore
+	Students   *store.StudentSessionStore
+	Categories *store.CategoryStore
+	Timeline   *store.TimelineStore
+	Roster     *roster.Syncer
+	Documents  *docgen.Generator
+	Storage    storage.Backend
 }
 
 type sessionResponse struct {
-	ID             string    `json:"id"`
-	Label          string    `json:"label"`
-	StartAt        time.Time `json:"start_at"`
-	EndAt          time.Time `json:"end_at"`
-	Status         string    `json:"status"`
-	IsOngoing      bool      `json:"is_ongoing"`
-	StudentCount   int       `json:"student_count"`
-	ValidatedCount int       `json:"validated_count"`
+	ID             string           `json:"id"`
+	Label          string           `json:"label"`
+	StartAt        time.Time        `json:"start_at"`
+	EndAt          time.Time        `json:"end_at"`
+	Status         string           `json:"status"`
+	IsOngoing      bool             `json:"is_ongoing"`
+	StudentCount   int              `json:"student_count"`
+	ValidatedCount int              `json:"validated_count"`
+	Eligibility    eligibility.Rule `json:"eligibility"`
 }
 
 type listSessionsResponse struct {
@@ -39,19 +48,66 @@ type listSessionsResponse struct {
 }
 
 type createSessionRequest struct {
+	Label       string          `json:"label"`
+	StartAt     time.Time       `json:"start_at"`
+	EndAt       time.Time       `json:"end_at"`
+	Eligibility json.RawMessage `json:"eligibility"`
+}
+
+type updateSessionRequest struct {
+	Label       *string         `json:"label"`
+	StartAt     *time.Time      `json:"start_at"`
+	EndAt       *time.Time      `json:"end_at"`
+	Status      *string         `json:"status"`
+	Eligibility json.RawMessage `json:"eligibility"`
+}
+
+type createSessionResponse struct {
+	Session sessionResponse `json:"session"`
+}
+
+type cloneSessionRequest struct {
 	Label   string    `json:"label"`
 	StartAt time.Time `json:"start_at"`
 	EndAt   time.Time `json:"end_at"`
 }
 
-type createSessionResponse struct {
-	Session sessionResponse `json:"session"`
+type cloneSessionResponse struct {
+	Session            sessionResponse `json:"session"`
+	SourceSessionID    string          `json:"source_session_id"`
+	CopiedCategories   int             `json:"copied_categories"`
+	CopiedRequirements int             `json:"copied_requirements"`
+	CopiedLinks        int             `json:"copied_links"`
 }
 
 // RegisterAdminRoutes declares the admin-facing HTTP endpoints using a chi router.
 func RegisterAdminRoutes(r chi.Router, handler *AdminHandler) {
 	r.Get("/sessions", handler.handleListSessions)
 	r.Post("/sessions", handler.handleCreateSession)
+	r.Patch("/sessions/{id}", handler.handleUpdateSession)
+	r.Post("/sessions/{id}/clone", handler.handleCloneSession)
+	r.Post("/sessions/{id}/rebuild-student-sessions", handler.handleRebuildStudentSessions)
+	r.Get("/sessions/{id}/configuration/export", handler.handleExportConfiguration)
+	r.Post("/sessions/{id}/configuration/import", handler.handleImportConfiguration)
+	r.Get("/sessions/{id}/categories", handler.handleListCategories)
+	r.Post("/sessions/{id}/categories", handler.handleCreateCategory)
+	r.Patch("/sessions/{id}/categories/{categoryID}", handler.handleUpdateCategory)
+	r.Delete("/sessions/{id}/categories/{categoryID}", handler.handleDeleteCategory)
+	r.Put("/sessions/{id}/categories/{categoryID}/requirements", handler.handleSetCategoryRequirements)
+	r.Put("/sessions/{id}/categories/{categoryID}/requirements/{requirementID}", handler.handleAttachRequirement)
+	r.Delete("/sessions/{id}/categories/{categoryID}/requirements/{requirementID}", handler.handleDetachRequirement)
+	r.Get("/sessions/{id}/requirements", handler.handleListRequirements)
+	r.Post("/sessions/{id}/requirements", handler.handleCreateRequirement)
+	r.Put("/sessions/{id}/requirements/order", handler.handleReorderRequirements)
+	r.Patch("/sessions/{id}/requirements/{requirementID}", handler.handleUpdateRequirement)
+	r.Delete("/sessions/{id}/requirements/{requirementID}", handler.handleDeleteRequirement)
+	r.Post("/eligibility/preview", handler.handlePreviewEligibility)
+	r.Get("/student-sessions", handler.handleSearchStudentSessions)
+	r.Get("/student-sessions/{id}", handler.handleGetStudentSession)
+	r.Get("/student-sessions/{id}/history", handler.handleGetStudentSessionHistory)
+	r.Post("/student-sessions/{id}/review", handler.handleReviewStudentSession)
+	r.Post("/student-sessions/{id}/reopen", handler.handleReopenStudentSession)
+	r.Post("/student-sessions/{id}/generate-documents", handler.handleGenerateDocuments)
 }
 
 func (h *AdminHandler) handleListSessions(w http.ResponseWriter, r *http.Request) {
@@ -72,11 +128,6 @@ func (h *AdminHandler) handleListSessions(w http.ResponseWriter, r *http.Request
 }
 
 func (h *AdminHandler) handleCreateSession(w http.ResponseWriter, r *http.Request) {
-	if h.Client == nil {
-		respondError(w, http.StatusInternalServerError, errors.New("pan bagnat client not configured"))
-		return
-	}
-
 	if r.Body == nil {
 		http.Error(w, "missing body", http.StatusBadRequest)
 		return
@@ -109,38 +160,33 @@ func (h *AdminHandler) handleCreateSession(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" && h.ServiceToken != "" {
-		authHeader = h.ServiceToken
-	}
-	if authHeader == "" {
-		http.Error(w, "missing Authorization header", http.StatusUnauthorized)
-		return
-	}
-	students, err := h.Client.ListAllUsers(r.Context(), authHeader)
-	if err != nil {
-		respondError(w, http.StatusBadGateway, err)
-		return
+	var configuration json.RawMessage
+	if len(payload.Eligibility) > 0 {
+		var err error
+		configuration, err = eligibilityConfiguration(payload.Eligibility)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
-	logins := dedupeLogins(students)
-
 	sessionID, err := ids.New("adm_session")
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	status := store.SessionStatus("draft")
+	status := workflow.SessionDraft
 	publishedAt := sql.NullTime{}
 	if !payload.StartAt.After(now) {
-		status = store.SessionStatus("active")
+		status = workflow.SessionActive
 		publishedAt = sql.NullTime{Time: now, Valid: true}
 	}
 
-	createdBy := r.Header.Get("X-User-Login")
-	if createdBy == "" {
-		createdBy = "unknown_admin"
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
 	}
 
 	params := store.CreateSessionParams{
@@ -149,35 +195,187 @@ func (h *AdminHandler) handleCreateSession(w http.ResponseWriter, r *http.Reques
 		StartAt:        payload.StartAt,
 		EndAt:          payload.EndAt,
 		Status:         status,
-		CreatedByLogin: createdBy,
+		CreatedByLogin: identity.Login,
 		PublishedAt:    publishedAt,
+		Configuration:  configuration,
 	}
 
-	if err := h.Sessions.InsertSessionWithStudents(r.Context(), params, logins); err != nil {
+	if err := h.Sessions.InsertSession(r.Context(), params); err != nil {
+		if errors.Is(err, store.ErrLabelTaken) {
+			respondError(w, http.StatusConflict, err)
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	summaries, err := h.Sessions.ListSummaries(r.Context())
+	// A session that is already open gets its roster right away; if Pan-Bagnat is
+	// unreachable the scheduler retries, so creation itself never fails on it.
+	if status == workflow.SessionActive && h.Roster != nil {
+		if _, err := h.Roster.Sync(r.Context(), sessionID, r.Header.Get("Authorization"), roster.Options{}); err != nil {
+			log.Printf("initial roster sync for %s: %v", sessionID, err)
+		}
+	}
+
+	created, err := h.Sessions.GetSummary(r.Context(), sessionID)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	var created store.SessionSummary
-	for _, summary := range summaries {
-		if summary.ID == sessionID {
-			created = summary
-			break
+	writeJSON(w, http.StatusCreated, createSessionResponse{Session: toSessionResponse(created, time.Now().UTC())})
+}
+
+func (h *AdminHandler) handleUpdateSession(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		http.Error(w, "missing body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var payload updateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	params := store.UpdateSessionParams{StartAt: payload.StartAt, EndAt: payload.EndAt}
+	if payload.Label != nil {
+		label := strings.TrimSpace(*payload.Label)
+		if label == "" {
+			http.Error(w, "label cannot be empty", http.StatusBadRequest)
+			return
 		}
+		params.Label = &label
+	}
+	if (payload.StartAt != nil && payload.StartAt.IsZero()) || (payload.EndAt != nil && payload.EndAt.IsZero()) {
+		http.Error(w, "start_at and end_at cannot be cleared", http.StatusBadRequest)
+		return
+	}
+	if payload.Status != nil {
+		status := store.SessionStatus(*payload.Status)
+		switch status {
+		case workflow.SessionDraft, workflow.SessionActive, workflow.SessionClosed:
+		default:
+			http.Error(w, `status must be "draft", "active" or "closed"`, http.StatusBadRequest)
+			return
+		}
+		params.Status = &status
+	}
+	if len(payload.Eligibility) > 0 {
+		configuration, err := eligibilityConfiguration(payload.Eligibility)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.Configuration = configuration
+	}
+	if params.Label == nil && params.StartAt == nil && params.EndAt == nil && params.Status == nil && params.Configuration == nil {
+		http.Error(w, "nothing to update", http.StatusBadRequest)
+		return
 	}
 
-	if created.ID == "" {
-		respondError(w, http.StatusInternalServerError, sql.ErrNoRows)
+	sessionID := chi.URLParam(r, "id")
+	if err := h.Sessions.UpdateSession(r.Context(), sessionID, params); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "session not found", http.StatusNotFound)
+		case errors.Is(err, store.ErrInvalidSchedule):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, store.ErrLabelTaken), errors.Is(err, workflow.ErrIllegalTransition):
+			respondError(w, http.StatusConflict, err)
+		default:
+			respondError(w, http.StatusInternalServerError, err)
+		}
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, createSessionResponse{Session: toSessionResponse(created, time.Now().UTC())})
+	updated, err := h.Sessions.GetSummary(r.Context(), sessionID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, createSessionResponse{Session: toSessionResponse(updated, time.Now().UTC())})
+}
+
+// handleCloneSession creates a draft session with the configuration of another one.
+// The clone is always inserted as a draft so its configuration can be edited freely;
+// the scheduler publishes it once start_at is reached.
+func (h *AdminHandler) handleCloneSession(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Body == nil {
+		http.Error(w, "missing body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var payload cloneSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	payload.Label = strings.TrimSpace(payload.Label)
+	if payload.Label == "" {
+		payload.Label = defaultLabelFor(payload.StartAt)
+	}
+	if payload.StartAt.IsZero() || payload.EndAt.IsZero() {
+		http.Error(w, "start_at and end_at are required", http.StatusBadRequest)
+		return
+	}
+	if !payload.EndAt.After(payload.StartAt) {
+		http.Error(w, "end_at must be after start_at", http.StatusBadRequest)
+		return
+	}
+	if payload.EndAt.Before(time.Now().UTC()) {
+		http.Error(w, "end_at cannot be in the past", http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := ids.New("adm_session")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sourceID := chi.URLParam(r, "id")
+	result, err := h.Sessions.Clone(r.Context(), store.CloneSessionParams{
+		SourceID:       sourceID,
+		ID:             sessionID,
+		Label:          payload.Label,
+		StartAt:        payload.StartAt,
+		EndAt:          payload.EndAt,
+		CreatedByLogin: identity.Login,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "session not found", http.StatusNotFound)
+		case errors.Is(err, store.ErrLabelTaken):
+			respondError(w, http.StatusConflict, err)
+		default:
+			respondError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	created, err := h.Sessions.GetSummary(r.Context(), sessionID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, cloneSessionResponse{
+		Session:            toSessionResponse(created, time.Now().UTC()),
+		SourceSessionID:    sourceID,
+		CopiedCategories:   result.Categories,
+		CopiedRequirements: result.Requirements,
+		CopiedLinks:        result.Links,
+	})
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -193,7 +391,25 @@ func respondError(w http.ResponseWriter, status int, err error) {
 	writeJSON(w, status, errorResponse{Error: err.Error()})
 }
 
+// isTransitionConflict reports errors caused by a student session or submission that is
+// not in a state allowing the requested action.
+func isTransitionConflict(err error) bool {
+	return errors.Is(err, workflow.ErrIllegalTransition) || errors.Is(err, store.ErrStaleRevision)
+}
+
+// eligibilityConfiguration validates a rule and wraps it as a configuration patch.
+func eligibilityConfiguration(raw json.RawMessage) (json.RawMessage, error) {
+	rule, err := eligibility.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]eligibility.Rule{eligibility.ConfigurationKey: rule})
+}
+
 func toSessionResponse(summary store.SessionSummary, now time.Time) sessionResponse {
+	// Configurations are only written through validated requests, so a decoding
+	// error can only come from a manual edit; show the session as unrestricted.
+	rule, _ := eligibility.FromConfiguration(summary.Configuration)
 	isOngoing := (now.After(summary.StartAt) || now.Equal(summary.StartAt)) && (now.Before(summary.EndAt) || now.Equal(summary.EndAt))
 
 	return sessionResponse{
@@ -205,27 +421,10 @@ func toSessionResponse(summary store.SessionSummary, now time.Time) sessionRespo
 		IsOngoing:      isOngoing,
 		StudentCount:   summary.StudentCount,
 		ValidatedCount: summary.ValidatedCount,
+		Eligibility:    rule,
 	}
 }
 
-func dedupeLogins(users []panbagnat.User) []string {
-	seen := make(map[string]string)
-	for _, user := range users {
-		login := strings.TrimSpace(user.FtLogin)
-		if login == "" {
-			continue
-		}
-		seen[strings.ToLower(login)] = login
-	}
-
-	logins := make([]string, 0, len(seen))
-	for _, login := range seen {
-		logins = append(logins, login)
-	}
-	sort.Strings(logins)
-	return logins
-}
-
 func defaultLabelFor(start time.Time) string {
 	year := start.In(time.UTC).Year()
 	if year <= 0 {
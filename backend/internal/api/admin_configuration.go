@@ -0,0 +1,678 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"adm-backend/internal/configbundle"
+	"adm-backend/internal/questionnaire"
+	"adm-backend/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type categoryResponse struct {
+	ID                 string          `json:"id"`
+	Code               string          `json:"code"`
+	Label              string          `json:"label"`
+	Description        *string         `json:"description"`
+	QuestionnaireLogic json.RawMessage `json:"questionnaire_logic"`
+	IsActive           bool            `json:"is_active"`
+	RequirementIDs     []string        `json:"requirement_ids"`
+}
+
+type listCategoriesResponse struct {
+	Categories []categoryResponse `json:"categories"`
+}
+
+type requirementResponse struct {
+	ID                string   `json:"id"`
+	Code              string   `json:"code"`
+	Title             string   `json:"title"`
+	Description       *string  `json:"description"`
+	AcceptedMimeTypes []string `json:"accepted_mime_types"`
+	MaxFileSizeBytes  *int64   `json:"max_file_size_bytes"`
+	ReminderOrder     *int64   `json:"reminder_order"`
+	IsMandatory       bool     `json:"is_mandatory"`
+}
+
+type listRequirementsResponse struct {
+	Requirements []requirementResponse `json:"requirements"`
+}
+
+// categoryRequest is shared by POST and PATCH; absent fields keep their current value
+// on PATCH. An empty description and a null questionnaire_logic clear them.
+type categoryRequest struct {
+	Code               *string         `json:"code"`
+	Label              *string         `json:"label"`
+	Description        *string         `json:"description"`
+	QuestionnaireLogic json.RawMessage `json:"questionnaire_logic"`
+	IsActive           *bool           `json:"is_active"`
+	RequirementIDs     []string        `json:"requirement_ids"`
+}
+
+// requirementRequest is shared by POST and PATCH; absent fields keep their current
+// value on PATCH. An empty description and a max_file_size_bytes of 0 clear them.
+type requirementRequest struct {
+	Code              *string   `json:"code"`
+	Title             *string   `json:"title"`
+	Description       *string   `json:"description"`
+	AcceptedMimeTypes *[]string `json:"accepted_mime_types"`
+	MaxFileSizeBytes  *int64    `json:"max_file_size_bytes"`
+	IsMandatory       *bool     `json:"is_mandatory"`
+}
+
+type requirementIDsRequest struct {
+	RequirementIDs []string `json:"requirement_ids"`
+}
+
+// categoryRequirementsResponse answers the endpoints that change the requirements of
+// a category. On a dry run the category is returned unchanged.
+type categoryRequirementsResponse struct {
+	DryRun   bool             `json:"dry_run"`
+	Category categoryResponse `json:"category"`
+	requirementChangeResponse
+}
+
+// updateRequirementResponse is the requirement followed by the re-sync of the
+// categories it is linked to, which only happens when it was made mandatory. On a dry
+// run the requirement is returned unchanged.
+type updateRequirementResponse struct {
+	requirementResponse
+	DryRun     bool                        `json:"dry_run"`
+	Categories []requirementChangeResponse `json:"categories"`
+}
+
+type deleteRequirementResponse struct {
+	DryRun     bool                        `json:"dry_run"`
+	Categories []requirementChangeResponse `json:"categories"`
+}
+
+type requirementChangeResponse struct {
+	CategoryID                   string                           `json:"category_id"`
+	AddedRequirementIDs          []string                         `json:"added_requirement_ids"`
+	RemovedRequirementIDs        []string                         `json:"removed_requirement_ids"`
+	NewlyMandatoryRequirementIDs []string                         `json:"newly_mandatory_requirement_ids"`
+	AffectedStudentSessions      []resyncedStudentSessionResponse `json:"affected_student_sessions"`
+}
+
+type resyncedStudentSessionResponse struct {
+	ID              string                     `json:"id"`
+	StudentLogin    string                     `json:"student_login"`
+	FromStatus      store.StudentSessionStatus `json:"from_status"`
+	ToStatus        store.StudentSessionStatus `json:"to_status"`
+	CurrentRevision int                        `json:"current_revision"`
+}
+
+type unknownRequirementsResponse struct {
+	Error   string   `json:"error"`
+	Unknown []string `json:"unknown_requirements"`
+}
+
+func (h *AdminHandler) handleListCategories(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	if !h.sessionExists(w, r, sessionID) {
+		return
+	}
+	categories, err := h.Categories.List(r.Context(), sessionID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := listCategoriesResponse{Categories: make([]categoryResponse, 0, len(categories))}
+	for _, category := range categories {
+		resp.Categories = append(resp.Categories, toCategoryResponse(category))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *AdminHandler) handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseConfigEdit(w, r)
+	if !ok {
+		return
+	}
+	var payload categoryRequest
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	params := store.CategoryParams{IsActive: true}
+	if payload.Code == nil || payload.Label == nil {
+		http.Error(w, "code and label are required", http.StatusBadRequest)
+		return
+	}
+	if err := applyCategoryRequest(&params, payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.Categories.CreateCategory(r.Context(), edit, params, payload.RequirementIDs)
+	if err != nil {
+		writeConfigurationError(w, err, "session not found")
+		return
+	}
+	h.writeCategory(w, r, edit.SessionID, id, http.StatusCreated)
+}
+
+func (h *AdminHandler) handleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseConfigEdit(w, r)
+	if !ok {
+		return
+	}
+	var payload categoryRequest
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	if payload.RequirementIDs != nil {
+		http.Error(w, "requirement_ids are changed through PUT .../requirements", http.StatusBadRequest)
+		return
+	}
+
+	categoryID := chi.URLParam(r, "categoryID")
+	current, err := h.Categories.Get(r.Context(), edit.SessionID, categoryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "category not found", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	params := store.CategoryParams{
+		Code:               current.Code,
+		Label:              current.Label,
+		Description:        current.Description,
+		QuestionnaireLogic: current.QuestionnaireLogic,
+		IsActive:           current.IsActive,
+	}
+	if err := applyCategoryRequest(&params, payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Categories.UpdateCategory(r.Context(), edit, categoryID, params); err != nil {
+		writeConfigurationError(w, err, "category not found")
+		return
+	}
+	h.writeCategory(w, r, edit.SessionID, categoryID, http.StatusOK)
+}
+
+func (h *AdminHandler) handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseConfigEdit(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Categories.DeleteCategory(r.Context(), edit, chi.URLParam(r, "categoryID")); err != nil {
+		writeConfigurationError(w, err, "category not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) handleSetCategoryRequirements(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseRequirementEdit(w, r)
+	if !ok {
+		return
+	}
+	var payload requirementIDsRequest
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	if payload.RequirementIDs == nil {
+		http.Error(w, "requirement_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	categoryID := chi.URLParam(r, "categoryID")
+	change, err := h.Categories.SetCategoryRequirements(r.Context(), edit, categoryID, payload.RequirementIDs)
+	if err != nil {
+		writeConfigurationError(w, err, "category not found")
+		return
+	}
+	h.writeRequirementChange(w, r, edit, change)
+}
+
+func (h *AdminHandler) handleAttachRequirement(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseRequirementEdit(w, r)
+	if !ok {
+		return
+	}
+	change, err := h.Categories.AttachRequirement(r.Context(), edit, chi.URLParam(r, "categoryID"), chi.URLParam(r, "requirementID"))
+	if err != nil {
+		writeConfigurationError(w, err, "category not found")
+		return
+	}
+	h.writeRequirementChange(w, r, edit, change)
+}
+
+func (h *AdminHandler) handleDetachRequirement(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseRequirementEdit(w, r)
+	if !ok {
+		return
+	}
+	change, err := h.Categories.DetachRequirement(r.Context(), edit, chi.URLParam(r, "categoryID"), chi.URLParam(r, "requirementID"))
+	if err != nil {
+		writeConfigurationError(w, err, "category or requirement link not found")
+		return
+	}
+	h.writeRequirementChange(w, r, edit, change)
+}
+
+func (h *AdminHandler) handleListRequirements(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	if !h.sessionExists(w, r, sessionID) {
+		return
+	}
+	requirements, err := h.Categories.ListRequirements(r.Context(), sessionID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toListRequirementsResponse(requirements))
+}
+
+func (h *AdminHandler) handleCreateRequirement(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseConfigEdit(w, r)
+	if !ok {
+		return
+	}
+	var payload requirementRequest
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	params := store.RequirementParams{IsMandatory: true}
+	if payload.Code == nil || payload.Title == nil {
+		http.Error(w, "code and title are required", http.StatusBadRequest)
+		return
+	}
+	if err := applyRequirementRequest(&params, payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.Categories.CreateRequirement(r.Context(), edit, params)
+	if err != nil {
+		writeConfigurationError(w, err, "session not found")
+		return
+	}
+	h.writeRequirement(w, r, edit.SessionID, id, http.StatusCreated)
+}
+
+func (h *AdminHandler) handleUpdateRequirement(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseRequirementEdit(w, r)
+	if !ok {
+		return
+	}
+	var payload requirementRequest
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	requirementID := chi.URLParam(r, "requirementID")
+	current, err := h.Categories.GetRequirement(r.Context(), edit.SessionID, requirementID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "requirement not found", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	params := store.RequirementParams{
+		Code:              current.Code,
+		Title:             current.Title,
+		Description:       current.Description,
+		AcceptedMimeTypes: current.AcceptedMimeTypes,
+		MaxFileSizeBytes:  current.MaxFileSizeBytes,
+		IsMandatory:       current.IsMandatory,
+	}
+	if err := applyRequirementRequest(&params, payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.Categories.UpdateRequirement(r.Context(), edit, requirementID, params)
+	if err != nil {
+		writeConfigurationError(w, err, "requirement not found")
+		return
+	}
+	requirement, err := h.Categories.GetRequirement(r.Context(), edit.SessionID, requirementID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updateRequirementResponse{
+		requirementResponse: toRequirementResponse(requirement),
+		DryRun:              edit.DryRun,
+		Categories:          toRequirementChangeResponses(changes),
+	})
+}
+
+func (h *AdminHandler) handleDeleteRequirement(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseRequirementEdit(w, r)
+	if !ok {
+		return
+	}
+	changes, err := h.Categories.DeleteRequirement(r.Context(), edit, chi.URLParam(r, "requirementID"))
+	if err != nil {
+		writeConfigurationError(w, err, "requirement not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, deleteRequirementResponse{DryRun: edit.DryRun, Categories: toRequirementChangeResponses(changes)})
+}
+
+// handleReorderRequirements sets the reminder order to the order of requirement_ids,
+// which must list every requirement of the session.
+func (h *AdminHandler) handleReorderRequirements(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseConfigEdit(w, r)
+	if !ok {
+		return
+	}
+	var payload requirementIDsRequest
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	if err := h.Categories.ReorderRequirements(r.Context(), edit, payload.RequirementIDs); err != nil {
+		writeConfigurationError(w, err, "session not found")
+		return
+	}
+	requirements, err := h.Categories.ListRequirements(r.Context(), edit.SessionID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toListRequirementsResponse(requirements))
+}
+
+func (h *AdminHandler) writeCategory(w http.ResponseWriter, r *http.Request, sessionID, categoryID string, status int) {
+	category, err := h.Categories.Get(r.Context(), sessionID, categoryID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, status, toCategoryResponse(category))
+}
+
+func (h *AdminHandler) writeRequirementChange(w http.ResponseWriter, r *http.Request, edit store.ConfigEdit, change store.RequirementChange) {
+	category, err := h.Categories.Get(r.Context(), edit.SessionID, change.CategoryID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, categoryRequirementsResponse{
+		DryRun:                    edit.DryRun,
+		Category:                  toCategoryResponse(category),
+		requirementChangeResponse: toRequirementChangeResponse(change),
+	})
+}
+
+func (h *AdminHandler) writeRequirement(w http.ResponseWriter, r *http.Request, sessionID, requirementID string, status int) {
+	requirement, err := h.Categories.GetRequirement(r.Context(), sessionID, requirementID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, status, toRequirementResponse(requirement))
+}
+
+// sessionExists answers 404 for listings of an unknown session, which would
+// otherwise be indistinguishable from an empty configuration.
+func (h *AdminHandler) sessionExists(w http.ResponseWriter, r *http.Request, sessionID string) bool {
+	if _, err := h.Sessions.GetSummary(r.Context(), sessionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return false
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return false
+	}
+	return true
+}
+
+// parseConfigEdit reads the session ID and the force query parameter that allows
+// editing the configuration of an active session.
+func parseConfigEdit(w http.ResponseWriter, r *http.Request) (store.ConfigEdit, bool) {
+	edit := store.ConfigEdit{SessionID: chi.URLParam(r, "id")}
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return edit, false
+	}
+	edit.EditedBy = identity.Login
+
+	if !parseBoolParam(w, r, "force", &edit.Force) {
+		return edit, false
+	}
+	return edit, true
+}
+
+// parseRequirementEdit is parseConfigEdit for changes that can re-sync student
+// sessions; dry_run previews the change and its impact without saving it.
+func parseRequirementEdit(w http.ResponseWriter, r *http.Request) (store.ConfigEdit, bool) {
+	edit, ok := parseConfigEdit(w, r)
+	if !ok || !parseBoolParam(w, r, "dry_run", &edit.DryRun) {
+		return edit, false
+	}
+	return edit, true
+}
+
+// parseBoolParam sets *target from an optional boolean query parameter and answers
+// 400 when it is malformed.
+func parseBoolParam(w http.ResponseWriter, r *http.Request, name string, target *bool) bool {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return true
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		http.Error(w, name+" must be true or false", http.StatusBadRequest)
+		return false
+	}
+	*target = value
+	return true
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, payload any) bool {
+	if r.Body == nil {
+		http.Error(w, "missing body", http.StatusBadRequest)
+		return false
+	}
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeConfigurationError(w http.ResponseWriter, err error, notFound string) {
+	var unknown *store.UnknownRequirementsError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, notFound, http.StatusNotFound)
+	case errors.As(err, &unknown):
+		writeJSON(w, http.StatusUnprocessableEntity, unknownRequirementsResponse{Error: err.Error(), Unknown: unknown.IDs})
+	case errors.Is(err, store.ErrIncompleteOrder):
+		respondError(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, store.ErrConfigurationLocked),
+		errors.Is(err, store.ErrCodeTaken),
+		errors.Is(err, store.ErrInUse),
+		isTransitionConflict(err):
+		respondError(w, http.StatusConflict, err)
+	default:
+		respondError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func applyCategoryRequest(params *store.CategoryParams, payload categoryRequest) error {
+	if payload.Code != nil {
+		code, err := normalizeCode(*payload.Code)
+		if err != nil {
+			return err
+		}
+		params.Code = code
+	}
+	if payload.Label != nil {
+		label := strings.TrimSpace(*payload.Label)
+		if label == "" {
+			return errors.New("label cannot be empty")
+		}
+		params.Label = label
+	}
+	if payload.Description != nil {
+		params.Description = optionalText(*payload.Description)
+	}
+	if payload.QuestionnaireLogic != nil {
+		if bytes.Equal(bytes.TrimSpace(payload.QuestionnaireLogic), []byte("null")) {
+			params.QuestionnaireLogic = nil
+		} else {
+			if _, err := questionnaire.ParseLogic(payload.QuestionnaireLogic); err != nil {
+				return fmt.Errorf("invalid questionnaire_logic: %w", err)
+			}
+			params.QuestionnaireLogic = payload.QuestionnaireLogic
+		}
+	}
+	if payload.IsActive != nil {
+		params.IsActive = *payload.IsActive
+	}
+	return nil
+}
+
+func applyRequirementRequest(params *store.RequirementParams, payload requirementRequest) error {
+	if payload.Code != nil {
+		code, err := normalizeCode(*payload.Code)
+		if err != nil {
+			return err
+		}
+		params.Code = code
+	}
+	if payload.Title != nil {
+		title := strings.TrimSpace(*payload.Title)
+		if title == "" {
+			return errors.New("title cannot be empty")
+		}
+		params.Title = title
+	}
+	if payload.Description != nil {
+		params.Description = optionalText(*payload.Description)
+	}
+	if payload.AcceptedMimeTypes != nil {
+		mimeTypes := make([]string, 0, len(*payload.AcceptedMimeTypes))
+		for _, value := range *payload.AcceptedMimeTypes {
+			value = strings.ToLower(strings.TrimSpace(value))
+			if !strings.Contains(value, "/") {
+				return fmt.Errorf("invalid mime type %q", value)
+			}
+			mimeTypes = append(mimeTypes, value)
+		}
+		params.AcceptedMimeTypes = mimeTypes
+	}
+	if payload.MaxFileSizeBytes != nil {
+		switch size := *payload.MaxFileSizeBytes; {
+		case size < 0:
+			return errors.New("max_file_size_bytes must be positive, or 0 for no limit")
+		case size == 0:
+			params.MaxFileSizeBytes = sql.NullInt64{}
+		default:
+			params.MaxFileSizeBytes = sql.NullInt64{Int64: size, Valid: true}
+		}
+	}
+	if payload.IsMandatory != nil {
+		params.IsMandatory = *payload.IsMandatory
+	}
+	return nil
+}
+
+func normalizeCode(raw string) (string, error) {
+	code := strings.TrimSpace(raw)
+	if !configbundle.ValidCode(code) {
+		return "", fmt.Errorf("code %q must be 1-64 lowercase letters, digits, '-' or '_', starting with a letter or digit", code)
+	}
+	return code, nil
+}
+
+func optionalText(raw string) sql.NullString {
+	value := strings.TrimSpace(raw)
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func toCategoryResponse(category store.Category) categoryResponse {
+	resp := categoryResponse{
+		ID:                 category.ID,
+		Code:               category.Code,
+		Label:              category.Label,
+		Description:        nullString(category.Description),
+		QuestionnaireLogic: category.QuestionnaireLogic,
+		IsActive:           category.IsActive,
+		RequirementIDs:     category.RequirementIDs,
+	}
+	if resp.RequirementIDs == nil {
+		resp.RequirementIDs = []string{}
+	}
+	return resp
+}
+
+func toRequirementResponse(req store.DocumentRequirement) requirementResponse {
+	resp := requirementResponse{
+		ID:                req.ID,
+		Code:              req.Code,
+		Title:             req.Title,
+		Description:       nullString(req.Description),
+		AcceptedMimeTypes: req.AcceptedMimeTypes,
+		MaxFileSizeBytes:  nullInt64(req.MaxFileSizeBytes),
+		ReminderOrder:     nullInt64(req.ReminderOrder),
+		IsMandatory:       req.IsMandatory,
+	}
+	if resp.AcceptedMimeTypes == nil {
+		resp.AcceptedMimeTypes = []string{}
+	}
+	return resp
+}
+
+func toRequirementChangeResponse(change store.RequirementChange) requirementChangeResponse {
+	resp := requirementChangeResponse{
+		CategoryID:                   change.CategoryID,
+		AddedRequirementIDs:          nonNil(change.Added),
+		RemovedRequirementIDs:        nonNil(change.Removed),
+		NewlyMandatoryRequirementIDs: nonNil(change.NewlyMandatory),
+		AffectedStudentSessions:      make([]resyncedStudentSessionResponse, 0, len(change.StudentSessions)),
+	}
+	for _, session := range change.StudentSessions {
+		resp.AffectedStudentSessions = append(resp.AffectedStudentSessions, resyncedStudentSessionResponse{
+			ID:              session.ID,
+			StudentLogin:    session.StudentLogin,
+			FromStatus:      session.From,
+			ToStatus:        session.To,
+			CurrentRevision: session.Revision,
+		})
+	}
+	return resp
+}
+
+func toRequirementChangeResponses(changes []store.RequirementChange) []requirementChangeResponse {
+	resp := make([]requirementChangeResponse, 0, len(changes))
+	for _, change := range changes {
+		resp = append(resp, toRequirementChangeResponse(change))
+	}
+	return resp
+}
+
+func toListRequirementsResponse(requirements []store.DocumentRequirement) listRequirementsResponse {
+	resp := listRequirementsResponse{Requirements: make([]requirementResponse, 0, len(requirements))}
+	for _, req := range requirements {
+		resp.Requirements = append(resp.Requirements, toRequirementResponse(req))
+	}
+	return resp
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"adm-backend/internal/configbundle"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxBundleBytes bounds the size of an imported configuration bundle.
+const maxBundleBytes = 1 << 20
+
+type importConfigurationResponse struct {
+	DryRun               bool                  `json:"dry_run"`
+	Applied              bool                  `json:"applied"`
+	ConfigurationVersion int                   `json:"configuration_version"`
+	Changes              []configbundle.Change `json:"changes"`
+	Untouched            []configbundle.Ref    `json:"untouched"`
+	// RequirementChanges lists the existing categories whose requirements changed and
+	// the student sessions sent back to waiting_for_documents.
+	RequirementChanges []requirementChangeResponse `json:"requirement_changes"`
+}
+
+// handleExportConfiguration serves the configuration of a session as a bundle, in
+// YAML unless format=json.
+func (h *AdminHandler) handleExportConfiguration(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "yaml"
+	}
+	contentType := map[string]string{"yaml": "application/yaml", "json": "application/json"}[format]
+	if contentType == "" {
+		http.Error(w, `format must be "yaml" or "json"`, http.StatusBadRequest)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "id")
+	bundle, err := h.Categories.ExportConfiguration(r.Context(), sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	body, err := configbundle.Encode(bundle, format)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, sessionID, format))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// handleImportConfiguration applies a YAML or JSON bundle to a session. Query
+// parameters: dry_run (only return the diff and its impact) and force (required on
+// active sessions).
+func (h *AdminHandler) handleImportConfiguration(w http.ResponseWriter, r *http.Request) {
+	edit, ok := parseRequirementEdit(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Body == nil {
+		http.Error(w, "missing body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBundleBytes))
+	if err != nil {
+		http.Error(w, "bundle too large or unreadable", http.StatusBadRequest)
+		return
+	}
+	bundle, err := configbundle.Parse(raw)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	result, err := h.Categories.ImportConfiguration(r.Context(), edit, bundle)
+	if err != nil {
+		writeConfigurationError(w, err, "session not found")
+		return
+	}
+
+	resp := importConfigurationResponse{
+		DryRun:               edit.DryRun,
+		Applied:              result.Applied,
+		ConfigurationVersion: result.Version,
+		Changes:              result.Diff.Changes,
+		Untouched:            result.Diff.Untouched,
+		RequirementChanges:   toRequirementChangeResponses(result.Requirements),
+	}
+	if resp.Changes == nil {
+		resp.Changes = []configbundle.Change{}
+	}
+	if resp.Untouched == nil {
+		resp.Untouched = []configbundle.Ref{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
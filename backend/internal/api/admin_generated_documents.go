@@ -0,0 +1,90 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"adm-backend/internal/docgen"
+	"adm-backend/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// generateDocumentsRequest selects the document types to generate; an empty body or
+// list generates every type.
+type generateDocumentsRequest struct {
+	DocumentTypes []string `json:"document_types"`
+}
+
+type generateDocumentsResponse struct {
+	StudentSessionID   string                      `json:"student_session_id"`
+	GeneratedDocuments []generatedDocumentResponse `json:"generated_documents"`
+}
+
+// handleGenerateDocuments renders the official documents of a validated student
+// session, replacing (and un-invalidating) earlier versions of the same type.
+func (h *AdminHandler) handleGenerateDocuments(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
+	if h.Documents == nil {
+		respondError(w, http.StatusInternalServerError, errors.New("document generation not configured"))
+		return
+	}
+
+	var payload generateDocumentsRequest
+	if r.Body != nil {
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid json body", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, docType := range payload.DocumentTypes {
+		if !docgen.Known(docType) {
+			http.Error(w, "unknown document type "+docType, http.StatusBadRequest)
+			return
+		}
+	}
+
+	studentSessionID := chi.URLParam(r, "id")
+	docs, err := h.Documents.Generate(r.Context(), studentSessionID, identity.Login, payload.DocumentTypes)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "student session not found", http.StatusNotFound)
+		case isTransitionConflict(err):
+			respondError(w, http.StatusConflict, err)
+		default:
+			respondError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	resp := generateDocumentsResponse{
+		StudentSessionID:   studentSessionID,
+		GeneratedDocuments: make([]generatedDocumentResponse, 0, len(docs)),
+	}
+	for _, doc := range docs {
+		resp.GeneratedDocuments = append(resp.GeneratedDocuments, toGeneratedDocumentResponse(doc, downloadURL(r.Context(), h.Storage, doc.StorageKey)))
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func toGeneratedDocumentResponse(doc store.GeneratedDocument, downloadURL *string) generatedDocumentResponse {
+	return generatedDocumentResponse{
+		ID:            doc.ID,
+		DocumentType:  doc.DocumentType,
+		FileName:      doc.FileName,
+		GeneratedBy:   doc.GeneratedBy,
+		GeneratedAt:   doc.GeneratedAt,
+		InvalidatedAt: nullTime(doc.InvalidatedAt),
+		InvalidatedBy: nullString(doc.InvalidatedBy),
+		DownloadURL:   downloadURL,
+	}
+}
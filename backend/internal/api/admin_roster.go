@@ -0,0 +1,141 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"adm-backend/internal/eligibility"
+	"adm-backend/internal/roster"
+	"adm-backend/internal/workflow"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type rosterSyncResponse struct {
+	SessionID string   `json:"session_id"`
+	DryRun    bool     `json:"dry_run"`
+	Added     []string `json:"added_logins"`
+	Removed   []string `json:"removed_logins"`
+	Unchanged []string `json:"unchanged_logins"`
+	Restored  []string `json:"restored_logins"`
+	Archived  []string `json:"archived_logins"`
+}
+
+type eligibilityPreviewResponse struct {
+	Total    int      `json:"total_users"`
+	Matching int      `json:"matching_users"`
+	Sample   []string `json:"sample_logins"`
+}
+
+// handlePreviewEligibility counts the Pan-Bagnat users an eligibility rule (the request
+// body) would enrol.
+func (h *AdminHandler) handlePreviewEligibility(w http.ResponseWriter, r *http.Request) {
+	if h.Roster == nil {
+		respondError(w, http.StatusInternalServerError, errors.New("roster sync not configured"))
+		return
+	}
+	if r.Body == nil {
+		http.Error(w, "missing body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	rule, err := eligibility.Parse(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	preview, err := h.Roster.Preview(r.Context(), rule, r.Header.Get("Authorization"))
+	if err != nil {
+		if errors.Is(err, roster.ErrDirectoryUnavailable) {
+			respondError(w, http.StatusBadGateway, err)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, eligibilityPreviewResponse{
+		Total:    preview.Total,
+		Matching: preview.Matching,
+		Sample:   nonNil(preview.Sample),
+	})
+}
+
+func (h *AdminHandler) handleRebuildStudentSessions(w http.ResponseWriter, r *http.Request) {
+	if h.Roster == nil {
+		respondError(w, http.StatusInternalServerError, errors.New("roster sync not configured"))
+		return
+	}
+
+	opts, err := parseRosterOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.Roster.Sync(r.Context(), chi.URLParam(r, "id"), r.Header.Get("Authorization"), opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "session not found", http.StatusNotFound)
+		case errors.Is(err, workflow.ErrIllegalTransition):
+			respondError(w, http.StatusConflict, err)
+		case errors.Is(err, roster.ErrDirectoryUnavailable):
+			respondError(w, http.StatusBadGateway, err)
+		default:
+			respondError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toRosterSyncResponse(result))
+}
+
+// parseRosterOptions reads the dry_run and archive_departed query flags.
+func parseRosterOptions(r *http.Request) (roster.Options, error) {
+	var opts roster.Options
+	query := r.URL.Query()
+	for name, target := range map[string]*bool{"dry_run": &opts.DryRun, "archive_departed": &opts.ArchiveDeparted} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return roster.Options{}, fmt.Errorf("%s must be true or false", name)
+		}
+		*target = value
+	}
+	return opts, nil
+}
+
+func toRosterSyncResponse(result roster.Result) rosterSyncResponse {
+	return rosterSyncResponse{
+		SessionID: result.SessionID,
+		DryRun:    result.DryRun,
+		Added:     nonNil(result.Added),
+		Removed:   nonNil(result.Removed),
+		Unchanged: nonNil(result.Unchanged),
+		Restored:  nonNil(result.Restored),
+		Archived:  nonNil(result.Archived),
+	}
+}
+
+// nonNil keeps empty lists as [] rather than null in JSON.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"adm-backend/internal/store"
+	"adm-backend/internal/workflow"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+var allStudentSessionStatuses = []workflow.StudentSessionStatus{
+	workflow.NotStarted,
+	workflow.WaitingForDocuments,
+	workflow.WaitingForValidation,
+	workflow.Validated,
+	workflow.Invalidated,
+}
+
+type studentSessionListItemResponse struct {
+	ID                 string     `json:"id"`
+	AdmSessionID       string     `json:"adm_session_id"`
+	AdmSessionLabel    string     `json:"adm_session_label"`
+	StudentLogin       string     `json:"student_login"`
+	Status             string     `json:"status"`
+	CurrentRevision    int        `json:"current_revision"`
+	CategoryID         *string    `json:"category_id"`
+	CategoryCode       *string    `json:"category_code"`
+	CategoryLabel      *string    `json:"category_label"`
+	LockedByStudent    bool       `json:"locked_by_student"`
+	LockedByAdmin      bool       `json:"locked_by_admin"`
+	InvalidationReason *string    `json:"invalidation_reason"`
+	LastSubmittedAt    *time.Time `json:"last_submitted_at"`
+	LastReviewedAt     *time.Time `json:"last_reviewed_at"`
+	ArchivedAt         *time.Time `json:"archived_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type searchStudentSessionsResponse struct {
+	StudentSessions []studentSessionListItemResponse `json:"student_sessions"`
+	// StatusCounts has one entry per status, ignoring the status filter.
+	StatusCounts map[string]int `json:"status_counts"`
+	NextCursor   *string        `json:"next_cursor"`
+}
+
+// handleSearchStudentSessions serves GET /admin/student-sessions. Query parameters:
+// adm_session_id, login (prefix), status (repeatable or comma-separated), category_id,
+// locked_by_student, locked_by_admin, submitted_from/submitted_to, reviewed_from/reviewed_to
+// (RFC 3339), include_archived, sort (login, submitted_at, reviewed_at or updated_at,
+// prefixed with "-" for descending order), cursor and limit (1-200).
+func (h *AdminHandler) handleSearchStudentSessions(w http.ResponseWriter, r *http.Request) {
+	params, err := parseStudentSessionSearch(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.Students.Search(r.Context(), params)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := searchStudentSessionsResponse{
+		StudentSessions: make([]studentSessionListItemResponse, 0, len(page.Sessions)),
+		StatusCounts:    make(map[string]int, len(allStudentSessionStatuses)),
+	}
+	for _, status := range allStudentSessionStatuses {
+		resp.StatusCounts[string(status)] = page.StatusCounts[status]
+	}
+	for _, item := range page.Sessions {
+		resp.StudentSessions = append(resp.StudentSessions, toStudentSessionListItemResponse(item))
+	}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func toStudentSessionListItemResponse(item store.StudentSessionListItem) studentSessionListItemResponse {
+	return studentSessionListItemResponse{
+		ID:                 item.ID,
+		AdmSessionID:       item.AdmSessionID,
+		AdmSessionLabel:    item.SessionLabel,
+		StudentLogin:       item.StudentLogin,
+		Status:             string(item.Status),
+		CurrentRevision:    item.CurrentRevision,
+		CategoryID:         nullString(item.CategoryID),
+		CategoryCode:       nullString(item.CategoryCode),
+		CategoryLabel:      nullString(item.CategoryLabel),
+		LockedByStudent:    item.LockedByStudent,
+		LockedByAdmin:      item.LockedByAdmin,
+		InvalidationReason: nullString(item.InvalidationReason),
+		LastSubmittedAt:    nullTime(item.LastSubmittedAt),
+		LastReviewedAt:     nullTime(item.LastReviewedAt),
+		ArchivedAt:         nullTime(item.ArchivedAt),
+		UpdatedAt:          item.UpdatedAt,
+	}
+}
+
+func parseStudentSessionSearch(query url.Values) (store.SearchStudentSessionsParams, error) {
+	params := store.SearchStudentSessionsParams{
+		AdmSessionID: strings.TrimSpace(query.Get("adm_session_id")),
+		LoginPrefix:  strings.TrimSpace(query.Get("login")),
+		CategoryID:   strings.TrimSpace(query.Get("category_id")),
+		Cursor:       query.Get("cursor"),
+		Limit:        defaultSearchLimit,
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			return params, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+		}
+		params.Limit = parsed
+	}
+
+	known := make(map[workflow.StudentSessionStatus]struct{}, len(allStudentSessionStatuses))
+	for _, status := range allStudentSessionStatuses {
+		known[status] = struct{}{}
+	}
+	for _, value := range query["status"] {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			status := workflow.StudentSessionStatus(part)
+			if _, ok := known[status]; !ok {
+				return params, fmt.Errorf("unknown status %q", part)
+			}
+			params.Statuses = append(params.Statuses, status)
+		}
+	}
+
+	for name, target := range map[string]**bool{"locked_by_student": &params.LockedByStudent, "locked_by_admin": &params.LockedByAdmin} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return params, fmt.Errorf("%s must be true or false", name)
+		}
+		*target = &value
+	}
+	if raw := query.Get("include_archived"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return params, errors.New("include_archived must be true or false")
+		}
+		params.IncludeArchived = value
+	}
+
+	for name, target := range map[string]*time.Time{
+		"submitted_from": &params.SubmittedFrom,
+		"submitted_to":   &params.SubmittedTo,
+		"reviewed_from":  &params.ReviewedFrom,
+		"reviewed_to":    &params.ReviewedTo,
+	} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return params, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+		}
+		*target = value
+	}
+
+	if raw := strings.TrimSpace(query.Get("sort")); raw != "" {
+		field, desc := strings.CutPrefix(raw, "-")
+		sort := store.StudentSessionSort(field)
+		if !sort.IsValid() {
+			return params, errors.New(`sort must be one of login, submitted_at, reviewed_at, updated_at (prefix with "-" for descending)`)
+		}
+		params.Sort = sort
+		params.Descending = desc
+	}
+	return params, nil
+}
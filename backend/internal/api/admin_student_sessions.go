@@ -0,0 +1,388 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"adm-backend/internal/store"
+	"adm-backend/internal/workflow"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type reviewDecisionRequest struct {
+	RequirementID string `json:"requirement_id"`
+	Decision      string `json:"decision"`
+	Reason        string `json:"reason"`
+}
+
+type reviewRequest struct {
+	Decisions []reviewDecisionRequest `json:"decisions"`
+}
+
+type reviewResponse struct {
+	StudentSessionID string   `json:"student_session_id"`
+	Status           string   `json:"status"`
+	CurrentRevision  int      `json:"current_revision"`
+	Validated        []string `json:"validated_requirements"`
+	Invalidated      []string `json:"invalidated_requirements"`
+	// GeneratedDocuments are issued automatically on validation. The review stands when
+	// generation fails; GenerationError then says why and generate-documents can retry.
+	GeneratedDocuments []generatedDocumentResponse `json:"generated_documents"`
+	GenerationError    string                      `json:"generation_error,omitempty"`
+}
+
+type reopenRequest struct {
+	Reason                       string `json:"reason"`
+	InvalidateGeneratedDocuments bool   `json:"invalidate_generated_documents"`
+}
+
+type reopenResponse struct {
+	StudentSessionID              string   `json:"student_session_id"`
+	Status                        string   `json:"status"`
+	CurrentRevision               int      `json:"current_revision"`
+	ReopenedRequirements          []string `json:"reopened_requirements"`
+	InvalidatedGeneratedDocuments int      `json:"invalidated_generated_documents"`
+}
+
+type incompleteReviewResponse struct {
+	Error      string   `json:"error"`
+	Undecided  []string `json:"undecided_requirements,omitempty"`
+	Unexpected []string `json:"unexpected_requirements,omitempty"`
+}
+
+func (h *AdminHandler) handleReviewStudentSession(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Body == nil {
+		http.Error(w, "missing body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var payload reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Decisions) == 0 {
+		http.Error(w, "decisions are required", http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[string]struct{}, len(payload.Decisions))
+	decisions := make([]store.ReviewDecision, 0, len(payload.Decisions))
+	for _, d := range payload.Decisions {
+		requirementID := strings.TrimSpace(d.RequirementID)
+		if requirementID == "" {
+			http.Error(w, "requirement_id is required for every decision", http.StatusBadRequest)
+			return
+		}
+		if _, dup := seen[requirementID]; dup {
+			http.Error(w, "duplicate decision for requirement "+requirementID, http.StatusBadRequest)
+			return
+		}
+		seen[requirementID] = struct{}{}
+
+		reason := strings.TrimSpace(d.Reason)
+		switch d.Decision {
+		case "valid":
+		case "invalid":
+			if reason == "" {
+				http.Error(w, "a reason is required to invalidate requirement "+requirementID, http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, `decision must be "valid" or "invalid"`, http.StatusBadRequest)
+			return
+		}
+
+		decisions = append(decisions, store.ReviewDecision{
+			RequirementID: requirementID,
+			Valid:         d.Decision == "valid",
+			Reason:        reason,
+		})
+	}
+
+	studentSessionID := chi.URLParam(r, "id")
+	result, err := h.Students.ReviewSubmissions(r.Context(), studentSessionID, decisions, identity.Login)
+	if err != nil {
+		var incomplete *store.IncompleteReviewError
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "student session not found", http.StatusNotFound)
+		case errors.As(err, &incomplete):
+			writeJSON(w, http.StatusUnprocessableEntity, incompleteReviewResponse{
+				Error:      "every submitted document must receive exactly one decision",
+				Undecided:  incomplete.Undecided,
+				Unexpected: incomplete.Unexpected,
+			})
+		case isTransitionConflict(err):
+			respondError(w, http.StatusConflict, err)
+		default:
+			respondError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	resp := reviewResponse{
+		StudentSessionID: studentSessionID,
+		Status:           string(result.Status),
+		CurrentRevision:  result.Revision,
+		Validated:        result.Validated,
+		Invalidated:      result.Invalidated,
+	}
+	if resp.Validated == nil {
+		resp.Validated = []string{}
+	}
+	if resp.Invalidated == nil {
+		resp.Invalidated = []string{}
+	}
+	resp.GeneratedDocuments = []generatedDocumentResponse{}
+	if result.Status == workflow.Validated && h.Documents != nil {
+		docs, err := h.Documents.Generate(r.Context(), studentSessionID, identity.Login, nil)
+		if err != nil {
+			log.Printf("generate documents for %s: %v", studentSessionID, err)
+			resp.GenerationError = err.Error()
+		}
+		for _, doc := range docs {
+			resp.GeneratedDocuments = append(resp.GeneratedDocuments, toGeneratedDocumentResponse(doc, downloadURL(r.Context(), h.Storage, doc.StorageKey)))
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *AdminHandler) handleReopenStudentSession(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Body == nil {
+		http.Error(w, "missing body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var payload reopenRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(payload.Reason)
+	if reason == "" {
+		http.Error(w, "a reason is required to reopen a student session", http.StatusBadRequest)
+		return
+	}
+
+	studentSessionID := chi.URLParam(r, "id")
+	result, err := h.Students.Reopen(r.Context(), store.ReopenParams{
+		StudentSessionID:             studentSessionID,
+		Reason:                       reason,
+		InvalidateGeneratedDocuments: payload.InvalidateGeneratedDocuments,
+		ReopenedBy:                   identity.Login,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "student session not found", http.StatusNotFound)
+		case isTransitionConflict(err):
+			respondError(w, http.StatusConflict, err)
+		default:
+			respondError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	resp := reopenResponse{
+		StudentSessionID:              studentSessionID,
+		Status:                        string(result.Status),
+		CurrentRevision:               result.Revision,
+		ReopenedRequirements:          result.Reopened,
+		InvalidatedGeneratedDocuments: result.InvalidatedGeneratedDocuments,
+	}
+	if resp.ReopenedRequirements == nil {
+		resp.ReopenedRequirements = []string{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// detailHistoryLimit is how many timeline events the detail view embeds; older ones
+// are paged through GET /admin/student-sessions/{id}/history with next_cursor.
+const detailHistoryLimit = 20
+
+type questionnaireResponseResponse struct {
+	ID                 string          `json:"id"`
+	RevisionNumber     int             `json:"revision_number"`
+	Answers            json.RawMessage `json:"answers"`
+	CalculatedCategory *string         `json:"calculated_category_id"`
+	SubmittedAt        time.Time       `json:"submitted_at"`
+}
+
+type questionnaireHistoryResponse struct {
+	Current  *questionnaireResponseResponse `json:"current"`
+	Previous *questionnaireResponseResponse `json:"previous"`
+}
+
+// toQuestionnaireHistoryResponse labels responses (newest first) by position: the
+// newest is current, the one before it previous. Answers are not rewritten on every
+// revision, so their revision numbers cannot be compared to the current revision.
+func toQuestionnaireHistoryResponse(responses []store.QuestionnaireResponse) questionnaireHistoryResponse {
+	var history questionnaireHistoryResponse
+	for i, response := range responses {
+		converted := &questionnaireResponseResponse{
+			ID:                 response.ID,
+			RevisionNumber:     response.RevisionNumber,
+			Answers:            response.Answers,
+			CalculatedCategory: nullString(response.CalculatedCategory),
+			SubmittedAt:        response.SubmittedAt,
+		}
+		switch i {
+		case 0:
+			history.Current = converted
+		case 1:
+			history.Previous = converted
+		}
+	}
+	return history
+}
+
+type adminSubmissionResponse struct {
+	ID             string     `json:"id"`
+	RevisionNumber int        `json:"revision_number"`
+	Status         string     `json:"status"`
+	FileName       string     `json:"file_name"`
+	FileSizeBytes  *int64     `json:"file_size_bytes"`
+	ChecksumSHA256 *string    `json:"checksum_sha256"`
+	UploadedAt     time.Time  `json:"uploaded_at"`
+	UploadedBy     string     `json:"uploaded_by"`
+	DecisionBy     *string    `json:"decision_by"`
+	DecisionAt     *time.Time `json:"decision_at"`
+	AdminComment   *string    `json:"admin_comment"`
+	// DownloadURL is a short-lived signed link, absent once the file was deleted.
+	DownloadURL *string `json:"download_url,omitempty"`
+}
+
+type adminRequirementResponse struct {
+	ID                string                    `json:"id"`
+	Code              string                    `json:"code"`
+	Title             string                    `json:"title"`
+	Description       *string                   `json:"description"`
+	AcceptedMimeTypes []string                  `json:"accepted_mime_types"`
+	MaxFileSizeBytes  *int64                    `json:"max_file_size_bytes"`
+	IsMandatory       bool                      `json:"is_mandatory"`
+	Required          bool                      `json:"required"`
+	Submissions       []adminSubmissionResponse `json:"submissions"`
+}
+
+type generatedDocumentResponse struct {
+	ID            string     `json:"id"`
+	DocumentType  string     `json:"document_type"`
+	FileName      string     `json:"file_name"`
+	GeneratedBy   string     `json:"generated_by"`
+	GeneratedAt   time.Time  `json:"generated_at"`
+	InvalidatedAt *time.Time `json:"invalidated_at"`
+	InvalidatedBy *string    `json:"invalidated_by"`
+	DownloadURL   *string    `json:"download_url,omitempty"`
+}
+
+type studentSessionDetailResponse struct {
+	StudentSession      studentSessionListItemResponse `json:"student_session"`
+	CategoryDescription *string                        `json:"category_description"`
+	Questionnaire       questionnaireHistoryResponse   `json:"questionnaire"`
+	Requirements        []adminRequirementResponse     `json:"requirements"`
+	GeneratedDocuments  []generatedDocumentResponse    `json:"generated_documents"`
+	History             historyResponse                `json:"history"`
+}
+
+func (h *AdminHandler) handleGetStudentSession(w http.ResponseWriter, r *http.Request) {
+	studentSessionID := chi.URLParam(r, "id")
+	detail, err := h.Students.GetDetail(r.Context(), studentSessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "student session not found", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	timeline, err := h.Timeline.List(r.Context(), store.ListTimelineParams{
+		StudentSessionID: studentSessionID,
+		Limit:            detailHistoryLimit,
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := studentSessionDetailResponse{
+		StudentSession:      toStudentSessionListItemResponse(detail.StudentSessionListItem),
+		CategoryDescription: nullString(detail.CategoryDescription),
+		Requirements:        make([]adminRequirementResponse, 0, len(detail.Requirements)),
+		GeneratedDocuments:  make([]generatedDocumentResponse, 0, len(detail.GeneratedDocuments)),
+		History:             historyResponse{Events: make([]timelineEventResponse, 0, len(timeline.Events))},
+	}
+
+	resp.Questionnaire = toQuestionnaireHistoryResponse(detail.Questionnaire)
+
+	for _, item := range detail.Requirements {
+		req := item.Requirement
+		converted := adminRequirementResponse{
+			ID:                req.ID,
+			Code:              req.Code,
+			Title:             req.Title,
+			Description:       nullString(req.Description),
+			AcceptedMimeTypes: req.AcceptedMimeTypes,
+			MaxFileSizeBytes:  nullInt64(req.MaxFileSizeBytes),
+			IsMandatory:       req.IsMandatory,
+			Required:          item.Required,
+			Submissions:       make([]adminSubmissionResponse, 0, len(item.Submissions)),
+		}
+		if converted.AcceptedMimeTypes == nil {
+			converted.AcceptedMimeTypes = []string{}
+		}
+		for _, sub := range item.Submissions {
+			submission := adminSubmissionResponse{
+				ID:             sub.ID,
+				RevisionNumber: sub.RevisionNumber,
+				Status:         string(sub.Status),
+				FileName:       sub.FileName,
+				FileSizeBytes:  nullInt64(sub.FileSizeBytes),
+				ChecksumSHA256: nullString(sub.ChecksumSHA256),
+				UploadedAt:     sub.UploadedAt,
+				UploadedBy:     sub.UploadedBy,
+				DecisionBy:     nullString(sub.DecisionBy),
+				DecisionAt:     nullTime(sub.DecisionAt),
+				AdminComment:   nullString(sub.AdminComment),
+			}
+			if !sub.FileDeletedAt.Valid {
+				submission.DownloadURL = downloadURL(r.Context(), h.Storage, sub.StorageKey)
+			}
+			converted.Submissions = append(converted.Submissions, submission)
+		}
+		resp.Requirements = append(resp.Requirements, converted)
+	}
+
+	for _, doc := range detail.GeneratedDocuments {
+		resp.GeneratedDocuments = append(resp.GeneratedDocuments, toGeneratedDocumentResponse(doc, downloadURL(r.Context(), h.Storage, doc.StorageKey)))
+	}
+
+	for _, event := range timeline.Events {
+		resp.History.Events = append(resp.History.Events, toTimelineEventResponse(event))
+	}
+	if timeline.NextCursor != "" {
+		resp.History.NextCursor = &timeline.NextCursor
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
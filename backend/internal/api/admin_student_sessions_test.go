@@ -0,0 +1,49 @@
+package api
+
+import (
+	"testing"
+
+	"adm-backend/internal/store"
+)
+
+func TestToQuestionnaireHistoryResponse(t *testing.T) {
+	tests := []struct {
+		name              string
+		responses         []store.QuestionnaireResponse
+		current, previous string
+	}{
+		{name: "none"},
+		{
+			name:      "single response behind the current revision",
+			responses: []store.QuestionnaireResponse{{ID: "r1", RevisionNumber: 1}},
+			current:   "r1",
+		},
+		{
+			name: "two responses with a gap in revisions",
+			responses: []store.QuestionnaireResponse{
+				{ID: "r4", RevisionNumber: 4},
+				{ID: "r1", RevisionNumber: 1},
+			},
+			current:  "r4",
+			previous: "r1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := toQuestionnaireHistoryResponse(tt.responses)
+			if got := responseID(history.Current); got != tt.current {
+				t.Errorf("current = %q, want %q", got, tt.current)
+			}
+			if got := responseID(history.Previous); got != tt.previous {
+				t.Errorf("previous = %q, want %q", got, tt.previous)
+			}
+		})
+	}
+}
+
+func responseID(response *questionnaireResponseResponse) string {
+	if response == nil {
+		return ""
+	}
+	return response.ID
+}
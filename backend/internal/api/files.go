@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"adm-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// downloadURLTTL is how long the download links included in API responses stay valid.
+const downloadURLTTL = 15 * time.Minute
+
+// downloadURL signs a download link for the object at key, or returns nil when there
+// is no storage backend or file. A signing failure only drops the link from the
+// response, so it is logged rather than returned.
+func downloadURL(ctx context.Context, backend storage.Backend, key string) *string {
+	if backend == nil || key == "" {
+		return nil
+	}
+	signed, err := backend.SignedURL(ctx, key, downloadURLTTL)
+	if err != nil {
+		log.Printf("sign download url for %s: %v", key, err)
+		return nil
+	}
+	return &signed
+}
+
+// FilesHandler serves the filesystem storage backend's signed download URLs. The
+// signature is the only authorization, so the route is mounted without auth.
+type FilesHandler struct {
+	Storage *storage.Filesystem
+}
+
+func RegisterFileRoutes(r chi.Router, handler *FilesHandler) {
+	r.Get("/files", handler.handleDownload)
+}
+
+func (h *FilesHandler) handleDownload(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	key := query.Get("key")
+	if !h.Storage.VerifySignature(key, query.Get("expires"), query.Get("signature")) {
+		http.Error(w, "invalid or expired download link", http.StatusForbidden)
+		return
+	}
+
+	body, object, err := h.Storage.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer body.Close()
+
+	contentType := object.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(object.Key)}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, body)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"adm-backend/internal/storage"
+	"adm-backend/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestFilesHandlerServesSignedURLsOnly(t *testing.T) {
+	fs, err := storage.NewFilesystem(t.TempDir(), "http://adm.example/files", []byte("secret"))
+	if err != nil {
+		t.Fatalf("new filesystem: %v", err)
+	}
+	ctx := context.Background()
+	if err := fs.Put(ctx, "generated/ss/attestation.pdf", strings.NewReader("%PDF"), 4, "application/pdf"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	router := chi.NewRouter()
+	RegisterFileRoutes(router, &FilesHandler{Storage: fs})
+
+	get := func(rawURL string) *httptest.ResponseRecorder {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatalf("parse %q: %v", rawURL, err)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+		return rec
+	}
+
+	signed, err := fs.SignedURL(ctx, "generated/ss/attestation.pdf", time.Minute)
+	if err != nil {
+		t.Fatalf("signed url: %v", err)
+	}
+	rec := get(signed)
+	if rec.Code != http.StatusOK || rec.Body.String() != "%PDF" {
+		t.Fatalf("signed download: %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=attestation.pdf` {
+		t.Fatalf("content disposition = %q", got)
+	}
+
+	if rec := get(strings.Replace(signed, "attestation", "certificat", 1)); rec.Code != http.StatusForbidden {
+		t.Fatalf("tampered key: %d, want 403", rec.Code)
+	}
+	if rec := get("http://adm.example/files?key=generated/ss/attestation.pdf"); rec.Code != http.StatusForbidden {
+		t.Fatalf("unsigned: %d, want 403", rec.Code)
+	}
+
+	missing, _ := fs.SignedURL(ctx, "generated/ss/missing.pdf", time.Minute)
+	if rec := get(missing); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing file: %d, want 404", rec.Code)
+	}
+}
+
+func TestStudentSessionResponseLinksFiles(t *testing.T) {
+	fs, err := storage.NewFilesystem(t.TempDir(), "http://adm.example/files", []byte("secret"))
+	if err != nil {
+		t.Fatalf("new filesystem: %v", err)
+	}
+	ctx := context.Background()
+	if err := fs.Put(ctx, "uploads/ss/id_card.pdf", strings.NewReader("%PDF"), 4, "application/pdf"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	router := chi.NewRouter()
+	RegisterFileRoutes(router, &FilesHandler{Storage: fs})
+
+	current := &store.CurrentStudentSession{Slots: []store.DocumentSlot{
+		{Requirement: store.DocumentRequirement{ID: "r_id_card"}, Submission: &store.DocumentSubmission{ID: "sub1", StorageKey: "uploads/ss/id_card.pdf"}},
+		// The cleanup job already deleted this file.
+		{Requirement: store.DocumentRequirement{ID: "r_contract"}, Submission: &store.DocumentSubmission{
+			ID:            "sub2",
+			StorageKey:    "uploads/ss/contract.pdf",
+			FileDeletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		}},
+	}}
+	resp := toStudentSessionResponse(ctx, fs, current)
+
+	link := resp.Requirements[0].Submission.DownloadURL
+	if link == nil {
+		t.Fatalf("uploaded file has no download url")
+	}
+	u, err := url.Parse(*link)
+	if err != nil {
+		t.Fatalf("parse %q: %v", *link, err)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "%PDF" {
+		t.Fatalf("download: %d %q", rec.Code, rec.Body.String())
+	}
+	if link := resp.Requirements[1].Submission.DownloadURL; link != nil {
+		t.Fatalf("deleted file links to %q", *link)
+	}
+
+	// Without a storage backend the links are left out.
+	if link := toStudentSessionResponse(ctx, nil, current).Requirements[0].Submission.DownloadURL; link != nil {
+		t.Fatalf("download url without storage: %q", *link)
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import "context"
+
+const (
+	RoleAdmin   = "admin"
+	RoleStudent = "student"
+)
+
+// Identity is the authenticated caller extracted from a Pan-Bagnat token.
+type Identity struct {
+	Login string
+	Role  string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the caller identity.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller identity stored by the auth middleware.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok && identity.Login != ""
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+
+	"adm-backend/internal/jobs"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// JobsHandler exposes the background jobs so they can also be triggered on demand.
+type JobsHandler struct {
+	Lifecycle *jobs.SessionLifecycle
+	Cleanup   *jobs.StorageCleanup
+}
+
+type sessionExpirationsResponse struct {
+	Activated              []string `json:"activated_sessions"`
+	RostersBuilt           []string `json:"rosters_built"`
+	RosterFailures         int      `json:"roster_failures"`
+	Closed                 []string `json:"closed_sessions"`
+	ExpiredStudentSessions int      `json:"expired_student_sessions"`
+}
+
+type storageCleanupResponse struct {
+	Deleted int `json:"deleted"`
+	Retried int `json:"retried"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+func RegisterJobRoutes(r chi.Router, handler *JobsHandler) {
+	r.Post("/jobs/process-session-expirations", handler.handleProcessSessionExpirations)
+	r.Post("/jobs/cleanup-storage", handler.handleCleanupStorage)
+}
+
+func (h *JobsHandler) handleProcessSessionExpirations(w http.ResponseWriter, r *http.Request) {
+	result, err := h.Lifecycle.Process(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := sessionExpirationsResponse{
+		Activated:              result.Activated,
+		RostersBuilt:           result.RostersBuilt,
+		RosterFailures:         result.RosterFailures,
+		Closed:                 result.Closed,
+		ExpiredStudentSessions: result.ExpiredStudentSessions,
+	}
+	if resp.Activated == nil {
+		resp.Activated = []string{}
+	}
+	if resp.RostersBuilt == nil {
+		resp.RostersBuilt = []string{}
+	}
+	if resp.Closed == nil {
+		resp.Closed = []string{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *JobsHandler) handleCleanupStorage(w http.ResponseWriter, r *http.Request) {
+	result, err := h.Cleanup.Process(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, storageCleanupResponse{
+		Deleted: result.Deleted,
+		Retried: result.Retried,
+		Failed:  result.Failed,
+		Skipped: result.Skipped,
+	})
+}
@@ -1,32 +1,348 @@
 package api
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
+
+	"adm-backend/internal/questionnaire"
+	"adm-backend/internal/storage"
+	"adm-backend/internal/store"
+	"adm-backend/internal/workflow"
 
 	"github.com/go-chi/chi/v5"
 )
 
+type StudentHandler struct {
+	Students   *store.StudentSessionStore
+	Categories *store.CategoryStore
+	Timeline   *store.TimelineStore
+	Storage    storage.Backend
+}
+
+type questionnaireRequest struct {
+	Answers json.RawMessage `json:"answers"`
+}
+
+type studentCategoryResponse struct {
+	ID          string  `json:"id"`
+	Code        string  `json:"code"`
+	Label       string  `json:"label"`
+	Description *string `json:"description"`
+}
+
+type studentSubmissionResponse struct {
+	ID             string     `json:"id"`
+	RevisionNumber int        `json:"revision_number"`
+	Status         string     `json:"status"`
+	FileName       string     `json:"file_name"`
+	FileSizeBytes  *int64     `json:"file_size_bytes"`
+	UploadedAt     time.Time  `json:"uploaded_at"`
+	DecisionAt     *time.Time `json:"decision_at"`
+	AdminComment   *string    `json:"admin_comment"`
+	DownloadURL    *string    `json:"download_url,omitempty"`
+}
+
+type studentRequirementResponse struct {
+	ID                string                     `json:"id"`
+	Code              string                     `json:"code"`
+	Title             string                     `json:"title"`
+	Description       *string                    `json:"description"`
+	AcceptedMimeTypes []string                   `json:"accepted_mime_types"`
+	MaxFileSizeBytes  *int64                     `json:"max_file_size_bytes"`
+	IsMandatory       bool                       `json:"is_mandatory"`
+	Submission        *studentSubmissionResponse `json:"submission"`
+	PreviousDecision  *studentSubmissionResponse `json:"previous_decision"`
+}
+
+type studentSessionResponse struct {
+	ID                 string                       `json:"id"`
+	AdmSessionID       string                       `json:"adm_session_id"`
+	AdmSessionLabel    string                       `json:"adm_session_label"`
+	StartAt            time.Time                    `json:"start_at"`
+	EndAt              time.Time                    `json:"end_at"`
+	StudentLogin       string                       `json:"student_login"`
+	Status             string                       `json:"status"`
+	CurrentRevision    int                          `json:"current_revision"`
+	LockedByStudent    bool                         `json:"locked_by_student"`
+	LockedByAdmin      bool                         `json:"locked_by_admin"`
+	InvalidationReason *string                      `json:"invalidation_reason"`
+	LastSubmittedAt    *time.Time                   `json:"last_submitted_at"`
+	LastReviewedAt     *time.Time                   `json:"last_reviewed_at"`
+	Category           *studentCategoryResponse     `json:"category"`
+	Requirements       []studentRequirementResponse `json:"requirements"`
+}
+
+type missingRequirementsResponse struct {
+	Error               string   `json:"error"`
+	MissingRequirements []string `json:"missing_requirements"`
+}
+
+type currentStudentSessionResponse struct {
+	Session *studentSessionResponse `json:"session"`
+}
+
 // RegisterStudentRoutes attaches student-facing handlers to the provided chi router.
-func RegisterStudentRoutes(r chi.Router) {
-	r.Get("/sessions/current", func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(`{"session": null, "message": "Student session endpoint placeholder"}`))
-	})
+func RegisterStudentRoutes(r chi.Router, handler *StudentHandler) {
+	r.Get("/sessions/current", handler.handleGetCurrentSession)
+	r.Get("/sessions/current/history", handler.handleGetCurrentHistory)
+
+	r.Post("/sessions/current/questionnaire", handler.handleSubmitQuestionnaire)
+	r.Post("/sessions/current/documents/{requirementId}", handler.handleUploadDocument)
+
+	r.Post("/sessions/current/submit", handler.handleSubmitForValidation)
+}
+
+func (h *StudentHandler) handleGetCurrentSession(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
 
-	r.Post("/sessions/current/questionnaire", func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusAccepted)
-		if _, err := w.Write([]byte(`{"message": "Questionnaire submission placeholder"}`)); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	current, err := h.Students.GetCurrentForLogin(r.Context(), identity.Login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusOK, currentStudentSessionResponse{})
+			return
 		}
-	})
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, currentStudentSessionResponse{Session: toStudentSessionResponse(r.Context(), h.Storage, current)})
+}
+
+func (h *StudentHandler) handleSubmitQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Body == nil {
+		http.Error(w, "missing body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var payload questionnaireRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	var answers questionnaire.Answers
+	if err := json.Unmarshal(payload.Answers, &answers); err != nil || answers == nil {
+		http.Error(w, "answers must be a json object", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.Students.GetCurrentForLogin(r.Context(), identity.Login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "no active ADM session for this student", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
 
-	r.Post("/sessions/current/submit", func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusAccepted)
-		if _, err := w.Write([]byte(`{"message": "Submit for validation placeholder"}`)); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	if _, err := workflow.Fire(current.Status, workflow.CompleteQuestionnaire, workflow.Input{}); err != nil {
+		respondError(w, http.StatusConflict, err)
+		return
+	}
+
+	categories, err := h.Categories.ListActive(r.Context(), current.AdmSessionID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	candidates := make([]questionnaire.Candidate, 0, len(categories))
+	for _, category := range categories {
+		candidates = append(candidates, questionnaire.Candidate{
+			ID:    category.ID,
+			Code:  category.Code,
+			Logic: category.QuestionnaireLogic,
+		})
+	}
+
+	chosen, err := questionnaire.Evaluate(answers, candidates)
+	if err != nil {
+		var qErr *questionnaire.Error
+		if errors.As(err, &qErr) {
+			status := http.StatusUnprocessableEntity
+			if qErr.Code == questionnaire.CodeInvalidLogic {
+				status = http.StatusInternalServerError
+			}
+			writeJSON(w, status, qErr)
+			return
 		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.Students.SubmitQuestionnaire(r.Context(), store.SubmitQuestionnaireParams{
+		StudentSessionID: current.ID,
+		Revision:         current.CurrentRevision,
+		Answers:          payload.Answers,
+		CategoryID:       chosen.ID,
+		SubmittedBy:      identity.Login,
 	})
+	if err != nil {
+		if isTransitionConflict(err) {
+			respondError(w, http.StatusConflict, err)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updated, err := h.Students.GetCurrentForLogin(r.Context(), identity.Login)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, currentStudentSessionResponse{Session: toStudentSessionResponse(r.Context(), h.Storage, updated)})
+}
+
+func (h *StudentHandler) handleSubmitForValidation(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
+
+	current, err := h.Students.GetCurrentForLogin(r.Context(), identity.Login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "no active ADM session for this student", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.Students.SubmitForValidation(r.Context(), current.ID, current.CurrentRevision, identity.Login)
+	if err != nil {
+		var missing *workflow.MissingRequirementsError
+		switch {
+		case errors.As(err, &missing):
+			writeJSON(w, http.StatusUnprocessableEntity, missingRequirementsResponse{
+				Error:               "some mandatory documents are missing",
+				MissingRequirements: missing.Codes,
+			})
+		case isTransitionConflict(err):
+			respondError(w, http.StatusConflict, err)
+		default:
+			respondError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	updated, err := h.Students.GetCurrentForLogin(r.Context(), identity.Login)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, currentStudentSessionResponse{Session: toStudentSessionResponse(r.Context(), h.Storage, updated)})
+}
+
+func toStudentSessionResponse(ctx context.Context, backend storage.Backend, current *store.CurrentStudentSession) *studentSessionResponse {
+	resp := &studentSessionResponse{
+		ID:                 current.ID,
+		AdmSessionID:       current.AdmSessionID,
+		AdmSessionLabel:    current.SessionLabel,
+		StartAt:            current.SessionStartAt,
+		EndAt:              current.SessionEndAt,
+		StudentLogin:       current.StudentLogin,
+		Status:             string(current.Status),
+		CurrentRevision:    current.CurrentRevision,
+		LockedByStudent:    current.LockedByStudent,
+		LockedByAdmin:      current.LockedByAdmin,
+		InvalidationReason: nullString(current.InvalidationReason),
+		LastSubmittedAt:    nullTime(current.LastSubmittedAt),
+		LastReviewedAt:     nullTime(current.LastReviewedAt),
+		Requirements:       make([]studentRequirementResponse, 0, len(current.Slots)),
+	}
+
+	if current.Category != nil {
+		resp.Category = &studentCategoryResponse{
+			ID:          current.Category.ID,
+			Code:        current.Category.Code,
+			Label:       current.Category.Label,
+			Description: nullString(current.Category.Description),
+		}
+	}
+
+	for _, slot := range current.Slots {
+		req := slot.Requirement
+		item := studentRequirementResponse{
+			ID:                req.ID,
+			Code:              req.Code,
+			Title:             req.Title,
+			Description:       nullString(req.Description),
+			AcceptedMimeTypes: req.AcceptedMimeTypes,
+			MaxFileSizeBytes:  nullInt64(req.MaxFileSizeBytes),
+			IsMandatory:       req.IsMandatory,
+		}
+		if item.AcceptedMimeTypes == nil {
+			item.AcceptedMimeTypes = []string{}
+		}
+		if sub := slot.Submission; sub != nil {
+			item.Submission = &studentSubmissionResponse{
+				ID:             sub.ID,
+				RevisionNumber: sub.RevisionNumber,
+				Status:         string(sub.Status),
+				FileName:       sub.FileName,
+				FileSizeBytes:  nullInt64(sub.FileSizeBytes),
+				UploadedAt:     sub.UploadedAt,
+				DecisionAt:     nullTime(sub.DecisionAt),
+				AdminComment:   nullString(sub.AdminComment),
+			}
+			if !sub.FileDeletedAt.Valid {
+				item.Submission.DownloadURL = downloadURL(ctx, backend, sub.StorageKey)
+			}
+		}
+		if prev := slot.PreviousSubmission; prev != nil {
+			item.PreviousDecision = &studentSubmissionResponse{
+				ID:             prev.ID,
+				RevisionNumber: prev.RevisionNumber,
+				Status:         string(prev.Status),
+				FileName:       prev.FileName,
+				DecisionAt:     nullTime(prev.DecisionAt),
+				AdminComment:   nullString(prev.AdminComment),
+			}
+		}
+		resp.Requirements = append(resp.Requirements, item)
+	}
+
+	return resp
+}
+
+func nullString(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+func nullInt64(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
 }
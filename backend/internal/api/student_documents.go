@@ -0,0 +1,259 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/store"
+	"adm-backend/internal/workflow"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultMaxUploadBytes caps uploads for requirements that do not set max_file_size_bytes.
+const defaultMaxUploadBytes int64 = 20 << 20
+
+var errFileTooLarge = errors.New("file exceeds the maximum allowed size")
+
+type uploadResponse struct {
+	Submission studentSubmissionResponse `json:"submission"`
+}
+
+func (h *StudentHandler) handleUploadDocument(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
+	if h.Storage == nil {
+		respondError(w, http.StatusInternalServerError, errors.New("storage backend not configured"))
+		return
+	}
+
+	requirementID := chi.URLParam(r, "requirementId")
+
+	current, err := h.Students.GetCurrentForLogin(r.Context(), identity.Login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "no active ADM session for this student", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	guardInput := workflow.Input{LockedByStudent: current.LockedByStudent}
+	if _, err := workflow.Fire(current.Status, workflow.UploadDocument, guardInput); err != nil {
+		respondError(w, http.StatusConflict, err)
+		return
+	}
+
+	var slot *store.DocumentSlot
+	for i := range current.Slots {
+		if current.Slots[i].Requirement.ID == requirementID {
+			slot = &current.Slots[i]
+			break
+		}
+	}
+	if slot == nil {
+		http.Error(w, "requirement not found for this student", http.StatusNotFound)
+		return
+	}
+	if sub := slot.Submission; sub != nil && sub.Status != workflow.SubmissionPending {
+		if _, err := workflow.FireSubmission(sub.Status, workflow.ReplaceFile, guardInput); err != nil {
+			respondError(w, http.StatusConflict, err)
+			return
+		}
+	}
+
+	maxSize := defaultMaxUploadBytes
+	if slot.Requirement.MaxFileSizeBytes.Valid && slot.Requirement.MaxFileSizeBytes.Int64 > 0 {
+		maxSize = slot.Requirement.MaxFileSizeBytes.Int64
+	}
+	// Leave room for the multipart envelope around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "expected a multipart/form-data body", http.StatusBadRequest)
+		return
+	}
+
+	var part *multipart.Part
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, "invalid multipart body", http.StatusBadRequest)
+			return
+		}
+		if p.FormName() == "file" {
+			part = p
+			defer p.Close()
+			break
+		}
+		p.Close()
+	}
+	if part == nil {
+		http.Error(w, `missing "file" form field`, http.StatusBadRequest)
+		return
+	}
+
+	fileName := sanitizeFileName(part.FileName())
+	if fileName == "" {
+		http.Error(w, "missing file name", http.StatusBadRequest)
+		return
+	}
+
+	buffered := bufio.NewReaderSize(part, 512)
+	head, _ := buffered.Peek(512)
+	// Rejected before anything reaches the storage backend.
+	if len(head) == 0 {
+		http.Error(w, "uploaded file is empty", http.StatusBadRequest)
+		return
+	}
+	contentType := detectContentType(head, fileName)
+	if !mimeAccepted(contentType, slot.Requirement.AcceptedMimeTypes) {
+		http.Error(w, "file type "+contentType+" is not accepted for this document", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	submissionID, err := ids.New("adm_document_submission")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	storageKey := submissionStorageKey(current.ID, requirementID, current.CurrentRevision, submissionID, fileName)
+
+	counter := &hashingLimitReader{r: buffered, limit: maxSize, hash: sha256.New()}
+	if err := h.Storage.Put(r.Context(), storageKey, counter, -1, contentType); err != nil {
+		_ = h.Storage.Delete(r.Context(), storageKey)
+		var maxErr *http.MaxBytesError
+		if errors.Is(err, errFileTooLarge) || errors.As(err, &maxErr) {
+			http.Error(w, errFileTooLarge.Error()+" ("+strconv.FormatInt(maxSize, 10)+" bytes)", http.StatusRequestEntityTooLarge)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	submission, err := h.Students.SaveUpload(r.Context(), store.SaveUploadParams{
+		SubmissionID:     submissionID,
+		StudentSessionID: current.ID,
+		RequirementID:    requirementID,
+		Revision:         current.CurrentRevision,
+		StorageKey:       storageKey,
+		FileName:         fileName,
+		FileSizeBytes:    counter.n,
+		ChecksumSHA256:   hex.EncodeToString(counter.hash.Sum(nil)),
+		UploadedBy:       identity.Login,
+	})
+	if err != nil {
+		_ = h.Storage.Delete(r.Context(), storageKey)
+		if isTransitionConflict(err) {
+			respondError(w, http.StatusConflict, err)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, uploadResponse{Submission: studentSubmissionResponse{
+		ID:             submission.ID,
+		RevisionNumber: submission.RevisionNumber,
+		Status:         string(submission.Status),
+		FileName:       submission.FileName,
+		FileSizeBytes:  nullInt64(submission.FileSizeBytes),
+		UploadedAt:     submission.UploadedAt,
+	}})
+}
+
+// hashingLimitReader hashes and counts what it reads and fails once limit is exceeded.
+type hashingLimitReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+	hash  hash.Hash
+}
+
+func (h *hashingLimitReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	if n > 0 {
+		h.n += int64(n)
+		if h.n > h.limit {
+			return 0, errFileTooLarge
+		}
+		h.hash.Write(p[:n])
+	}
+	return n, err
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
+
+// detectContentType sniffs the file content, falling back to the file extension
+// when the content is not recognised.
+func detectContentType(head []byte, fileName string) string {
+	sniffed := http.DetectContentType(head)
+	if sniffed != "application/octet-stream" && !strings.HasPrefix(sniffed, "text/plain") {
+		return stripMimeParams(sniffed)
+	}
+	if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName))); byExt != "" {
+		return stripMimeParams(byExt)
+	}
+	return stripMimeParams(sniffed)
+}
+
+func stripMimeParams(value string) string {
+	if mediaType, _, err := mime.ParseMediaType(value); err == nil {
+		return mediaType
+	}
+	return value
+}
+
+// mimeAccepted matches contentType against accepted entries such as
+// "application/pdf" or "image/*". An empty list accepts everything.
+func mimeAccepted(contentType string, accepted []string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	for _, candidate := range accepted {
+		candidate = strings.ToLower(strings.TrimSpace(candidate))
+		if candidate == contentType || candidate == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(candidate, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func submissionStorageKey(studentSessionID, requirementID string, revision int, submissionID, fileName string) string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if len(ext) > 10 {
+		ext = ""
+	}
+	return "submissions/" + studentSessionID + "/" + requirementID + "/r" + strconv.Itoa(revision) + "/" + submissionID + ext
+}
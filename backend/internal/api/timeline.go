@@ -0,0 +1,140 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"adm-backend/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+type timelineEventResponse struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedBy *string         `json:"created_by"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type historyResponse struct {
+	Events     []timelineEventResponse `json:"events"`
+	NextCursor *string                 `json:"next_cursor"`
+}
+
+func (h *StudentHandler) handleGetCurrentHistory(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "missing caller identity", http.StatusUnauthorized)
+		return
+	}
+
+	current, err := h.Students.GetCurrentForLogin(r.Context(), identity.Login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "no active ADM session for this student", http.StatusNotFound)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeHistory(w, r, h.Timeline, current.ID)
+}
+
+func (h *AdminHandler) handleGetStudentSessionHistory(w http.ResponseWriter, r *http.Request) {
+	writeHistory(w, r, h.Timeline, chi.URLParam(r, "id"))
+}
+
+// writeHistory serves one page of a student session's timeline. Query parameters:
+// limit (1-200), cursor (from next_cursor) and type (repeatable or comma-separated).
+func writeHistory(w http.ResponseWriter, r *http.Request, timeline *store.TimelineStore, studentSessionID string) {
+	query := r.URL.Query()
+
+	limit := defaultHistoryLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxHistoryLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	types, err := parseTimelineTypes(query["type"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := timeline.List(r.Context(), store.ListTimelineParams{
+		StudentSessionID: studentSessionID,
+		Types:            types,
+		Cursor:           query.Get("cursor"),
+		Limit:            limit,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "student session not found", http.StatusNotFound)
+		case errors.Is(err, store.ErrInvalidCursor):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			respondError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	resp := historyResponse{Events: make([]timelineEventResponse, 0, len(page.Events))}
+	for _, event := range page.Events {
+		resp.Events = append(resp.Events, toTimelineEventResponse(event))
+	}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func toTimelineEventResponse(event store.TimelineEvent) timelineEventResponse {
+	return timelineEventResponse{
+		ID:        event.ID,
+		Type:      string(event.Type),
+		Payload:   event.Payload,
+		CreatedBy: nullString(event.CreatedBy),
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+func parseTimelineTypes(values []string) ([]store.TimelineEventType, error) {
+	known := make(map[store.TimelineEventType]struct{}, len(store.TimelineEventTypes))
+	for _, t := range store.TimelineEventTypes {
+		known[t] = struct{}{}
+	}
+
+	var types []store.TimelineEventType
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			eventType := store.TimelineEventType(part)
+			if _, ok := known[eventType]; !ok {
+				return nil, fmt.Errorf("unknown event type %q", part)
+			}
+			types = append(types, eventType)
+		}
+	}
+	return types, nil
+}
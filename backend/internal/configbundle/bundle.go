@@ -0,0 +1,296 @@
+// Package configbundle defines the portable form of an ADM session configuration,
+// used to keep configurations in version control and review changes before import.
+//
+// A bundle is written in YAML or JSON with the same field names:
+//
+//	format: adm-configuration
+//	version: 1
+//	source:                      # informational, ignored on import
+//	  session_id: adm_session_01...
+//	  label: ADM 2026
+//	  configuration_version: 4
+//	eligibility:                 # optional, see package eligibility
+//	  campuses: [paris]
+//	questionnaire: {...}         # optional, opaque questionnaire schema for the student UI
+//	requirements:                # listed in reminder order
+//	  - code: id-card
+//	    title: Identity card
+//	    description: Both sides
+//	    accepted_mime_types: [application/pdf, image/jpeg]
+//	    max_file_size_bytes: 5242880
+//	    is_mandatory: true       # defaults to true
+//	categories:
+//	  - code: alternant
+//	    label: Apprentice
+//	    is_active: true          # defaults to true
+//	    questionnaire_logic: {...}  # see package questionnaire
+//	    requirements: [id-card]  # requirement codes
+//
+// Rows are matched by code, never by ID, so a bundle exported from one session can be
+// imported into another. Import creates and updates rows but never deletes them:
+// categories and requirements missing from the bundle are reported as untouched.
+package configbundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"adm-backend/internal/eligibility"
+	"adm-backend/internal/questionnaire"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Format identifies a configuration bundle.
+	Format = "adm-configuration"
+	// Version is the bundle format version this package reads and writes.
+	Version = 1
+
+	// QuestionnaireKey is the adm_sessions.configuration key holding the questionnaire schema.
+	QuestionnaireKey = "questionnaire"
+	// VersionKey is the adm_sessions.configuration key counting configuration changes.
+	VersionKey = "configuration_version"
+)
+
+var codePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ValidCode reports whether code may identify a category or requirement: 1-64
+// lowercase letters, digits, '-' or '_', starting with a letter or digit.
+func ValidCode(code string) bool {
+	return codePattern.MatchString(code)
+}
+
+type Bundle struct {
+	Format        string          `json:"format"`
+	Version       int             `json:"version"`
+	Source        *Source         `json:"source,omitempty"`
+	Eligibility   json.RawMessage `json:"eligibility,omitempty"`
+	Questionnaire json.RawMessage `json:"questionnaire,omitempty"`
+	Requirements  []Requirement   `json:"requirements"`
+	Categories    []Category      `json:"categories"`
+}
+
+// Source records where a bundle was exported from.
+type Source struct {
+	SessionID            string `json:"session_id,omitempty"`
+	Label                string `json:"label,omitempty"`
+	ConfigurationVersion int    `json:"configuration_version,omitempty"`
+}
+
+type Requirement struct {
+	Code              string   `json:"code"`
+	Title             string   `json:"title"`
+	Description       string   `json:"description,omitempty"`
+	AcceptedMimeTypes []string `json:"accepted_mime_types,omitempty"`
+	MaxFileSizeBytes  int64    `json:"max_file_size_bytes,omitempty"`
+	IsMandatory       *bool    `json:"is_mandatory,omitempty"`
+}
+
+type Category struct {
+	Code               string          `json:"code"`
+	Label              string          `json:"label"`
+	Description        string          `json:"description,omitempty"`
+	IsActive           *bool           `json:"is_active,omitempty"`
+	QuestionnaireLogic json.RawMessage `json:"questionnaire_logic,omitempty"`
+	Requirements       []string        `json:"requirements"`
+}
+
+// Mandatory reports the requirement's flag, which defaults to true.
+func (r Requirement) Mandatory() bool {
+	return r.IsMandatory == nil || *r.IsMandatory
+}
+
+// Active reports the category's flag, which defaults to true.
+func (c Category) Active() bool {
+	return c.IsActive == nil || *c.IsActive
+}
+
+// Parse decodes a YAML or JSON bundle and validates it. Unknown fields are rejected
+// so that typos do not silently drop settings.
+func Parse(data []byte) (*Bundle, error) {
+	raw := bytes.TrimSpace(data)
+	if len(raw) == 0 {
+		return nil, errors.New("empty configuration bundle")
+	}
+	if raw[0] != '{' {
+		var doc any
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("parse configuration bundle: %w", err)
+		}
+		// Round-trip through JSON so YAML keys follow the same tags as JSON bundles.
+		var err error
+		if raw, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("parse configuration bundle: %w", err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	var bundle Bundle
+	if err := decoder.Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("parse configuration bundle: %w", err)
+	}
+	if err := bundle.normalize(); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// normalize validates the bundle and rewrites values to the form stored in the database.
+func (b *Bundle) normalize() error {
+	if b.Format != Format {
+		return fmt.Errorf("format must be %q", Format)
+	}
+	if b.Version != Version {
+		return fmt.Errorf("unsupported bundle version %d (expected %d)", b.Version, Version)
+	}
+
+	if isNull(b.Eligibility) {
+		b.Eligibility = nil
+	} else {
+		rule, err := eligibility.Parse(b.Eligibility)
+		if err != nil {
+			return err
+		}
+		if b.Eligibility, err = json.Marshal(rule); err != nil {
+			return err
+		}
+	}
+	if isNull(b.Questionnaire) {
+		b.Questionnaire = nil
+	} else {
+		b.Questionnaire = compact(b.Questionnaire)
+	}
+
+	requirements := make(map[string]struct{}, len(b.Requirements))
+	for i := range b.Requirements {
+		req := &b.Requirements[i]
+		req.Code = strings.TrimSpace(req.Code)
+		if !ValidCode(req.Code) {
+			return fmt.Errorf("requirement %d: invalid code %q", i+1, req.Code)
+		}
+		if _, dup := requirements[req.Code]; dup {
+			return fmt.Errorf("requirement %q is listed twice", req.Code)
+		}
+		requirements[req.Code] = struct{}{}
+
+		req.Title = strings.TrimSpace(req.Title)
+		if req.Title == "" {
+			return fmt.Errorf("requirement %q: title is required", req.Code)
+		}
+		req.Description = strings.TrimSpace(req.Description)
+		for j, mimeType := range req.AcceptedMimeTypes {
+			mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+			if !strings.Contains(mimeType, "/") {
+				return fmt.Errorf("requirement %q: invalid mime type %q", req.Code, mimeType)
+			}
+			req.AcceptedMimeTypes[j] = mimeType
+		}
+		if req.MaxFileSizeBytes < 0 {
+			return fmt.Errorf("requirement %q: max_file_size_bytes must be positive", req.Code)
+		}
+	}
+
+	categories := make(map[string]struct{}, len(b.Categories))
+	for i := range b.Categories {
+		category := &b.Categories[i]
+		category.Code = strings.TrimSpace(category.Code)
+		if !ValidCode(category.Code) {
+			return fmt.Errorf("category %d: invalid code %q", i+1, category.Code)
+		}
+		if _, dup := categories[category.Code]; dup {
+			return fmt.Errorf("category %q is listed twice", category.Code)
+		}
+		categories[category.Code] = struct{}{}
+
+		category.Label = strings.TrimSpace(category.Label)
+		if category.Label == "" {
+			return fmt.Errorf("category %q: label is required", category.Code)
+		}
+		category.Description = strings.TrimSpace(category.Description)
+		if isNull(category.QuestionnaireLogic) {
+			category.QuestionnaireLogic = nil
+		} else {
+			if _, err := questionnaire.ParseLogic(category.QuestionnaireLogic); err != nil {
+				return fmt.Errorf("category %q: invalid questionnaire_logic: %w", category.Code, err)
+			}
+			category.QuestionnaireLogic = compact(category.QuestionnaireLogic)
+		}
+
+		linked := make(map[string]struct{}, len(category.Requirements))
+		for _, code := range category.Requirements {
+			if _, ok := requirements[code]; !ok {
+				return fmt.Errorf("category %q: unknown requirement %q", category.Code, code)
+			}
+			if _, dup := linked[code]; dup {
+				return fmt.Errorf("category %q: requirement %q is listed twice", category.Code, code)
+			}
+			linked[code] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// Encode writes the bundle as indented JSON, or as YAML when format is "yaml".
+func Encode(b *Bundle, format string) ([]byte, error) {
+	out, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode configuration bundle: %w", err)
+	}
+	switch format {
+	case "json":
+		return append(out, '\n'), nil
+	case "yaml":
+		return jsonToYAML(out)
+	default:
+		return nil, fmt.Errorf("unknown bundle format %q", format)
+	}
+}
+
+// jsonToYAML re-renders a JSON document as block-style YAML. JSON is valid YAML, so
+// decoding it into a node keeps the field order of the Go structs; clearing the
+// flow and quoting styles then lets the encoder pick the plain YAML forms.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("encode configuration bundle: %w", err)
+	}
+	resetStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, fmt.Errorf("encode configuration bundle: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("encode configuration bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+func isNull(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// compact drops insignificant whitespace so that equal documents compare equal.
+func compact(raw json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
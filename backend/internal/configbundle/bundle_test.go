@@ -0,0 +1,153 @@
+package configbundle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleYAML = `
+format: adm-configuration
+version: 1
+eligibility:
+  campuses: [Paris]
+requirements:
+  - code: id-card
+    title: Identity card
+    accepted_mime_types: [Application/PDF]
+    max_file_size_bytes: 5242880
+  - code: contract
+    title: Apprenticeship contract
+    is_mandatory: false
+categories:
+  - code: alternant
+    label: Apprentice
+    questionnaire_logic:
+      required: [status]
+      rule: {question: status, equals: alternant}
+    requirements: [id-card, contract]
+  - code: initial
+    label: Initial training
+    is_active: false
+    requirements: [id-card]
+`
+
+func TestParseYAMLMatchesJSON(t *testing.T) {
+	fromYAML, err := Parse([]byte(sampleYAML))
+	if err != nil {
+		t.Fatalf("parse yaml: %v", err)
+	}
+
+	encoded, err := Encode(fromYAML, "json")
+	if err != nil {
+		t.Fatalf("encode json: %v", err)
+	}
+	fromJSON, err := Parse(encoded)
+	if err != nil {
+		t.Fatalf("parse json: %v", err)
+	}
+	if diff := Compare(fromYAML, fromJSON); !diff.Empty() {
+		t.Fatalf("json round trip changed the bundle: %+v", diff.Changes)
+	}
+
+	if got := string(fromYAML.Eligibility); got != `{"campuses":["Paris"]}` {
+		t.Errorf("eligibility = %s", got)
+	}
+	if got := fromYAML.Requirements[0].AcceptedMimeTypes; !reflect.DeepEqual(got, []string{"application/pdf"}) {
+		t.Errorf("mime types = %v", got)
+	}
+	if !fromYAML.Requirements[0].Mandatory() || fromYAML.Requirements[1].Mandatory() {
+		t.Errorf("is_mandatory should default to true and honour false")
+	}
+	if !fromYAML.Categories[0].Active() || fromYAML.Categories[1].Active() {
+		t.Errorf("is_active should default to true and honour false")
+	}
+}
+
+func TestEncodeYAMLRoundTrip(t *testing.T) {
+	bundle, err := Parse([]byte(sampleYAML))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	bundle.Requirements[0].Title = "123"
+
+	out, err := Encode(bundle, "yaml")
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !strings.HasPrefix(string(out), "format: adm-configuration\nversion: 1\n") {
+		t.Fatalf("fields are not in struct order:\n%s", out)
+	}
+
+	again, err := Parse(out)
+	if err != nil {
+		t.Fatalf("parse encoded bundle: %v\n%s", err, out)
+	}
+	if again.Requirements[0].Title != "123" {
+		t.Errorf("numeric-looking string lost its type: %q", again.Requirements[0].Title)
+	}
+	if diff := Compare(bundle, again); !diff.Empty() {
+		t.Fatalf("yaml round trip changed the bundle: %+v", diff.Changes)
+	}
+}
+
+func TestParseRejectsInvalidBundles(t *testing.T) {
+	for name, tc := range map[string]struct {
+		bundle string
+		want   string
+	}{
+		"format":      {"format: other\nversion: 1\n", "format must be"},
+		"version":     {"format: adm-configuration\nversion: 2\n", "unsupported bundle version"},
+		"unknown":     {"format: adm-configuration\nversion: 1\nextra: true\n", "unknown field"},
+		"code":        {"format: adm-configuration\nversion: 1\nrequirements: [{code: Bad Code, title: x}]\n", "invalid code"},
+		"duplicate":   {"format: adm-configuration\nversion: 1\nrequirements: [{code: a, title: x}, {code: a, title: y}]\n", "listed twice"},
+		"link":        {"format: adm-configuration\nversion: 1\ncategories: [{code: a, label: A, requirements: [missing]}]\n", `unknown requirement "missing"`},
+		"logic":       {"format: adm-configuration\nversion: 1\ncategories: [{code: a, label: A, questionnaire_logic: {required: []}}]\n", "invalid questionnaire_logic"},
+		"mime":        {"format: adm-configuration\nversion: 1\nrequirements: [{code: a, title: x, accepted_mime_types: [pdf]}]\n", "invalid mime type"},
+		"eligibility": {"format: adm-configuration\nversion: 1\neligibility: {pool_years: [12]}\n", "pool year"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.bundle))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	current, err := Parse([]byte(sampleYAML))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// The database returns JSONB with its own key order and whitespace.
+	current.Categories[0].QuestionnaireLogic = []byte(`{"rule": {"equals": "alternant", "question": "status"}, "required": ["status"]}`)
+	current.Categories = append(current.Categories, Category{Code: "legacy", Label: "Legacy"})
+
+	next, err := Parse([]byte(sampleYAML))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	next.Requirements[0], next.Requirements[1] = next.Requirements[1], next.Requirements[0]
+	next.Requirements[1].Title = "National identity card"
+	next.Requirements = append(next.Requirements, Requirement{Code: "photo", Title: "Photo"})
+	next.Categories[1].Requirements = []string{"id-card", "photo"}
+	next.Eligibility = nil
+
+	diff := Compare(current, next)
+	want := []Change{
+		{Kind: KindRequirement, Action: ActionUpdate, Code: "contract", Fields: []string{"position"}},
+		{Kind: KindRequirement, Action: ActionUpdate, Code: "id-card", Fields: []string{"title", "position"}},
+		{Kind: KindRequirement, Action: ActionCreate, Code: "photo"},
+		{Kind: KindCategory, Action: ActionUpdate, Code: "initial", Fields: []string{"requirements"}},
+	}
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("changes:\n%+v\nwant:\n%+v", diff.Changes, want)
+	}
+	if want := []Ref{{Kind: KindCategory, Code: "legacy"}}; !reflect.DeepEqual(diff.Untouched, want) {
+		t.Errorf("untouched = %+v, want %+v", diff.Untouched, want)
+	}
+	if got := RequirementOrder(current, next); !reflect.DeepEqual(got, []string{"contract", "id-card", "photo"}) {
+		t.Errorf("order = %v", got)
+	}
+}
@@ -0,0 +1,205 @@
+package configbundle
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+)
+
+// Kinds of configuration items reported in a Diff.
+const (
+	KindEligibility   = "eligibility"
+	KindQuestionnaire = "questionnaire"
+	KindRequirement   = "requirement"
+	KindCategory      = "category"
+)
+
+// Actions reported in a Diff.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+)
+
+// Change is one item an import creates or updates. Fields lists the changed
+// fields of an update using their bundle names.
+type Change struct {
+	Kind   string   `json:"kind"`
+	Action string   `json:"action"`
+	Code   string   `json:"code,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Ref names a category or requirement.
+type Ref struct {
+	Kind string `json:"kind"`
+	Code string `json:"code"`
+}
+
+// Diff describes what importing a bundle changes in a session configuration.
+type Diff struct {
+	Changes []Change `json:"changes"`
+	// Untouched lists categories and requirements of the session that the bundle
+	// does not mention; import keeps them as they are.
+	Untouched []Ref `json:"untouched"`
+}
+
+// Empty reports whether the import would change nothing.
+func (d Diff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// Compare lists the changes importing next brings to current, the bundle exported
+// from the target session. Requirements keep the order of next, followed by the
+// untouched ones in their current order.
+func Compare(current, next *Bundle) Diff {
+	var diff Diff
+
+	if next.Eligibility != nil && !sameJSON(current.Eligibility, next.Eligibility) {
+		diff.Changes = append(diff.Changes, Change{Kind: KindEligibility, Action: actionFor(current.Eligibility != nil)})
+	}
+	if next.Questionnaire != nil && !sameJSON(current.Questionnaire, next.Questionnaire) {
+		diff.Changes = append(diff.Changes, Change{Kind: KindQuestionnaire, Action: actionFor(current.Questionnaire != nil)})
+	}
+
+	existingRequirements := make(map[string]Requirement, len(current.Requirements))
+	for _, req := range current.Requirements {
+		existingRequirements[req.Code] = req
+	}
+	order := RequirementOrder(current, next)
+	currentOrder := make([]string, 0, len(current.Requirements))
+	for _, req := range current.Requirements {
+		currentOrder = append(currentOrder, req.Code)
+	}
+	for _, req := range next.Requirements {
+		existing, ok := existingRequirements[req.Code]
+		if !ok {
+			diff.Changes = append(diff.Changes, Change{Kind: KindRequirement, Action: ActionCreate, Code: req.Code})
+			continue
+		}
+		var fields []string
+		if existing.Title != req.Title {
+			fields = append(fields, "title")
+		}
+		if existing.Description != req.Description {
+			fields = append(fields, "description")
+		}
+		if !slices.Equal(existing.AcceptedMimeTypes, req.AcceptedMimeTypes) {
+			fields = append(fields, "accepted_mime_types")
+		}
+		if existing.MaxFileSizeBytes != req.MaxFileSizeBytes {
+			fields = append(fields, "max_file_size_bytes")
+		}
+		if existing.Mandatory() != req.Mandatory() {
+			fields = append(fields, "is_mandatory")
+		}
+		if slices.Index(currentOrder, req.Code) != slices.Index(order, req.Code) {
+			fields = append(fields, "position")
+		}
+		if len(fields) > 0 {
+			diff.Changes = append(diff.Changes, Change{Kind: KindRequirement, Action: ActionUpdate, Code: req.Code, Fields: fields})
+		}
+	}
+
+	existingCategories := make(map[string]Category, len(current.Categories))
+	for _, category := range current.Categories {
+		existingCategories[category.Code] = category
+	}
+	for _, category := range next.Categories {
+		existing, ok := existingCategories[category.Code]
+		if !ok {
+			diff.Changes = append(diff.Changes, Change{Kind: KindCategory, Action: ActionCreate, Code: category.Code})
+			continue
+		}
+		var fields []string
+		if existing.Label != category.Label {
+			fields = append(fields, "label")
+		}
+		if existing.Description != category.Description {
+			fields = append(fields, "description")
+		}
+		if existing.Active() != category.Active() {
+			fields = append(fields, "is_active")
+		}
+		if !sameJSON(existing.QuestionnaireLogic, category.QuestionnaireLogic) {
+			fields = append(fields, "questionnaire_logic")
+		}
+		if !sameSet(existing.Requirements, category.Requirements) {
+			fields = append(fields, "requirements")
+		}
+		if len(fields) > 0 {
+			diff.Changes = append(diff.Changes, Change{Kind: KindCategory, Action: ActionUpdate, Code: category.Code, Fields: fields})
+		}
+	}
+
+	diff.Untouched = untouched(current, next)
+	return diff
+}
+
+// RequirementOrder returns the requirement codes of the imported configuration in
+// reminder order: those of next, then the untouched ones of current.
+func RequirementOrder(current, next *Bundle) []string {
+	order := make([]string, 0, len(current.Requirements)+len(next.Requirements))
+	listed := make(map[string]struct{}, len(next.Requirements))
+	for _, req := range next.Requirements {
+		order = append(order, req.Code)
+		listed[req.Code] = struct{}{}
+	}
+	for _, req := range current.Requirements {
+		if _, ok := listed[req.Code]; !ok {
+			order = append(order, req.Code)
+		}
+	}
+	return order
+}
+
+func untouched(current, next *Bundle) []Ref {
+	var refs []Ref
+	requirements := make(map[string]struct{}, len(next.Requirements))
+	for _, req := range next.Requirements {
+		requirements[req.Code] = struct{}{}
+	}
+	for _, req := range current.Requirements {
+		if _, ok := requirements[req.Code]; !ok {
+			refs = append(refs, Ref{Kind: KindRequirement, Code: req.Code})
+		}
+	}
+	categories := make(map[string]struct{}, len(next.Categories))
+	for _, category := range next.Categories {
+		categories[category.Code] = struct{}{}
+	}
+	for _, category := range current.Categories {
+		if _, ok := categories[category.Code]; !ok {
+			refs = append(refs, Ref{Kind: KindCategory, Code: category.Code})
+		}
+	}
+	return refs
+}
+
+func actionFor(exists bool) string {
+	if exists {
+		return ActionUpdate
+	}
+	return ActionCreate
+}
+
+// sameJSON compares documents by value: Postgres JSONB does not keep key order.
+func sameJSON(a, b json.RawMessage) bool {
+	if isNull(a) || isNull(b) {
+		return isNull(a) == isNull(b)
+	}
+	var left, right any
+	if json.Unmarshal(a, &left) != nil || json.Unmarshal(b, &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA, sortedB := slices.Clone(a), slices.Clone(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadPairsAndSortsScripts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0010_later.up.sql":     {Data: []byte("up 10")},
+		"migrations/0002_second.down.sql":  {Data: []byte("down 2")},
+		"migrations/0001_initial.up.sql":   {Data: []byte("up 1")},
+		"migrations/0002_second.up.sql":    {Data: []byte("up 2")},
+		"migrations/0001_initial.down.sql": {Data: []byte("down 1")},
+	}
+	migrations, err := load(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "initial", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+		// Versions sort numerically and a missing down script is allowed.
+		{Version: 10, Name: "later", Up: "up 10"},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("got %d migrations, want %d", len(migrations), len(want))
+	}
+	for i := range want {
+		if migrations[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, migrations[i], want[i])
+		}
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := map[string]struct {
+		files []string
+		err   string
+	}{
+		"bad extension":     {[]string{"0001_initial.sql"}, "unexpected migration file name"},
+		"missing version":   {[]string{"initial.up.sql"}, "unexpected migration file name"},
+		"upper case name":   {[]string{"0001_Initial.up.sql"}, "unexpected migration file name"},
+		"version zero":      {[]string{"0000_initial.up.sql"}, "invalid migration version"},
+		"version overflow":  {[]string{"99999999999999999999_initial.up.sql"}, "invalid migration version"},
+		"conflicting names": {[]string{"0001_initial.up.sql", "0001_other.down.sql"}, "conflicting names"},
+		"down without up":   {[]string{"0001_initial.down.sql"}, "has no up script"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for _, file := range tt.files {
+				fsys["migrations/"+file] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+			}
+			_, err := load(fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := load(embedded)
+	if err != nil {
+		t.Fatalf("load embedded migrations: %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: versions are not contiguous", m.Version, m.Name)
+		}
+		// Down must be able to walk back to an empty schema.
+		if strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+-- Reverts the initial schema. Destroys every ADM table and type.
+
+DROP TABLE IF EXISTS adm_storage_cleanup_queue;
+DROP TABLE IF EXISTS adm_timeline_events;
+DROP TABLE IF EXISTS adm_generated_documents;
+DROP TABLE IF EXISTS adm_document_submissions;
+DROP TABLE IF EXISTS adm_questionnaire_responses;
+DROP TABLE IF EXISTS adm_student_sessions;
+DROP TABLE IF EXISTS adm_category_requirements;
+DROP TABLE IF EXISTS adm_document_requirements;
+DROP TABLE IF EXISTS adm_categories;
+DROP TABLE IF EXISTS adm_sessions;
+
+DROP FUNCTION IF EXISTS adm_touch_updated_at();
+
+DROP TYPE IF EXISTS adm_timeline_event_type;
+DROP TYPE IF EXISTS adm_document_submission_status;
+DROP TYPE IF EXISTS adm_student_session_status;
+DROP TYPE IF EXISTS adm_session_status;
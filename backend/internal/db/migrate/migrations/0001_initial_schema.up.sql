@@ -1,4 +1,4 @@
--- ADM module database bootstrap
+-- ADM module initial schema
 -- IDs follow Pan-Bagnat conventions: prefixed ULIDs stored as TEXT (e.g. adm_session_01H...)
 
 SET client_encoding = 'UTF8';
@@ -0,0 +1,3 @@
+-- Timeline entry written when an admin reopens a validated student session.
+-- Enum values cannot be dropped, so the down script leaves the value in place.
+ALTER TYPE adm_timeline_event_type ADD VALUE IF NOT EXISTS 'session_reopened';
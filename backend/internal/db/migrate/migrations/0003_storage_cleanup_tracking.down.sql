@@ -0,0 +1,8 @@
+ALTER TABLE adm_document_submissions
+    DROP COLUMN IF EXISTS file_deleted_at;
+
+DROP INDEX IF EXISTS adm_storage_cleanup_queue_pending_idx;
+
+ALTER TABLE adm_storage_cleanup_queue
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS student_session_id;
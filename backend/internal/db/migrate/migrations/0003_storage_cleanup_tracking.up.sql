@@ -0,0 +1,13 @@
+-- Lets the storage cleanup worker attribute deletions to a student session,
+-- count attempts for its backoff and flag submissions whose file is gone.
+
+ALTER TABLE adm_storage_cleanup_queue
+    ADD COLUMN IF NOT EXISTS student_session_id TEXT REFERENCES adm_student_sessions(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS adm_storage_cleanup_queue_pending_idx
+    ON adm_storage_cleanup_queue (scheduled_for)
+    WHERE processed_at IS NULL;
+
+ALTER TABLE adm_document_submissions
+    ADD COLUMN IF NOT EXISTS file_deleted_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+ALTER TABLE adm_sessions
+    DROP COLUMN IF EXISTS roster_synced_at;
@@ -0,0 +1,11 @@
+-- Student rosters are now built when a session activates rather than at creation.
+-- roster_synced_at records the last successful sync; sessions that already have
+-- student sessions were populated by the old creation flow.
+
+ALTER TABLE adm_sessions
+    ADD COLUMN IF NOT EXISTS roster_synced_at TIMESTAMPTZ;
+
+UPDATE adm_sessions s
+SET roster_synced_at = s.created_at
+WHERE roster_synced_at IS NULL
+  AND EXISTS (SELECT 1 FROM adm_student_sessions ss WHERE ss.adm_session_id = s.id);
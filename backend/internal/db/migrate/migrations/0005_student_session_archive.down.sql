@@ -0,0 +1,2 @@
+ALTER TABLE adm_student_sessions
+    DROP COLUMN IF EXISTS archived_at;
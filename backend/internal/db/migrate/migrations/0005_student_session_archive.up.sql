@@ -0,0 +1,5 @@
+-- Students who left Pan-Bagnat can be archived by a roster sync instead of deleted,
+-- keeping their answers, uploads and timeline for audit.
+
+ALTER TABLE adm_student_sessions
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
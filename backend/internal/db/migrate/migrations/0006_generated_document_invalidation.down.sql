@@ -0,0 +1,3 @@
+ALTER TABLE adm_generated_documents
+    DROP COLUMN IF EXISTS invalidated_by_login,
+    DROP COLUMN IF EXISTS invalidated_at;
@@ -0,0 +1,6 @@
+-- Reopening a validated student session can invalidate the documents generated for it.
+-- Invalidated rows are kept for audit until the document is generated again.
+
+ALTER TABLE adm_generated_documents
+    ADD COLUMN IF NOT EXISTS invalidated_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS invalidated_by_login TEXT;
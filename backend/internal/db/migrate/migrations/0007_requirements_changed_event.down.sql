@@ -0,0 +1,2 @@
+-- PostgreSQL cannot drop an enum value. 'requirements_changed' stays in
+-- adm_timeline_event_type; the up script adds it with IF NOT EXISTS, so re-applying is safe.
@@ -0,0 +1,4 @@
+-- Timeline entry written when a requirement is added to or removed from the category
+-- of a student session, sending it back to waiting_for_documents.
+-- Enum values cannot be dropped, so the down script leaves the value in place.
+ALTER TYPE adm_timeline_event_type ADD VALUE IF NOT EXISTS 'requirements_changed';
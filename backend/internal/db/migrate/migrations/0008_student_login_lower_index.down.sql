@@ -0,0 +1 @@
+DROP INDEX IF EXISTS adm_student_sessions_login_lower_idx;
@@ -0,0 +1,5 @@
+-- Students are looked up by login case-insensitively, since the casing Pan-Bagnat
+-- returns is not guaranteed to match the one in the token.
+
+CREATE INDEX IF NOT EXISTS adm_student_sessions_login_lower_idx
+    ON adm_student_sessions (lower(student_login));
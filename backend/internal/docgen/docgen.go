@@ -0,0 +1,197 @@
+// Package docgen renders the official documents issued for a validated student
+// session and stores them as adm_generated_documents.
+//
+// Each document type has a text/template in templates/<type>.tmpl, executed with Data.
+// The output is laid out as plain text: a line starting with "# " is a heading, a
+// blank line separates paragraphs and long lines are wrapped. It is rendered to PDF
+// by a small writer using the standard Helvetica fonts, so nothing is embedded and no
+// external tool is needed.
+package docgen
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/storage"
+	"adm-backend/internal/store"
+	"adm-backend/internal/workflow"
+)
+
+// Document types, as stored in adm_generated_documents.document_type.
+const (
+	AttestationInscription = "attestation_inscription"
+	CertificatScolarite    = "certificat_scolarite"
+)
+
+// types lists the document types in generation order.
+var types = []string{AttestationInscription, CertificatScolarite}
+
+// ErrUnknownType is returned for a document type without a template.
+var ErrUnknownType = errors.New("unknown document type")
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("02/01/2006")
+	},
+}).ParseFS(templateFS, "templates/*.tmpl"))
+
+// Types returns every document type, in generation order.
+func Types() []string {
+	return append([]string(nil), types...)
+}
+
+// Known reports whether docType has a template.
+func Known(docType string) bool {
+	for _, t := range types {
+		if t == docType {
+			return true
+		}
+	}
+	return false
+}
+
+// Data is what templates print about a student session.
+type Data struct {
+	// Issuer is the name of the school issuing the document.
+	Issuer       string
+	StudentLogin string
+	SessionLabel string
+	SessionStart time.Time
+	SessionEnd   time.Time
+	// Category is the label of the student's category, empty when unknown.
+	Category    string
+	ValidatedAt time.Time
+	GeneratedAt time.Time
+	// Reference identifies the student session and revision the document was issued for.
+	Reference string
+}
+
+// Render executes the template of docType and returns the PDF. The first heading is
+// used as the document title.
+func Render(docType string, data Data) ([]byte, error) {
+	if !Known(docType) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, docType)
+	}
+	var text bytes.Buffer
+	if err := templates.ExecuteTemplate(&text, docType+".tmpl", data); err != nil {
+		return nil, fmt.Errorf("execute %s template: %w", docType, err)
+	}
+
+	title := docType
+	for _, raw := range strings.Split(text.String(), "\n") {
+		if trimmed := strings.TrimSpace(raw); strings.HasPrefix(trimmed, "# ") {
+			title = strings.TrimSpace(trimmed[2:])
+			break
+		}
+	}
+	return writePDF(title, layout(text.String()), data.GeneratedAt), nil
+}
+
+// Generator renders documents for a student session, stores them through the storage
+// backend and records them.
+type Generator struct {
+	Students *store.StudentSessionStore
+	Storage  storage.Backend
+	Issuer   string
+}
+
+// Generate renders docTypes (every type when empty) for a validated student session
+// and saves them, replacing the documents of the same type generated earlier. It
+// returns sql.ErrNoRows for an unknown student session and a *workflow.TransitionError
+// when the session is not validated. Documents are saved one at a time; on error the
+// ones already saved are returned with it.
+func (g *Generator) Generate(ctx context.Context, studentSessionID, generatedBy string, docTypes []string) ([]store.GeneratedDocument, error) {
+	if g.Storage == nil {
+		return nil, errors.New("storage backend not configured")
+	}
+	if len(docTypes) == 0 {
+		docTypes = types
+	}
+	for _, docType := range docTypes {
+		if !Known(docType) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownType, docType)
+		}
+	}
+
+	session, err := g.Students.GetDocumentContext(ctx, studentSessionID)
+	if err != nil {
+		return nil, err
+	}
+	// Checked again when saving; failing here avoids rendering and uploading for nothing.
+	if _, err := workflow.Fire(session.Status, workflow.GenerateDocuments, workflow.Input{}); err != nil {
+		return nil, err
+	}
+
+	data := Data{
+		Issuer:       g.Issuer,
+		StudentLogin: session.StudentLogin,
+		SessionLabel: session.SessionLabel,
+		SessionStart: session.SessionStartAt,
+		SessionEnd:   session.SessionEndAt,
+		Category:     session.CategoryLabel.String,
+		ValidatedAt:  session.ValidatedAt.Time,
+		GeneratedAt:  time.Now().UTC(),
+		Reference:    fmt.Sprintf("%s-r%d", session.StudentSessionID, session.CurrentRevision),
+	}
+
+	docs := make([]store.GeneratedDocument, 0, len(docTypes))
+	for _, docType := range docTypes {
+		doc, err := g.generate(ctx, session, docType, data, generatedBy)
+		if err != nil {
+			return docs, fmt.Errorf("generate %s: %w", docType, err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (g *Generator) generate(ctx context.Context, session store.DocumentContext, docType string, data Data, generatedBy string) (store.GeneratedDocument, error) {
+	pdf, err := Render(docType, data)
+	if err != nil {
+		return store.GeneratedDocument{}, err
+	}
+	id, err := ids.New("adm_generated_document")
+	if err != nil {
+		return store.GeneratedDocument{}, err
+	}
+
+	storageKey := "generated/" + session.StudentSessionID + "/" + docType + "/" + id + ".pdf"
+	if err := g.Storage.Put(ctx, storageKey, bytes.NewReader(pdf), int64(len(pdf)), "application/pdf"); err != nil {
+		_ = g.Storage.Delete(ctx, storageKey)
+		return store.GeneratedDocument{}, err
+	}
+
+	doc, replacedKey, err := g.Students.SaveGeneratedDocument(ctx, store.SaveGeneratedDocumentParams{
+		ID:               id,
+		StudentSessionID: session.StudentSessionID,
+		DocumentType:     docType,
+		StorageKey:       storageKey,
+		FileName:         docType + "_" + session.StudentLogin + ".pdf",
+		GeneratedBy:      generatedBy,
+	})
+	if err != nil {
+		_ = g.Storage.Delete(ctx, storageKey)
+		return store.GeneratedDocument{}, err
+	}
+
+	if replacedKey != "" && replacedKey != storageKey {
+		if err := g.Storage.Delete(ctx, replacedKey); err != nil {
+			log.Printf("delete replaced generated document %s: %v", replacedKey, err)
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,105 @@
+package docgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleData() Data {
+	return Data{
+		Issuer:       "École (Nice)",
+		StudentLogin: "jdoe",
+		SessionLabel: "ADM 2026",
+		SessionStart: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		SessionEnd:   time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC),
+		Category:     "Alternant",
+		ValidatedAt:  time.Date(2026, 9, 15, 10, 0, 0, 0, time.UTC),
+		GeneratedAt:  time.Date(2026, 9, 15, 10, 5, 0, 0, time.UTC),
+		Reference:    "adm_student_session_01-r1",
+	}
+}
+
+func TestRenderEveryType(t *testing.T) {
+	for _, docType := range Types() {
+		t.Run(docType, func(t *testing.T) {
+			pdf, err := Render(docType, sampleData())
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			checkStructure(t, pdf)
+			for _, want := range []string{"jdoe", "ADM 2026", "01/09/2026", "30/06/2027", "Alternant", "adm_student_session_01-r1"} {
+				if !bytes.Contains(pdf, []byte(want)) {
+					t.Errorf("pdf does not mention %q", want)
+				}
+			}
+			// Accents are WinAnsi-encoded and parentheses escaped.
+			if !bytes.Contains(pdf, []byte("\xc9cole \\(Nice\\)")) {
+				t.Errorf("issuer is not encoded as expected")
+			}
+		})
+	}
+
+	again, _ := Render(AttestationInscription, sampleData())
+	first, _ := Render(AttestationInscription, sampleData())
+	if !bytes.Equal(first, again) {
+		t.Errorf("rendering is not deterministic")
+	}
+}
+
+func TestRenderUnknownType(t *testing.T) {
+	if _, err := Render("diplome", sampleData()); !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("err = %v, want ErrUnknownType", err)
+	}
+}
+
+func TestLayoutWrapsAndPaginates(t *testing.T) {
+	paragraph := strings.Repeat("lorem ipsum dolor sit amet ", 40)
+	pages := layout("# Title\n\n" + strings.Repeat(paragraph+"\n\n", 12))
+	if len(pages) < 2 {
+		t.Fatalf("got %d page(s), want the text to overflow", len(pages))
+	}
+	for i, page := range pages {
+		for _, l := range page {
+			if width := textWidth(l.text, l.style); width > pageWidth-2*margin {
+				t.Errorf("page %d: line %q is %.1fpt wide", i+1, l.text, width)
+			}
+			if l.y < margin {
+				t.Errorf("page %d: line %q is in the bottom margin", i+1, l.text)
+			}
+		}
+	}
+	checkStructure(t, writePDF("Title", pages, time.Time{}))
+}
+
+// checkStructure verifies the header, the trailer and that every xref entry points
+// at its object.
+func checkStructure(t *testing.T, pdf []byte) {
+	t.Helper()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if match == nil {
+		t.Fatalf("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatalf("empty xref table")
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, pdf[offset:offset+10])
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A4 in PDF points, with one-inch margins.
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 72.0
+)
+
+// textStyle selects one of the two standard fonts every PDF reader ships, so no font
+// needs to be embedded.
+type textStyle struct {
+	font    string // resource name in the page dictionary
+	size    float64
+	leading float64
+	// widthScale approximates the bold face with the regular metrics.
+	widthScale float64
+}
+
+var (
+	bodyStyle    = textStyle{font: "F1", size: 11, leading: 16, widthScale: 1}
+	headingStyle = textStyle{font: "F2", size: 16, leading: 24, widthScale: 1.1}
+)
+
+// line is one laid-out line of WinAnsi-encoded text.
+type line struct {
+	style textStyle
+	text  []byte
+	x, y  float64
+}
+
+// layout places the rendered template on pages. "# " lines are headings, blank lines
+// add a paragraph gap and every other line is wrapped to the text width.
+func layout(source string) [][]line {
+	var (
+		pages  [][]line
+		page   []line
+		cursor = pageHeight - margin
+	)
+	place := func(style textStyle, text []byte) {
+		if cursor-style.leading < margin {
+			pages = append(pages, page)
+			page, cursor = nil, pageHeight-margin
+		}
+		cursor -= style.leading
+		page = append(page, line{style: style, text: text, x: margin, y: cursor})
+	}
+
+	for _, raw := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(raw)
+		switch {
+		case trimmed == "":
+			// Gaps are dropped at the top of a page.
+			if len(page) > 0 {
+				cursor -= bodyStyle.leading / 2
+			}
+		case strings.HasPrefix(trimmed, "# "):
+			for _, text := range wrap(winAnsi(strings.TrimSpace(trimmed[2:])), headingStyle) {
+				place(headingStyle, text)
+			}
+		default:
+			for _, text := range wrap(winAnsi(trimmed), bodyStyle) {
+				place(bodyStyle, text)
+			}
+		}
+	}
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// wrap splits text on spaces so every line fits between the margins. A word wider
+// than the page is left on its own line.
+func wrap(text []byte, style textStyle) [][]byte {
+	maxWidth := pageWidth - 2*margin
+	var (
+		lines   [][]byte
+		current []byte
+	)
+	for _, word := range bytes.Fields(text) {
+		candidate := word
+		if len(current) > 0 {
+			candidate = append(append(append([]byte{}, current...), ' '), word...)
+		}
+		if len(current) > 0 && textWidth(candidate, style) > maxWidth {
+			lines = append(lines, current)
+			candidate = word
+		}
+		current = candidate
+	}
+	if len(current) > 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// writePDF serialises the pages as an uncompressed PDF 1.4 file. Output only depends
+// on its arguments, so the same data always yields the same bytes.
+func writePDF(title string, pages [][]line, created time.Time) []byte {
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-5 are fixed; each page then takes a page object and a content stream.
+	const firstPage = 6
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title %s /Producer (adm-backend) /CreationDate (D:%s) >>",
+		pdfString(winAnsi(title)), created.UTC().Format("20060102150405Z")))
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		content.WriteString("BT\n")
+		for _, l := range page {
+			fmt.Fprintf(&content, "/%s %.0f Tf\n1 0 0 1 %.2f %.2f Tm\n%s Tj\n", l.style.font, l.style.size, l.x, l.y, pdfString(l.text))
+		}
+		content.WriteString("ET")
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfString writes text as a literal string, escaping the delimiters.
+func pdfString(text []byte) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range text {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// winAnsiExtras maps the characters of Windows-1252 that differ from Latin-1.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, 'Œ': 0x8c, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99, 'œ': 0x9c, 'Ÿ': 0x9f,
+}
+
+// winAnsi encodes text for the standard fonts. French text is fully covered;
+// characters outside Windows-1252 become '?'.
+func winAnsi(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			if c, ok := winAnsiExtras[r]; ok {
+				out = append(out, c)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// helveticaWidths are the Helvetica advance widths of ASCII 32-126, in thousandths
+// of the font size.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// textWidth measures WinAnsi text in points. Accented letters are counted as a
+// regular lowercase letter, which is close enough for wrapping.
+func textWidth(text []byte, style textStyle) float64 {
+	units := 0
+	for _, c := range text {
+		if c >= 32 && c <= 126 {
+			units += helveticaWidths[c-32]
+		} else {
+			units += 556
+		}
+	}
+	return float64(units) / 1000 * style.size * style.widthScale
+}
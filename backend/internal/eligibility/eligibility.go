@@ -0,0 +1,146 @@
+// Package eligibility decides which Pan-Bagnat users belong to an ADM session roster.
+// A rule is stored under the "eligibility" key of adm_sessions.configuration:
+//
+//	{
+//	  "campuses":    ["paris"],
+//	  "kinds":       ["student"],
+//	  "cursus":      ["42cursus"],
+//	  "pool_years":  [2023, 2024],
+//	  "active_only": true
+//	}
+//
+// Every field is optional and an empty list places no constraint, so the zero rule
+// matches every user. String comparisons ignore case; a user matches "cursus" when
+// any of their cursus is listed.
+package eligibility
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"adm-backend/internal/panbagnat"
+)
+
+// ConfigurationKey is the adm_sessions.configuration key holding the rule.
+const ConfigurationKey = "eligibility"
+
+type Rule struct {
+	Campuses   []string `json:"campuses,omitempty"`
+	Kinds      []string `json:"kinds,omitempty"`
+	Cursus     []string `json:"cursus,omitempty"`
+	PoolYears  []int    `json:"pool_years,omitempty"`
+	ActiveOnly bool     `json:"active_only,omitempty"`
+}
+
+// FromConfiguration extracts the rule of a session configuration document. A missing
+// configuration or key yields the zero rule.
+func FromConfiguration(configuration json.RawMessage) (Rule, error) {
+	if len(configuration) == 0 || string(configuration) == "null" {
+		return Rule{}, nil
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(configuration, &doc); err != nil {
+		return Rule{}, fmt.Errorf("decode session configuration: %w", err)
+	}
+	raw, ok := doc[ConfigurationKey]
+	if !ok {
+		return Rule{}, nil
+	}
+	return Parse(raw)
+}
+
+// Parse decodes and validates a rule.
+func Parse(raw json.RawMessage) (Rule, error) {
+	var rule Rule
+	if len(raw) == 0 || string(raw) == "null" {
+		return rule, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rule); err != nil {
+		return Rule{}, fmt.Errorf("invalid eligibility rule: %w", err)
+	}
+	for _, year := range rule.PoolYears {
+		if year < 1900 || year > 9999 {
+			return Rule{}, fmt.Errorf("invalid eligibility rule: pool year %d out of range", year)
+		}
+	}
+	rule.Campuses = normalize(rule.Campuses)
+	rule.Kinds = normalize(rule.Kinds)
+	rule.Cursus = normalize(rule.Cursus)
+	return rule, nil
+}
+
+// Match reports whether user satisfies every constraint of the rule.
+func (r Rule) Match(user panbagnat.User) bool {
+	if r.ActiveOnly && !user.Active {
+		return false
+	}
+	if len(r.Campuses) > 0 && !containsFold(r.Campuses, user.Campus) {
+		return false
+	}
+	if len(r.Kinds) > 0 && !containsFold(r.Kinds, user.Kind) {
+		return false
+	}
+	if len(r.Cursus) > 0 {
+		found := false
+		for _, cursus := range user.Cursus {
+			if containsFold(r.Cursus, cursus) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.PoolYears) > 0 {
+		found := false
+		for _, year := range r.PoolYears {
+			if year == user.PoolYear {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the users matching the rule, in their original order.
+func (r Rule) Filter(users []panbagnat.User) []panbagnat.User {
+	matched := make([]panbagnat.User, 0, len(users))
+	for _, user := range users {
+		if r.Match(user) {
+			matched = append(matched, user)
+		}
+	}
+	return matched
+}
+
+func normalize(values []string) []string {
+	cleaned := make([]string, 0, len(values))
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			cleaned = append(cleaned, trimmed)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil
+	}
+	return cleaned
+}
+
+func containsFold(values []string, target string) bool {
+	target = strings.TrimSpace(target)
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
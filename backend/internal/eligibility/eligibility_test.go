@@ -0,0 +1,141 @@
+package eligibility
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"adm-backend/internal/panbagnat"
+)
+
+func TestParse(t *testing.T) {
+	rule, err := Parse(json.RawMessage(`{"campuses":[" Paris ",""],"kinds":["  "],"cursus":["42cursus"],"pool_years":[1900,9999],"active_only":true}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := Rule{Campuses: []string{"Paris"}, Cursus: []string{"42cursus"}, PoolYears: []int{1900, 9999}, ActiveOnly: true}
+	if !reflect.DeepEqual(rule, want) {
+		t.Fatalf("rule = %+v, want %+v", rule, want)
+	}
+
+	for _, raw := range []string{"", "null"} {
+		if rule, err := Parse(json.RawMessage(raw)); err != nil || !reflect.DeepEqual(rule, Rule{}) {
+			t.Errorf("Parse(%q) = %+v, %v, want the zero rule", raw, rule, err)
+		}
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	tests := map[string]struct {
+		raw string
+		err string
+	}{
+		"unknown field":       {`{"campus":["paris"]}`, `unknown field "campus"`},
+		"wrong type":          {`{"campuses":"paris"}`, "cannot unmarshal"},
+		"pool year too small": {`{"pool_years":[1899]}`, "pool year 1899 out of range"},
+		"pool year too large": {`{"pool_years":[10000]}`, "pool year 10000 out of range"},
+		"not an object":       {`[]`, "cannot unmarshal"},
+	}
+	for name, tt := range tests {
+		if _, err := Parse(json.RawMessage(tt.raw)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: err = %v, want %q", name, err, tt.err)
+		}
+	}
+}
+
+func TestFromConfiguration(t *testing.T) {
+	tests := []struct {
+		name          string
+		configuration string
+		want          Rule
+		wantErr       bool
+	}{
+		{name: "no configuration", configuration: ""},
+		{name: "null configuration", configuration: "null"},
+		{name: "no eligibility key", configuration: `{"other":1}`},
+		{name: "rule", configuration: `{"eligibility":{"kinds":["student"]}}`, want: Rule{Kinds: []string{"student"}}},
+		{name: "invalid document", configuration: `[1]`, wantErr: true},
+		{name: "invalid rule", configuration: `{"eligibility":{"unknown":true}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		rule, err := FromConfiguration(json.RawMessage(tt.configuration))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(rule, tt.want) {
+			t.Errorf("%s: rule = %+v, want %+v", tt.name, rule, tt.want)
+		}
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	student := panbagnat.User{
+		FtLogin:  "jdoe",
+		Campus:   "Paris",
+		Kind:     "student",
+		Active:   true,
+		Cursus:   []string{"C Piscine", "42cursus"},
+		PoolYear: 2023,
+	}
+	inactive := student
+	inactive.Active = false
+	noCursus := student
+	noCursus.Cursus = nil
+	unknownYear := student
+	unknownYear.PoolYear = 0
+
+	tests := []struct {
+		name string
+		rule string
+		user panbagnat.User
+		want bool
+	}{
+		{"zero rule", `{}`, panbagnat.User{FtLogin: "anyone"}, true},
+		{"campus folds case", `{"campuses":["PARIS"]}`, student, true},
+		{"campus trims spaces", `{"campuses":[" paris "]}`, student, true},
+		{"campus outside list", `{"campuses":["nice","lyon"]}`, student, false},
+		{"kind folds case", `{"kinds":["Student"]}`, student, true},
+		{"kind outside list", `{"kinds":["staff"]}`, student, false},
+		{"cursus matches any of the user's", `{"cursus":["42CURSUS"]}`, student, true},
+		{"cursus matches any listed", `{"cursus":["other","c piscine"]}`, student, true},
+		{"cursus without overlap", `{"cursus":["other"]}`, student, false},
+		{"cursus on user without cursus", `{"cursus":["42cursus"]}`, noCursus, false},
+		{"pool year listed", `{"pool_years":[2022,2023]}`, student, true},
+		{"pool year not listed", `{"pool_years":[2024]}`, student, false},
+		{"unknown pool year", `{"pool_years":[2023]}`, unknownYear, false},
+		{"active only with active user", `{"active_only":true}`, student, true},
+		{"active only with inactive user", `{"active_only":true}`, inactive, false},
+		{"inactive users match by default", `{}`, inactive, true},
+		{"every constraint holds", `{"campuses":["paris"],"kinds":["student"],"cursus":["42cursus"],"pool_years":[2023],"active_only":true}`, student, true},
+		{"one constraint fails", `{"campuses":["paris"],"kinds":["student"],"cursus":["42cursus"],"pool_years":[2024]}`, student, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(json.RawMessage(tt.rule))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := rule.Match(tt.user); got != tt.want {
+				t.Fatalf("match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleFilterKeepsOrder(t *testing.T) {
+	users := []panbagnat.User{
+		{FtLogin: "c", Kind: "student"},
+		{FtLogin: "a", Kind: "staff"},
+		{FtLogin: "b", Kind: "Student"},
+	}
+	rule := Rule{Kinds: []string{"student"}}
+	var logins []string
+	for _, user := range rule.Filter(users) {
+		logins = append(logins, user.FtLogin)
+	}
+	if want := []string{"c", "b"}; !reflect.DeepEqual(logins, want) {
+		t.Fatalf("filtered %v, want %v", logins, want)
+	}
+}
@@ -0,0 +1,120 @@
+// Package jobs runs periodic background work inside the server process. Every
+// replica starts a Scheduler, but only the one holding a Postgres advisory lock
+// runs jobs; the others keep trying and take over if the leader disappears.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// leaderLockKey identifies the advisory lock held by the replica that runs jobs.
+const leaderLockKey int64 = 0x61646d5f6a6f62 // "adm_job"
+
+// Job is one unit of periodic work. Run must be safe to call concurrently with
+// the matching HTTP trigger.
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	db       *sql.DB
+	interval time.Duration
+	jobs     []Job
+
+	// leader is the connection holding the advisory lock; nil while following.
+	leader *sql.Conn
+}
+
+func NewScheduler(db *sql.DB, interval time.Duration, jobs ...Job) *Scheduler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Scheduler{db: db, interval: interval, jobs: jobs}
+}
+
+// Run ticks until ctx is cancelled, then releases leadership.
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.resign()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	leader, err := s.ensureLeader(ctx)
+	if err != nil {
+		log.Printf("[jobs] leader election: %v", err)
+		return
+	}
+	if !leader {
+		return
+	}
+
+	for _, job := range s.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		started := time.Now()
+		if err := job.Run(ctx); err != nil {
+			log.Printf("[jobs] %s failed after %s: %v", job.Name, time.Since(started).Round(time.Millisecond), err)
+		}
+	}
+}
+
+// ensureLeader keeps or tries to acquire the advisory lock. Session-level advisory
+// locks die with their connection, so a leader whose connection breaks loses the
+// lock and another replica picks it up on its next tick.
+func (s *Scheduler) ensureLeader(ctx context.Context) (bool, error) {
+	if s.leader != nil {
+		if err := s.leader.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		log.Printf("[jobs] lost leader connection, re-electing")
+		_ = s.leader.Close()
+		s.leader = nil
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquire connection: %w", err)
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, leaderLockKey).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return false, fmt.Errorf("try leader lock: %w", err)
+	}
+	if !acquired {
+		_ = conn.Close()
+		return false, nil
+	}
+
+	log.Printf("[jobs] acquired leadership")
+	s.leader = conn
+	return true, nil
+}
+
+func (s *Scheduler) resign() {
+	if s.leader == nil {
+		return
+	}
+	// Use a fresh context so the lock is released even though ctx was cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = s.leader.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, leaderLockKey)
+	_ = s.leader.Close()
+	s.leader = nil
+}
@@ -0,0 +1,231 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockServer stands in for Postgres' session-level advisory lock: the lock belongs to
+// a connection and is released when that connection dies.
+type lockServer struct {
+	mu     sync.Mutex
+	holder *lockConn
+}
+
+func (s *lockServer) Connect(context.Context) (driver.Conn, error) {
+	return &lockConn{server: s}, nil
+}
+
+func (s *lockServer) Driver() driver.Driver { return lockDriver{} }
+
+// kill breaks the connection holding the lock, as a crashed replica or a dropped
+// network link would.
+func (s *lockServer) kill() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.holder != nil {
+		s.holder.broken = true
+		s.holder = nil
+	}
+}
+
+func (s *lockServer) held() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.holder != nil
+}
+
+type lockDriver struct{}
+
+func (lockDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use the connector") }
+
+type lockConn struct {
+	server *lockServer
+	broken bool
+}
+
+func (c *lockConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *lockConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *lockConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.server.holder == c {
+		c.server.holder = nil
+	}
+	return nil
+}
+
+func (c *lockConn) Ping(context.Context) error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *lockConn) ResetSession(ctx context.Context) error { return c.Ping(ctx) }
+func (c *lockConn) IsValid() bool                          { return c.Ping(context.Background()) == nil }
+
+func (c *lockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.broken {
+		return nil, driver.ErrBadConn
+	}
+	if !strings.Contains(query, "pg_try_advisory_lock") || len(args) != 1 || args[0].Value != leaderLockKey {
+		return nil, errors.New("unexpected query " + query)
+	}
+	acquired := c.server.holder == nil || c.server.holder == c
+	if acquired {
+		c.server.holder = c
+	}
+	return &boolRows{value: acquired}, nil
+}
+
+func (c *lockConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.broken {
+		return nil, driver.ErrBadConn
+	}
+	if !strings.Contains(query, "pg_advisory_unlock") || len(args) != 1 || args[0].Value != leaderLockKey {
+		return nil, errors.New("unexpected statement " + query)
+	}
+	if c.server.holder == c {
+		c.server.holder = nil
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type boolRows struct {
+	value bool
+	done  bool
+}
+
+func (r *boolRows) Columns() []string { return []string{"locked"} }
+func (r *boolRows) Close() error      { return nil }
+
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+// replica is one server process: its own connection pool and scheduler.
+type replica struct {
+	scheduler *Scheduler
+	runs      []string
+}
+
+func newReplica(t *testing.T, server *lockServer, name string) *replica {
+	t.Helper()
+	db := sql.OpenDB(server)
+	t.Cleanup(func() { db.Close() })
+	r := &replica{}
+	r.scheduler = NewScheduler(db, time.Hour, Job{Name: "job", Run: func(context.Context) error {
+		r.runs = append(r.runs, name)
+		return nil
+	}})
+	return r
+}
+
+func TestSchedulerLeaderElection(t *testing.T) {
+	server := &lockServer{}
+	a, b := newReplica(t, server, "a"), newReplica(t, server, "b")
+	ctx := context.Background()
+
+	a.scheduler.tick(ctx)
+	b.scheduler.tick(ctx)
+	a.scheduler.tick(ctx)
+	if !reflect.DeepEqual(a.runs, []string{"a", "a"}) || len(b.runs) != 0 {
+		t.Fatalf("runs a=%v b=%v, want only a to run", a.runs, b.runs)
+	}
+
+	// The leader's connection dies: its lock goes with it and b takes over.
+	server.kill()
+	b.scheduler.tick(ctx)
+	a.scheduler.tick(ctx)
+	if len(b.runs) != 1 || len(a.runs) != 2 {
+		t.Fatalf("after failover runs a=%v b=%v, want b to take over", a.runs, b.runs)
+	}
+	if a.scheduler.leader != nil {
+		t.Fatalf("a kept a dead leader connection")
+	}
+
+	// Resigning releases the lock for the other replica.
+	b.scheduler.resign()
+	if server.held() {
+		t.Fatalf("lock still held after resign")
+	}
+	a.scheduler.tick(ctx)
+	b.scheduler.tick(ctx)
+	if len(a.runs) != 3 || len(b.runs) != 1 {
+		t.Fatalf("after resign runs a=%v b=%v, want a to lead again", a.runs, b.runs)
+	}
+}
+
+func TestSchedulerTickRunsEveryJob(t *testing.T) {
+	server := &lockServer{}
+	db := sql.OpenDB(server)
+	defer db.Close()
+
+	var ran []string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewScheduler(db, time.Hour,
+		Job{Name: "failing", Run: func(context.Context) error { ran = append(ran, "failing"); return errors.New("boom") }},
+		Job{Name: "cancelling", Run: func(context.Context) error { ran = append(ran, "cancelling"); cancel(); return nil }},
+		Job{Name: "skipped", Run: func(context.Context) error { ran = append(ran, "skipped"); return nil }},
+	)
+
+	// A failing job does not stop the next one; cancellation stops the tick.
+	s.tick(ctx)
+	if want := []string{"failing", "cancelling"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestSchedulerRunResignsOnCancel(t *testing.T) {
+	server := &lockServer{}
+	db := sql.OpenDB(server)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{}, 1)
+	s := NewScheduler(db, time.Hour, Job{Name: "job", Run: func(context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}})
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	<-ran
+	if !server.held() {
+		t.Fatalf("running scheduler does not hold the lock")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run did not return after cancel")
+	}
+	if server.held() {
+		t.Fatalf("lock still held after Run returned")
+	}
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"adm-backend/internal/roster"
+)
+
+// SessionLifecycleResult lists what one pass of SessionLifecycle changed. RostersBuilt
+// holds the active sessions whose roster was populated during the pass.
+type SessionLifecycleResult struct {
+	Activated              []string
+	RostersBuilt           []string
+	RosterFailures         int
+	Closed                 []string
+	ExpiredStudentSessions int
+}
+
+// LifecycleSessions is the part of *store.SessionStore SessionLifecycle uses.
+type LifecycleSessions interface {
+	ActivateDue(ctx context.Context) ([]string, error)
+	ListUnsyncedActive(ctx context.Context) ([]string, error)
+	CloseEnded(ctx context.Context) ([]string, error)
+}
+
+// LifecycleStudents is the part of *store.StudentSessionStore SessionLifecycle uses.
+type LifecycleStudents interface {
+	ExpireEnded(ctx context.Context) (int, error)
+}
+
+// RosterSyncer is implemented by *roster.Syncer.
+type RosterSyncer interface {
+	Sync(ctx context.Context, sessionID, authHeader string, opts roster.Options) (roster.Result, error)
+}
+
+// SessionLifecycle activates draft ADM sessions when they start, builds the roster of
+// newly active sessions, closes sessions when they end and expires the student
+// sessions left unfinished.
+type SessionLifecycle struct {
+	Sessions LifecycleSessions
+	Students LifecycleStudents
+	Roster   RosterSyncer
+}
+
+// Process runs one pass. Every step is idempotent, so a pass interrupted half-way is
+// completed by the next one.
+func (l *SessionLifecycle) Process(ctx context.Context) (SessionLifecycleResult, error) {
+	var result SessionLifecycleResult
+
+	activated, err := l.Sessions.ActivateDue(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Activated = activated
+
+	// Rosters are retried on every pass until Pan-Bagnat answers; a failure must not
+	// hold back closing and expiring other sessions.
+	unsynced, err := l.Sessions.ListUnsyncedActive(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, sessionID := range unsynced {
+		synced, err := l.Roster.Sync(ctx, sessionID, "", roster.Options{})
+		if err != nil {
+			log.Printf("[jobs] roster sync for %s: %v", sessionID, err)
+			result.RosterFailures++
+			continue
+		}
+		log.Printf("[jobs] roster for %s: %d added, %d removed", sessionID, len(synced.Added), len(synced.Removed))
+		result.RostersBuilt = append(result.RostersBuilt, sessionID)
+	}
+
+	closed, err := l.Sessions.CloseEnded(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Closed = closed
+
+	expired, err := l.Students.ExpireEnded(ctx)
+	result.ExpiredStudentSessions = expired
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Job adapts Process to the scheduler.
+func (l *SessionLifecycle) Job() Job {
+	return Job{
+		Name: "process-session-expirations",
+		Run: func(ctx context.Context) error {
+			result, err := l.Process(ctx)
+			if len(result.Activated) > 0 || len(result.Closed) > 0 || result.ExpiredStudentSessions > 0 {
+				log.Printf("[jobs] sessions activated=%d closed=%d student sessions expired=%d",
+					len(result.Activated), len(result.Closed), result.ExpiredStudentSessions)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"adm-backend/internal/roster"
+)
+
+// lifecycleFake records the calls SessionLifecycle makes, in order.
+type lifecycleFake struct {
+	calls     []string
+	activated []string
+	unsynced  []string
+	closed    []string
+	expired   int
+	failSync  map[string]bool
+	fail      map[string]error
+}
+
+func (f *lifecycleFake) step(name string) error {
+	f.calls = append(f.calls, name)
+	return f.fail[name]
+}
+
+func (f *lifecycleFake) ActivateDue(context.Context) ([]string, error) {
+	return f.activated, f.step("activate")
+}
+
+func (f *lifecycleFake) ListUnsyncedActive(context.Context) ([]string, error) {
+	return f.unsynced, f.step("list-unsynced")
+}
+
+func (f *lifecycleFake) CloseEnded(context.Context) ([]string, error) {
+	return f.closed, f.step("close")
+}
+
+func (f *lifecycleFake) ExpireEnded(context.Context) (int, error) {
+	return f.expired, f.step("expire")
+}
+
+func (f *lifecycleFake) Sync(_ context.Context, sessionID, authHeader string, opts roster.Options) (roster.Result, error) {
+	f.calls = append(f.calls, "sync "+sessionID)
+	if authHeader != "" || opts.DryRun || opts.ArchiveDeparted {
+		return roster.Result{}, errors.New("unexpected sync arguments")
+	}
+	if f.failSync[sessionID] {
+		return roster.Result{}, errors.New("directory down")
+	}
+	return roster.Result{SessionID: sessionID, Added: []string{"jdoe"}}, nil
+}
+
+func newLifecycle(f *lifecycleFake) *SessionLifecycle {
+	return &SessionLifecycle{Sessions: f, Students: f, Roster: f}
+}
+
+func TestSessionLifecycleProcessOrder(t *testing.T) {
+	fake := &lifecycleFake{
+		activated: []string{"s_new"},
+		unsynced:  []string{"s_new", "s_down", "s_old"},
+		closed:    []string{"s_ended"},
+		expired:   4,
+		failSync:  map[string]bool{"s_down": true},
+	}
+	result, err := newLifecycle(fake).Process(context.Background())
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	// Rosters are built after activation so new sessions get theirs in the same pass,
+	// and students are expired once their sessions are closed.
+	wantCalls := []string{"activate", "list-unsynced", "sync s_new", "sync s_down", "sync s_old", "close", "expire"}
+	if !reflect.DeepEqual(fake.calls, wantCalls) {
+		t.Fatalf("calls = %v, want %v", fake.calls, wantCalls)
+	}
+	want := SessionLifecycleResult{
+		Activated:              []string{"s_new"},
+		RostersBuilt:           []string{"s_new", "s_old"},
+		RosterFailures:         1,
+		Closed:                 []string{"s_ended"},
+		ExpiredStudentSessions: 4,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("result = %+v, want %+v", result, want)
+	}
+}
+
+func TestSessionLifecycleProcessStopsOnStoreErrors(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		failing string
+		calls   []string
+	}{
+		{"activate", []string{"activate"}},
+		{"list-unsynced", []string{"activate", "list-unsynced"}},
+		{"close", []string{"activate", "list-unsynced", "sync s1", "close"}},
+		{"expire", []string{"activate", "list-unsynced", "sync s1", "close", "expire"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.failing, func(t *testing.T) {
+			fake := &lifecycleFake{unsynced: []string{"s1"}, expired: 2, fail: map[string]error{tt.failing: boom}}
+			result, err := newLifecycle(fake).Process(context.Background())
+			if !errors.Is(err, boom) {
+				t.Fatalf("err = %v, want %v", err, boom)
+			}
+			if !reflect.DeepEqual(fake.calls, tt.calls) {
+				t.Fatalf("calls = %v, want %v", fake.calls, tt.calls)
+			}
+			// Student sessions expired before a failure are still reported.
+			if tt.failing == "expire" && result.ExpiredStudentSessions != 2 {
+				t.Fatalf("expired = %d, want 2", result.ExpiredStudentSessions)
+			}
+		})
+	}
+}
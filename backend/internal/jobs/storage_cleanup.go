@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"adm-backend/internal/storage"
+	"adm-backend/internal/store"
+)
+
+const (
+	defaultCleanupBatchSize   = 50
+	defaultCleanupMaxAttempts = 8
+	cleanupBaseBackoff        = time.Minute
+	cleanupMaxBackoff         = 6 * time.Hour
+)
+
+// StorageCleanupResult counts what one pass of StorageCleanup did.
+type StorageCleanupResult struct {
+	Deleted int
+	Retried int
+	Failed  int
+	// Skipped counts objects a submission still needed, which were kept.
+	Skipped int
+}
+
+// StorageCleanup drains adm_storage_cleanup_queue, deleting objects through the
+// storage backend. Failed deletions are retried with exponential backoff until
+// MaxAttempts, after which the item is closed with its last failure_reason.
+type StorageCleanup struct {
+	Queue       *store.StorageCleanupStore
+	Storage     storage.Backend
+	BatchSize   int
+	MaxAttempts int
+
+	// now defaults to time.Now; tests replace it to walk the retry schedule.
+	now func() time.Time
+}
+
+// Process claims batches until the queue has nothing due.
+func (c *StorageCleanup) Process(ctx context.Context) (StorageCleanupResult, error) {
+	var result StorageCleanupResult
+	for ctx.Err() == nil {
+		processed, err := c.processBatch(ctx, &result)
+		if err != nil {
+			return result, err
+		}
+		if processed == 0 {
+			break
+		}
+	}
+	return result, ctx.Err()
+}
+
+func (c *StorageCleanup) processBatch(ctx context.Context, result *StorageCleanupResult) (int, error) {
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+	maxAttempts := c.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultCleanupMaxAttempts
+	}
+
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+
+	batch, err := c.Queue.Claim(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	defer batch.Rollback()
+
+	for _, item := range batch.Items {
+		if item.InUse {
+			if err := batch.Skip(ctx, item); err != nil {
+				return 0, err
+			}
+			result.Skipped++
+			continue
+		}
+
+		err := c.Storage.Delete(ctx, item.StorageKey)
+		if err == nil || errors.Is(err, storage.ErrNotFound) {
+			if err := batch.Complete(ctx, item); err != nil {
+				return 0, err
+			}
+			result.Deleted++
+			continue
+		}
+
+		attempt := item.Attempts + 1
+		giveUp := attempt >= maxAttempts
+		if err := batch.Fail(ctx, item, err, now().Add(cleanupBackoff(attempt)), giveUp); err != nil {
+			return 0, err
+		}
+		if giveUp {
+			log.Printf("[jobs] giving up deleting %s after %d attempts: %v", item.StorageKey, attempt, err)
+			result.Failed++
+		} else {
+			result.Retried++
+		}
+	}
+
+	if err := batch.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch.Items), nil
+}
+
+// cleanupBackoff doubles the delay after each failed attempt, capped at cleanupMaxBackoff.
+func cleanupBackoff(attempt int) time.Duration {
+	delay := cleanupBaseBackoff
+	for i := 1; i < attempt && delay < cleanupMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > cleanupMaxBackoff {
+		delay = cleanupMaxBackoff
+	}
+	return delay
+}
+
+// Job adapts Process to the scheduler.
+func (c *StorageCleanup) Job() Job {
+	return Job{
+		Name: "cleanup-storage",
+		Run: func(ctx context.Context) error {
+			result, err := c.Process(ctx)
+			if result != (StorageCleanupResult{}) {
+				log.Printf("[jobs] storage cleanup deleted=%d retried=%d failed=%d skipped=%d", result.Deleted, result.Retried, result.Failed, result.Skipped)
+			}
+			return err
+		},
+	}
+}
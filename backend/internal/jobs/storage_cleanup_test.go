@@ -0,0 +1,391 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"adm-backend/internal/storage"
+	"adm-backend/internal/store"
+)
+
+func TestCleanupBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{7, 64 * time.Minute},
+		{9, 256 * time.Minute},
+		// 512 minutes is past the cap.
+		{10, 6 * time.Hour},
+		{1000, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := cleanupBackoff(tt.attempt); got != tt.want {
+			t.Errorf("cleanupBackoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+// queueRow is one adm_storage_cleanup_queue row of queueDB.
+type queueRow struct {
+	id               int64
+	storageKey       string
+	studentSessionID string
+	attempts         int64
+	scheduledFor     time.Time
+	processed        bool
+	failureReason    string
+	// inUse stands for a submission of an open student session pointing at the key.
+	inUse bool
+}
+
+// queueDB answers the statements of store.StorageCleanupStore from memory. Changes
+// made in a transaction are only kept when it commits.
+type queueDB struct {
+	mu     sync.Mutex
+	now    time.Time
+	rows   []queueRow
+	claims []int64
+	// submissions maps storage keys to the submission ids flagged on completion.
+	submissions map[string][]string
+	flagged     []string
+	events      []string
+}
+
+func (q *queueDB) Connect(context.Context) (driver.Conn, error) { return &queueConn{db: q}, nil }
+func (q *queueDB) Driver() driver.Driver                        { return queueDriver{} }
+
+func (q *queueDB) row(key string) queueRow {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, row := range q.rows {
+		if row.storageKey == key {
+			return row
+		}
+	}
+	return queueRow{}
+}
+
+type queueDriver struct{}
+
+func (queueDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use the connector") }
+
+type queueConn struct {
+	db       *queueDB
+	snapshot []queueRow
+}
+
+func (c *queueConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *queueConn) Close() error                        { return nil }
+
+func (c *queueConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.snapshot = append([]queueRow(nil), c.db.rows...)
+	return c, nil
+}
+
+func (c *queueConn) Commit() error { return nil }
+
+func (c *queueConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.rows = c.snapshot
+	return nil
+}
+
+func (c *queueConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q := c.db
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case strings.Contains(query, "FROM adm_storage_cleanup_queue q"):
+		limit := args[0].Value.(int64)
+		q.claims = append(q.claims, limit)
+		due := make([]queueRow, 0, len(q.rows))
+		for _, row := range q.rows {
+			if !row.processed && !row.scheduledFor.After(q.now) {
+				due = append(due, row)
+			}
+		}
+		sort.SliceStable(due, func(i, j int) bool { return due[i].scheduledFor.Before(due[j].scheduledFor) })
+		result := &valueRows{columns: []string{"id", "storage_key", "student_session_id", "attempts", "in_use"}}
+		for i, row := range due {
+			if int64(i) == limit {
+				break
+			}
+			var session driver.Value
+			if row.studentSessionID != "" {
+				session = row.studentSessionID
+			}
+			result.values = append(result.values, []driver.Value{row.id, row.storageKey, session, row.attempts, row.inUse})
+		}
+		return result, nil
+	case strings.Contains(query, "UPDATE adm_document_submissions"):
+		key := args[0].Value.(string)
+		result := &valueRows{columns: []string{"id", "document_requirement_id"}}
+		for _, id := range q.submissions[key] {
+			q.flagged = append(q.flagged, id)
+			result.values = append(result.values, []driver.Value{id, "r_" + key})
+		}
+		delete(q.submissions, key)
+		return result, nil
+	}
+	return nil, errors.New("unexpected query " + query)
+}
+
+func (c *queueConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	q := c.db
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if strings.Contains(query, "INSERT INTO adm_timeline_events") {
+		q.events = append(q.events, fmt.Sprintf("%v %v", args[1].Value, args[2].Value))
+		return driver.RowsAffected(1), nil
+	}
+	if !strings.Contains(query, "UPDATE adm_storage_cleanup_queue") {
+		return nil, errors.New("unexpected statement " + query)
+	}
+	id := args[0].Value.(int64)
+	for i := range q.rows {
+		row := &q.rows[i]
+		if row.id != id {
+			continue
+		}
+		switch len(args) {
+		case 1:
+			// Complete.
+			row.attempts++
+			row.processed = true
+			row.failureReason = ""
+		case 2:
+			// Skip.
+			row.processed = true
+			row.failureReason = args[1].Value.(string)
+		default:
+			// Fail.
+			row.attempts++
+			row.failureReason = args[1].Value.(string)
+			row.scheduledFor = args[2].Value.(time.Time)
+			row.processed = args[3].Value.(bool)
+		}
+		return driver.RowsAffected(1), nil
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type valueRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *valueRows) Columns() []string { return r.columns }
+func (r *valueRows) Close() error      { return nil }
+
+func (r *valueRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// deleteBackend fails the deletion of the keys listed in errs.
+type deleteBackend struct {
+	storage.Backend
+	errs    map[string]error
+	deleted []string
+}
+
+func (b *deleteBackend) Delete(_ context.Context, key string) error {
+	if err := b.errs[key]; err != nil {
+		return err
+	}
+	b.deleted = append(b.deleted, key)
+	return nil
+}
+
+func newCleanup(t *testing.T, q *queueDB, backend storage.Backend) *StorageCleanup {
+	t.Helper()
+	db := sql.OpenDB(q)
+	t.Cleanup(func() { db.Close() })
+	return &StorageCleanup{
+		Queue:   store.NewStorageCleanupStore(db),
+		Storage: backend,
+		now:     func() time.Time { return q.now },
+	}
+}
+
+func TestStorageCleanupProcess(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	q := &queueDB{
+		now: start,
+		rows: []queueRow{
+			{id: 1, storageKey: "deleted", studentSessionID: "ss1", scheduledFor: start.Add(-time.Hour)},
+			{id: 2, storageKey: "missing", scheduledFor: start.Add(-time.Hour)},
+			{id: 3, storageKey: "failing", attempts: 2, scheduledFor: start.Add(-time.Minute)},
+			{id: 4, storageKey: "later", scheduledFor: start.Add(time.Minute)},
+		},
+		submissions: map[string][]string{"deleted": {"sub1", "sub2"}},
+	}
+	backend := &deleteBackend{errs: map[string]error{
+		"missing": storage.ErrNotFound,
+		"failing": errors.New("bucket unreachable"),
+	}}
+	cleanup := newCleanup(t, q, backend)
+	cleanup.BatchSize = 2
+
+	result, err := cleanup.Process(context.Background())
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if want := (StorageCleanupResult{Deleted: 2, Retried: 1}); result != want {
+		t.Fatalf("result = %+v, want %+v", result, want)
+	}
+	// Batches are claimed until one comes back empty.
+	if want := []int64{2, 2, 2}; !reflect.DeepEqual(q.claims, want) {
+		t.Fatalf("claims = %v, want %v", q.claims, want)
+	}
+	if want := []string{"deleted"}; !reflect.DeepEqual(backend.deleted, want) {
+		t.Fatalf("deleted = %v, want %v", backend.deleted, want)
+	}
+
+	// A missing object counts as deleted.
+	for _, key := range []string{"deleted", "missing"} {
+		if row := q.row(key); !row.processed || row.attempts != 1 {
+			t.Errorf("%s: row = %+v, want processed after one attempt", key, row)
+		}
+	}
+	if want := []string{"sub1", "sub2"}; !reflect.DeepEqual(q.flagged, want) {
+		t.Errorf("flagged submissions = %v, want %v", q.flagged, want)
+	}
+	// Only items tied to a student session get a timeline event.
+	if want := []string{"ss1 " + string(store.TimelineDocumentDeleted)}; !reflect.DeepEqual(q.events, want) {
+		t.Errorf("events = %v, want %v", q.events, want)
+	}
+
+	failing := q.row("failing")
+	if failing.processed || failing.attempts != 3 || failing.failureReason != "bucket unreachable" {
+		t.Errorf("failing row = %+v", failing)
+	}
+	if want := start.Add(4 * time.Minute); !failing.scheduledFor.Equal(want) {
+		t.Errorf("failing item rescheduled for %v, want %v", failing.scheduledFor, want)
+	}
+	if row := q.row("later"); row.processed || row.attempts != 0 {
+		t.Errorf("item not yet due was processed: %+v", row)
+	}
+}
+
+func TestStorageCleanupGivesUpAfterMaxAttempts(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	q := &queueDB{now: start, rows: []queueRow{{id: 1, storageKey: "stuck", scheduledFor: start}}}
+	cleanup := newCleanup(t, q, &deleteBackend{errs: map[string]error{"stuck": errors.New("access denied")}})
+
+	wantDelay := time.Minute
+	for attempt := 1; attempt < defaultCleanupMaxAttempts; attempt++ {
+		result, err := cleanup.Process(context.Background())
+		if err != nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+		if want := (StorageCleanupResult{Retried: 1}); result != want {
+			t.Fatalf("attempt %d: result = %+v, want %+v", attempt, result, want)
+		}
+		row := q.row("stuck")
+		if row.processed || row.attempts != int64(attempt) {
+			t.Fatalf("attempt %d: row = %+v", attempt, row)
+		}
+		if got := row.scheduledFor.Sub(q.now); got != wantDelay {
+			t.Fatalf("attempt %d: retried after %v, want %v", attempt, got, wantDelay)
+		}
+
+		// Nothing is due before the retry time.
+		q.now = row.scheduledFor.Add(-time.Second)
+		if result, _ := cleanup.Process(context.Background()); result != (StorageCleanupResult{}) {
+			t.Fatalf("attempt %d: retried early: %+v", attempt, result)
+		}
+		q.now = row.scheduledFor
+		wantDelay *= 2
+	}
+
+	result, err := cleanup.Process(context.Background())
+	if err != nil {
+		t.Fatalf("last attempt: %v", err)
+	}
+	if want := (StorageCleanupResult{Failed: 1}); result != want {
+		t.Fatalf("last attempt: result = %+v, want %+v", result, want)
+	}
+	row := q.row("stuck")
+	if !row.processed || row.attempts != defaultCleanupMaxAttempts || row.failureReason != "access denied" {
+		t.Fatalf("row after giving up = %+v", row)
+	}
+
+	q.now = q.now.Add(24 * time.Hour)
+	if result, _ := cleanup.Process(context.Background()); result != (StorageCleanupResult{}) {
+		t.Fatalf("closed item was claimed again: %+v", result)
+	}
+}
+
+func TestStorageCleanupMaxAttemptsOverride(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	q := &queueDB{now: start, rows: []queueRow{{id: 1, storageKey: "stuck", attempts: 1, scheduledFor: start}}}
+	cleanup := newCleanup(t, q, &deleteBackend{errs: map[string]error{"stuck": errors.New("access denied")}})
+	cleanup.MaxAttempts = 2
+
+	result, err := cleanup.Process(context.Background())
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if want := (StorageCleanupResult{Failed: 1}); result != want {
+		t.Fatalf("result = %+v, want %+v", result, want)
+	}
+	if row := q.row("stuck"); !row.processed || row.attempts != 2 {
+		t.Fatalf("row = %+v, want closed after 2 attempts", row)
+	}
+}
+
+func TestStorageCleanupKeepsObjectsInUse(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	q := &queueDB{
+		now: start,
+		rows: []queueRow{
+			{id: 1, storageKey: "reopened", studentSessionID: "ss1", scheduledFor: start, inUse: true},
+			{id: 2, storageKey: "validated", studentSessionID: "ss2", scheduledFor: start},
+		},
+		submissions: map[string][]string{"reopened": {"sub1"}, "validated": {"sub2"}},
+	}
+	backend := &deleteBackend{}
+	result, err := newCleanup(t, q, backend).Process(context.Background())
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if want := (StorageCleanupResult{Deleted: 1, Skipped: 1}); result != want {
+		t.Fatalf("result = %+v, want %+v", result, want)
+	}
+	if want := []string{"validated"}; !reflect.DeepEqual(backend.deleted, want) {
+		t.Fatalf("deleted = %v, want %v", backend.deleted, want)
+	}
+	// The kept object's submissions are not flagged and no event is recorded for them.
+	if want := []string{"sub2"}; !reflect.DeepEqual(q.flagged, want) {
+		t.Errorf("flagged submissions = %v, want %v", q.flagged, want)
+	}
+	if want := []string{"ss2 " + string(store.TimelineDocumentDeleted)}; !reflect.DeepEqual(q.events, want) {
+		t.Errorf("events = %v, want %v", q.events, want)
+	}
+	if row := q.row("reopened"); !row.processed || row.attempts != 0 || row.failureReason == "" {
+		t.Errorf("skipped row = %+v", row)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,6 +18,13 @@ import (
 type User struct {
 	ID      string `json:"id"`
 	FtLogin string `json:"ft_login"`
+	Campus  string `json:"campus"`
+	// Kind is the account type, e.g. "student", "staff" or "piscine".
+	Kind   string   `json:"kind"`
+	Active bool     `json:"active"`
+	Cursus []string `json:"cursus"`
+	// PoolYear is the year of the user's piscine, 0 when unknown.
+	PoolYear int `json:"pool_year"`
 }
 
 type listUsersResponse struct {
@@ -22,21 +32,98 @@ type listUsersResponse struct {
 	NextPageToken string `json:"next_page_token"`
 }
 
+// Config tunes how the client talks to Pan-Bagnat. Zero fields take the defaults
+// below; a negative RateLimit or BreakerThreshold disables that mechanism.
+type Config struct {
+	BaseURL string
+	// Timeout bounds a single HTTP attempt.
+	Timeout time.Duration
+	// MaxAttempts is how many times a request is tried on network errors, 5xx and 429.
+	MaxAttempts int
+	// BaseBackoff and MaxBackoff bound the jittered exponential delay between attempts.
+	// A Retry-After longer than MaxBackoff ends the retries.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// RateLimit is the sustained number of requests per second, with bursts of RateBurst.
+	RateLimit float64
+	RateBurst int
+	// BreakerThreshold consecutive failures open the circuit for BreakerCooldown.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+const (
+	defaultTimeout          = 15 * time.Second
+	defaultMaxAttempts      = 4
+	defaultBaseBackoff      = 500 * time.Millisecond
+	defaultMaxBackoff       = 10 * time.Second
+	defaultRateLimit        = 10
+	defaultRateBurst        = 10
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = defaultBaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.RateLimit == 0 {
+		cfg.RateLimit = defaultRateLimit
+	}
+	if cfg.RateBurst <= 0 {
+		cfg.RateBurst = defaultRateBurst
+	}
+	if cfg.BreakerThreshold == 0 {
+		cfg.BreakerThreshold = defaultBreakerThreshold
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = defaultBreakerCooldown
+	}
+	return cfg
+}
+
 // Client wraps HTTP access to the Pan-Bagnat API.
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	cfg        Config
+	limiter    *rateLimiter
+	breaker    *circuitBreaker
+
+	// Test hooks.
+	sleep  func(context.Context, time.Duration) error
+	jitter func(time.Duration) time.Duration
 }
 
-// NewClient builds a Client using the provided base URL (e.g. https://pan-bagnat.local).
+// NewClient builds a Client with default settings using the provided base URL
+// (e.g. https://pan-bagnat.local).
 func NewClient(baseURL string) *Client {
-	trimmed := strings.TrimSuffix(baseURL, "/")
-	return &Client{
-		baseURL: trimmed,
-		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
-		},
+	return New(Config{BaseURL: baseURL})
+}
+
+// New builds a Client from cfg.
+func New(cfg Config) *Client {
+	cfg = cfg.withDefaults()
+	c := &Client{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		cfg:        cfg,
+		sleep:      sleepContext,
+		jitter:     fullJitter,
 	}
+	sleep := func(ctx context.Context, d time.Duration) error { return c.sleep(ctx, d) }
+	c.limiter = newRateLimiter(cfg.RateLimit, cfg.RateBurst, time.Now, sleep)
+	c.breaker = newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown, time.Now)
+	return c
 }
 
 func (c *Client) isConfigured() bool {
@@ -50,62 +137,169 @@ func (c *Client) ListAllUsers(ctx context.Context, authHeader string) ([]User, e
 		return nil, errors.New("pan bagnat api base url not configured")
 	}
 
-	endpoint, err := url.Parse(c.baseURL + "/api/v1/admin/users")
-	if err != nil {
-		return nil, fmt.Errorf("parse users endpoint: %w", err)
-	}
-	query := endpoint.Query()
-	query.Set("limit", "200")
-	endpoint.RawQuery = query.Encode()
-
+	const path = "/api/v1/admin/users"
 	var all []User
 	nextToken := ""
 
 	for {
-		reqURL := *endpoint
+		query := url.Values{}
+		query.Set("limit", "200")
 		if nextToken != "" {
-			q := reqURL.Query()
-			q.Set("next_page_token", nextToken)
-			reqURL.RawQuery = q.Encode()
+			query.Set("next_page_token", nextToken)
+		}
+
+		var payload listUsersResponse
+		if err := c.getJSON(ctx, path, query, authHeader, &payload); err != nil {
+			return nil, err
+		}
+
+		for _, user := range payload.Users {
+			if user.FtLogin != "" {
+				all = append(all, user)
+			}
+		}
+
+		if payload.NextPageToken == "" {
+			break
+		}
+		nextToken = payload.NextPageToken
+	}
+
+	return all, nil
+}
+
+// getJSON performs a GET with retries and decodes the 2xx body into out.
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, authHeader string, out any) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.Allow() {
+			return ErrCircuitOpen
+		}
+		if err := c.limiter.Wait(ctx); err != nil {
+			c.breaker.Abort()
+			return err
 		}
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 		if err != nil {
-			return nil, fmt.Errorf("create users request: %w", err)
+			c.breaker.Abort()
+			return fmt.Errorf("create %s request: %w", path, err)
 		}
 		if authHeader != "" {
 			req.Header.Set("Authorization", authHeader)
 		}
 		req.Header.Set("Accept", "application/json")
 
+		var (
+			lastErr    error
+			retryAfter time.Duration
+		)
 		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("request users: %w", err)
-		}
-
-		if resp.StatusCode != http.StatusOK {
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				c.breaker.Abort()
+				return ctx.Err()
+			}
+			c.breaker.Failure()
+			lastErr = fmt.Errorf("request %s: %w", path, err)
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			err := json.NewDecoder(resp.Body).Decode(out)
 			resp.Body.Close()
-			return nil, fmt.Errorf("pan bagnat users request failed: %s", resp.Status)
-		}
-
-		var payload listUsersResponse
-		if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+			c.breaker.Success()
+			if err != nil {
+				return fmt.Errorf("decode %s response: %w", path, err)
+			}
+			return nil
+		default:
+			apiErr := &APIError{
+				Method:     http.MethodGet,
+				Path:       path,
+				StatusCode: resp.StatusCode,
+				Body:       readSnippet(resp.Body),
+				Attempts:   attempt,
+			}
 			resp.Body.Close()
-			return nil, fmt.Errorf("decode users response: %w", err)
+			if !apiErr.Temporary() {
+				// Pan-Bagnat answered; a client error says nothing about its health.
+				c.breaker.Success()
+				return apiErr
+			}
+			c.breaker.Failure()
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			lastErr = apiErr
 		}
-		resp.Body.Close()
 
-		for _, user := range payload.Users {
-			if user.FtLogin != "" {
-				all = append(all, user)
+		if attempt >= c.cfg.MaxAttempts {
+			return lastErr
+		}
+		delay := c.backoff(attempt)
+		if retryAfter > 0 {
+			if retryAfter > c.cfg.MaxBackoff {
+				return lastErr
 			}
+			delay = retryAfter
+		}
+		if err := c.sleep(ctx, delay); err != nil {
+			return err
 		}
+	}
+}
 
-		if payload.NextPageToken == "" {
-			break
+// backoff returns the jittered delay before attempt+1.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.cfg.BaseBackoff << (attempt - 1)
+	if d <= 0 || d > c.cfg.MaxBackoff {
+		d = c.cfg.MaxBackoff
+	}
+	return c.jitter(d)
+}
+
+// fullJitter picks a delay uniformly in [d/2, d].
+func fullJitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter accepts both forms of the header: delay-seconds and an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs <= 0 {
+			return 0
 		}
-		nextToken = payload.NextPageToken
+		return time.Duration(secs) * time.Second
 	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
 
-	return all, nil
+func readSnippet(r io.Reader) string {
+	raw, _ := io.ReadAll(io.LimitReader(r, bodySnippetLimit))
+	return strings.TrimSpace(string(raw))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,333 @@
+package panbagnat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAPI is an httptest stand-in for Pan-Bagnat's user listing. Each request
+// consumes the next scripted response; once the script is exhausted it serves pages.
+type fakeAPI struct {
+	mu       sync.Mutex
+	script   []fakeResponse
+	pages    [][]User
+	requests []*http.Request
+}
+
+type fakeResponse struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Clone(context.Background()))
+	var scripted *fakeResponse
+	if len(f.script) > 0 {
+		scripted = &f.script[0]
+		f.script = f.script[1:]
+	}
+	f.mu.Unlock()
+
+	if scripted != nil {
+		for k, v := range scripted.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(scripted.status)
+		_, _ = w.Write([]byte(scripted.body))
+		return
+	}
+
+	page := 0
+	if token := r.URL.Query().Get("next_page_token"); token != "" {
+		page = int(token[0] - '0')
+	}
+	resp := listUsersResponse{}
+	if page < len(f.pages) {
+		resp.Users = f.pages[page]
+	}
+	if page+1 < len(f.pages) {
+		resp.NextPageToken = string(rune('0' + page + 1))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeAPI) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// newTestClient returns a client pointed at api that records sleeps instead of waiting.
+func newTestClient(t *testing.T, api *fakeAPI, cfg Config) (*Client, *[]time.Duration) {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	cfg.BaseURL = srv.URL
+	if cfg.RateLimit == 0 {
+		cfg.RateLimit = -1
+	}
+	c := New(cfg)
+	var sleeps []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	c.jitter = func(d time.Duration) time.Duration { return d }
+	return c, &sleeps
+}
+
+func TestListAllUsersFollowsPagination(t *testing.T) {
+	api := &fakeAPI{pages: [][]User{
+		{{ID: "1", FtLogin: "alice"}, {ID: "2"}},
+		{{ID: "3", FtLogin: "bob"}},
+	}}
+	c, _ := newTestClient(t, api, Config{})
+
+	users, err := c.ListAllUsers(context.Background(), "Bearer token")
+	if err != nil {
+		t.Fatalf("ListAllUsers: %v", err)
+	}
+	if len(users) != 2 || users[0].FtLogin != "alice" || users[1].FtLogin != "bob" {
+		t.Fatalf("users = %+v", users)
+	}
+	if got := api.requests[0].Header.Get("Authorization"); got != "Bearer token" {
+		t.Fatalf("authorization = %q", got)
+	}
+	if got := api.requests[1].URL.Query().Get("next_page_token"); got != "1" {
+		t.Fatalf("second page token = %q", got)
+	}
+}
+
+func TestRetriesServerErrorsWithBackoff(t *testing.T) {
+	api := &fakeAPI{
+		script: []fakeResponse{{status: 502}, {status: 503}},
+		pages:  [][]User{{{FtLogin: "alice"}}},
+	}
+	c, sleeps := newTestClient(t, api, Config{BaseBackoff: 100 * time.Millisecond})
+
+	users, err := c.ListAllUsers(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ListAllUsers: %v", err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("users = %+v", users)
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(*sleeps) != len(want) || (*sleeps)[0] != want[0] || (*sleeps)[1] != want[1] {
+		t.Fatalf("sleeps = %v, want %v", *sleeps, want)
+	}
+}
+
+func TestHonoursRetryAfter(t *testing.T) {
+	api := &fakeAPI{
+		script: []fakeResponse{{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "3"}}},
+		pages:  [][]User{{{FtLogin: "alice"}}},
+	}
+	c, sleeps := newTestClient(t, api, Config{})
+
+	if _, err := c.ListAllUsers(context.Background(), ""); err != nil {
+		t.Fatalf("ListAllUsers: %v", err)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 3*time.Second {
+		t.Fatalf("sleeps = %v, want [3s]", *sleeps)
+	}
+}
+
+func TestRetryAfterBeyondMaxBackoffGivesUp(t *testing.T) {
+	api := &fakeAPI{script: []fakeResponse{{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "120"}}}}
+	c, _ := newTestClient(t, api, Config{})
+
+	_, err := c.ListAllUsers(context.Background(), "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want 429 APIError", err)
+	}
+	if api.count() != 1 {
+		t.Fatalf("requests = %d, want 1", api.count())
+	}
+}
+
+func TestClientErrorIsNotRetried(t *testing.T) {
+	api := &fakeAPI{script: []fakeResponse{{status: http.StatusForbidden, body: `{"error":"missing scope users:read"}`}}}
+	c, sleeps := newTestClient(t, api, Config{})
+
+	_, err := c.ListAllUsers(context.Background(), "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden || apiErr.Attempts != 1 || apiErr.Temporary() {
+		t.Fatalf("apiErr = %+v", apiErr)
+	}
+	if !strings.Contains(apiErr.Body, "missing scope") || !strings.Contains(err.Error(), "missing scope") {
+		t.Fatalf("body snippet missing from %q", err.Error())
+	}
+	if len(*sleeps) != 0 {
+		t.Fatalf("sleeps = %v, want none", *sleeps)
+	}
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	api := &fakeAPI{script: []fakeResponse{{status: 500}, {status: 500}, {status: 500, body: strings.Repeat("x", 2*bodySnippetLimit)}}}
+	c, _ := newTestClient(t, api, Config{MaxAttempts: 3})
+
+	_, err := c.ListAllUsers(context.Background(), "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want APIError", err)
+	}
+	if apiErr.Attempts != 3 || !apiErr.Temporary() || len(apiErr.Body) != bodySnippetLimit {
+		t.Fatalf("apiErr = attempts %d, temporary %v, body %d bytes", apiErr.Attempts, apiErr.Temporary(), len(apiErr.Body))
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	api := &fakeAPI{
+		script: []fakeResponse{{status: 503}, {status: 503}},
+		pages:  [][]User{{{FtLogin: "alice"}}},
+	}
+	c, _ := newTestClient(t, api, Config{MaxAttempts: 1})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.breaker = newCircuitBreaker(2, time.Minute, func() time.Time { return now })
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.ListAllUsers(context.Background(), ""); err == nil {
+			t.Fatalf("call %d: expected error", i)
+		}
+	}
+	if _, err := c.ListAllUsers(context.Background(), ""); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if api.count() != 2 {
+		t.Fatalf("requests = %d, want 2 while open", api.count())
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := c.ListAllUsers(context.Background(), ""); err != nil {
+		t.Fatalf("probe after cooldown: %v", err)
+	}
+	if !c.breaker.Allow() {
+		t.Fatal("breaker should be closed after a successful probe")
+	}
+}
+
+func TestCircuitBreakerProbeCancelled(t *testing.T) {
+	tests := map[string]func(c *Client, api *blockingAPI) error{
+		// The caller goes away while the probe is in flight.
+		"during the request": func(c *Client, api *blockingAPI) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			api.block.Store(true)
+			go func() {
+				<-api.started
+				cancel()
+			}()
+			_, err := c.ListAllUsers(ctx, "")
+			api.block.Store(false)
+			return err
+		},
+		// The caller goes away while the probe waits for the rate limiter.
+		"while rate limited": func(c *Client, api *blockingAPI) error {
+			c.limiter = newRateLimiter(1, 1, time.Now, func(context.Context, time.Duration) error { return context.Canceled })
+			c.limiter.tokens = 0
+			defer func() { c.limiter = nil }()
+			_, err := c.ListAllUsers(context.Background(), "")
+			return err
+		},
+	}
+	for name, cancelProbe := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := &blockingAPI{started: make(chan struct{}, 1)}
+			srv := httptest.NewServer(api)
+			defer srv.Close()
+			c := New(Config{BaseURL: srv.URL, MaxAttempts: 1, RateLimit: -1})
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			c.breaker = newCircuitBreaker(1, time.Minute, func() time.Time { return now })
+
+			api.status.Store(http.StatusServiceUnavailable)
+			if _, err := c.ListAllUsers(context.Background(), ""); err == nil {
+				t.Fatal("expected the failure that opens the breaker")
+			}
+			now = now.Add(time.Minute)
+			api.status.Store(http.StatusOK)
+
+			if err := cancelProbe(c, api); !errors.Is(err, context.Canceled) {
+				t.Fatalf("cancelled probe: err = %v, want context.Canceled", err)
+			}
+			// The cancelled probe is released: the next call probes and closes the breaker.
+			if _, err := c.ListAllUsers(context.Background(), ""); err != nil {
+				t.Fatalf("probe after cancellation: %v", err)
+			}
+			if !c.breaker.Allow() {
+				t.Fatal("breaker should be closed after a successful probe")
+			}
+		})
+	}
+}
+
+// blockingAPI answers status, or holds requests until the client gives up when block is set.
+type blockingAPI struct {
+	status  atomic.Int32
+	block   atomic.Bool
+	started chan struct{}
+}
+
+func (a *blockingAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.block.Load() {
+		a.started <- struct{}{}
+		<-r.Context().Done()
+		return
+	}
+	if status := int(a.status.Load()); status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(listUsersResponse{Users: []User{{FtLogin: "alice"}}})
+}
+
+func TestRateLimiterWaitsForTokens(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var waited time.Duration
+	l := newRateLimiter(2, 1, func() time.Time { return now }, func(_ context.Context, d time.Duration) error {
+		waited += d
+		now = now.Add(d)
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if waited != time.Second {
+		t.Fatalf("waited %v, want 1s for two extra requests at 2/s", waited)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Thu, 01 Jan 2026 00:00:30 GMT": 30 * time.Second,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
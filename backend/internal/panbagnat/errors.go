@@ -0,0 +1,45 @@
+package panbagnat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrCircuitOpen is returned without contacting Pan-Bagnat while the circuit breaker
+// is open after repeated failures.
+var ErrCircuitOpen = errors.New("pan bagnat circuit breaker open")
+
+// bodySnippetLimit caps how much of an error response body is kept in APIError.
+const bodySnippetLimit = 512
+
+// APIError reports a non-2xx answer from Pan-Bagnat.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Body is the beginning of the response body, useful for Pan-Bagnat's JSON errors.
+	Body string
+	// Attempts is how many requests were made before giving up.
+	Attempts int
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("pan bagnat %s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Temporary reports whether retrying later may succeed.
+func (e *APIError) Temporary() bool {
+	return retryableStatus(e.StatusCode)
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
@@ -0,0 +1,271 @@
+// Package fake is an in-process stand-in for the Pan-Bagnat core API. It serves
+// /api/v1/admin/users from a fixture so the backend can be developed and tested
+// without a live Pan-Bagnat.
+package fake
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"adm-backend/internal/panbagnat"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	usersPath       = "/api/v1/admin/users"
+	defaultPageSize = 200
+)
+
+// Fixture is the content served by the fake.
+type Fixture struct {
+	Users []panbagnat.User `json:"users"`
+}
+
+// LoadFixture reads a fixture file. Files ending in .yaml or .yml are parsed as
+// YAML, anything else as JSON; both use the JSON field names of panbagnat.User.
+func LoadFixture(path string) (Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("read fixture: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return Fixture{}, fmt.Errorf("parse fixture %s: %w", path, err)
+		}
+		// Round-trip through JSON so YAML keys follow the same tags as the API.
+		if raw, err = json.Marshal(doc); err != nil {
+			return Fixture{}, fmt.Errorf("parse fixture %s: %w", path, err)
+		}
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(raw, &fixture); err != nil {
+		return Fixture{}, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	return fixture, nil
+}
+
+// Options tunes the fake's behaviour.
+type Options struct {
+	// Tokens lists the accepted bearer tokens. When empty any bearer token is accepted,
+	// but the Authorization header is still required.
+	Tokens []string
+	// MaxPageSize caps the limit query parameter; it defaults to 200.
+	MaxPageSize int
+	// Latency is added before every answer.
+	Latency time.Duration
+	// ErrorRate is the probability in [0, 1] that a request fails with ErrorStatus.
+	ErrorRate float64
+	// ErrorStatus defaults to 503.
+	ErrorStatus int
+}
+
+// Server serves a Fixture with Pan-Bagnat's pagination and auth semantics.
+type Server struct {
+	opts Options
+
+	mu       sync.Mutex
+	users    []panbagnat.User
+	failures []Failure
+	requests int
+}
+
+// Failure is a scripted error answer, see Server.FailNext.
+type Failure struct {
+	Status int
+	// RetryAfter is sent as the Retry-After header when positive, in seconds.
+	RetryAfter int
+	Body       string
+}
+
+// NewServer builds a fake serving fixture.
+func NewServer(fixture Fixture, opts Options) *Server {
+	if opts.MaxPageSize <= 0 {
+		opts.MaxPageSize = defaultPageSize
+	}
+	if opts.ErrorStatus == 0 {
+		opts.ErrorStatus = http.StatusServiceUnavailable
+	}
+	return &Server{opts: opts, users: append([]panbagnat.User(nil), fixture.Users...)}
+}
+
+// NewTestServer starts the fake on a loopback httptest server. Callers must Close it.
+func NewTestServer(fixture Fixture, opts Options) (*Server, *httptest.Server) {
+	s := NewServer(fixture, opts)
+	return s, httptest.NewServer(s)
+}
+
+// SetUsers replaces the served users, e.g. to simulate directory changes between syncs.
+func (s *Server) SetUsers(users []panbagnat.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append([]panbagnat.User(nil), users...)
+}
+
+// FailNext makes the next len(failures) requests answer with the given errors, in order.
+func (s *Server) FailNext(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failures...)
+}
+
+// Requests returns how many requests reached the fake.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	var failure *Failure
+	if len(s.failures) > 0 {
+		failure = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if s.opts.Latency > 0 {
+		select {
+		case <-time.After(s.opts.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if failure == nil && s.opts.ErrorRate > 0 && rand.Float64() < s.opts.ErrorRate {
+		failure = &Failure{Status: s.opts.ErrorStatus}
+	}
+	if failure != nil {
+		if failure.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(failure.RetryAfter))
+		}
+		body := failure.Body
+		if body == "" {
+			body = "injected failure"
+		}
+		writeError(w, failure.Status, body)
+		return
+	}
+
+	if r.URL.Path != usersPath {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorized(r.Header.Get("Authorization")) {
+		writeError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+		return
+	}
+	s.listUsers(w, r)
+}
+
+func (s *Server) authorized(header string) bool {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	token = strings.TrimSpace(token)
+	if !ok || token == "" {
+		return false
+	}
+	if len(s.opts.Tokens) == 0 {
+		return true
+	}
+	for _, allowed := range s.opts.Tokens {
+		if token == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := s.opts.MaxPageSize
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		if parsed < limit {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if token := query.Get("next_page_token"); token != "" {
+		parsed, ok := decodePageToken(token)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid next_page_token")
+			return
+		}
+		offset = parsed
+	}
+
+	s.mu.Lock()
+	total := len(s.users)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	page := append([]panbagnat.User{}, s.users[offset:end]...)
+	s.mu.Unlock()
+
+	resp := struct {
+		Users         []panbagnat.User `json:"users"`
+		NextPageToken string           `json:"next_page_token"`
+	}{Users: page}
+	if end < total {
+		resp.NextPageToken = encodePageToken(end)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// Page tokens are opaque to clients, like Pan-Bagnat's.
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, false
+	}
+	v, ok := strings.CutPrefix(string(raw), "offset:")
+	if !ok {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(v)
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+	return offset, true
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
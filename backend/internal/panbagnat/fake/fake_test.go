@@ -0,0 +1,124 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"adm-backend/internal/panbagnat"
+)
+
+func testUsers(n int) []panbagnat.User {
+	users := make([]panbagnat.User, n)
+	for i := range users {
+		users[i] = panbagnat.User{ID: string(rune('a' + i)), FtLogin: "login-" + string(rune('a'+i)), Active: true}
+	}
+	return users
+}
+
+func newClient(url string) *panbagnat.Client {
+	return panbagnat.New(panbagnat.Config{
+		BaseURL:     url,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+		RateLimit:   -1,
+	})
+}
+
+func TestClientPaginatesThroughFake(t *testing.T) {
+	fake, ts := NewTestServer(Fixture{Users: testUsers(7)}, Options{Tokens: []string{"dev"}, MaxPageSize: 3})
+	defer ts.Close()
+
+	users, err := newClient(ts.URL).ListAllUsers(context.Background(), "Bearer dev")
+	if err != nil {
+		t.Fatalf("ListAllUsers: %v", err)
+	}
+	if len(users) != 7 || users[6].FtLogin != "login-g" {
+		t.Fatalf("users = %+v", users)
+	}
+	if fake.Requests() != 3 {
+		t.Fatalf("requests = %d, want 3 pages", fake.Requests())
+	}
+}
+
+func TestFakeRejectsBadTokens(t *testing.T) {
+	_, ts := NewTestServer(Fixture{Users: testUsers(1)}, Options{Tokens: []string{"dev"}})
+	defer ts.Close()
+
+	for _, header := range []string{"", "dev", "Bearer other"} {
+		_, err := newClient(ts.URL).ListAllUsers(context.Background(), header)
+		var apiErr *panbagnat.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+			t.Errorf("header %q: err = %v, want 401", header, err)
+		}
+	}
+}
+
+func TestFakeRejectsForgedPageToken(t *testing.T) {
+	_, ts := NewTestServer(Fixture{Users: testUsers(1)}, Options{})
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+usersPath+"?next_page_token=bogus", nil)
+	req.Header.Set("Authorization", "Bearer any")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestInjectedFailuresAreRetried(t *testing.T) {
+	fake, ts := NewTestServer(Fixture{Users: testUsers(2)}, Options{})
+	defer ts.Close()
+	fake.FailNext(Failure{Status: http.StatusBadGateway}, Failure{Status: http.StatusTooManyRequests})
+
+	users, err := newClient(ts.URL).ListAllUsers(context.Background(), "Bearer any")
+	if err != nil {
+		t.Fatalf("ListAllUsers: %v", err)
+	}
+	if len(users) != 2 || fake.Requests() != 3 {
+		t.Fatalf("users = %d, requests = %d", len(users), fake.Requests())
+	}
+}
+
+func TestLoadFixtureYAMLAndJSON(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users.yaml": "users:\n  - ft_login: alice\n    pool_year: 2024\n    cursus: [42cursus]\n    active: true\n",
+		"users.json": `{"users":[{"ft_login":"alice","pool_year":2024,"cursus":["42cursus"],"active":true}]}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		fixture, err := LoadFixture(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(fixture.Users) != 1 {
+			t.Fatalf("%s: users = %+v", name, fixture.Users)
+		}
+		u := fixture.Users[0]
+		if u.FtLogin != "alice" || u.PoolYear != 2024 || !u.Active || len(u.Cursus) != 1 {
+			t.Fatalf("%s: user = %+v", name, u)
+		}
+	}
+}
+
+func TestExampleFixtureLoads(t *testing.T) {
+	fixture, err := LoadFixture("../../../cmd/panbagnat-fake/users.example.yaml")
+	if err != nil {
+		t.Fatalf("LoadFixture: %v", err)
+	}
+	if len(fixture.Users) == 0 {
+		t.Fatal("example fixture has no users")
+	}
+}
@@ -0,0 +1,302 @@
+// Package questionnaire evaluates adm_categories.questionnaire_logic against a
+// student's answers to decide which category they belong to.
+//
+// A category's logic is a JSON document of the form:
+//
+//	{
+//	  "required": ["status", "age"],
+//	  "rule": {
+//	    "all": [
+//	      {"question": "status", "equals": "alternant"},
+//	      {"question": "age", "gte": 18, "lt": 26},
+//	      {"not": {"question": "campus", "in": ["remote"]}},
+//	      {"any": [ ... ]}
+//	    ]
+//	  }
+//	}
+//
+// A rule node is either a combinator (exactly one of "all", "any", "not") or a
+// condition on a single question using "equals", "in" and/or the numeric bounds
+// "gt", "gte", "lt", "lte"; every operator present on a condition must hold.
+// "in" also matches multi-choice answers when any selected value is listed.
+// Categories without logic never match.
+//
+// "required" lists the questions a category needs answered before it can be
+// assigned. It is only checked for the category the rule picked: the questionnaire
+// may ask some questions of a single category (e.g. the employer of an alternant),
+// and other students must not be forced to answer them.
+package questionnaire
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Error codes reported by Evaluate.
+const (
+	CodeMissingAnswers = "missing_answers"
+	CodeNoMatch        = "no_category_matched"
+	CodeAmbiguous      = "ambiguous_category"
+	CodeInvalidLogic   = "invalid_logic"
+)
+
+// Error describes why no single category could be assigned.
+type Error struct {
+	Code       string   `json:"code"`
+	Message    string   `json:"error"`
+	Questions  []string `json:"questions,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Answers maps question identifiers to the decoded JSON value the student submitted.
+type Answers map[string]any
+
+// Candidate is a category that may be assigned, together with its raw logic.
+type Candidate struct {
+	ID    string
+	Code  string
+	Logic json.RawMessage
+}
+
+// Logic is the parsed form of a category's questionnaire_logic.
+type Logic struct {
+	Required []string `json:"required"`
+	Rule     *Rule    `json:"rule"`
+}
+
+// Rule is a node in the rule tree.
+type Rule struct {
+	All []Rule `json:"all,omitempty"`
+	Any []Rule `json:"any,omitempty"`
+	Not *Rule  `json:"not,omitempty"`
+
+	Question string   `json:"question,omitempty"`
+	Equals   any      `json:"equals,omitempty"`
+	In       []any    `json:"in,omitempty"`
+	Gt       *float64 `json:"gt,omitempty"`
+	Gte      *float64 `json:"gte,omitempty"`
+	Lt       *float64 `json:"lt,omitempty"`
+	Lte      *float64 `json:"lte,omitempty"`
+}
+
+// ParseLogic decodes and validates a questionnaire_logic document.
+func ParseLogic(raw json.RawMessage) (*Logic, error) {
+	var logic Logic
+	if err := json.Unmarshal(raw, &logic); err != nil {
+		return nil, fmt.Errorf("decode questionnaire logic: %w", err)
+	}
+	if logic.Rule == nil {
+		return nil, fmt.Errorf("questionnaire logic has no rule")
+	}
+	if err := logic.Rule.validate(); err != nil {
+		return nil, err
+	}
+	return &logic, nil
+}
+
+func (r *Rule) validate() error {
+	kinds := 0
+	if r.All != nil {
+		kinds++
+	}
+	if r.Any != nil {
+		kinds++
+	}
+	if r.Not != nil {
+		kinds++
+	}
+	if r.Question != "" {
+		kinds++
+	}
+	if kinds != 1 {
+		return fmt.Errorf("rule must have exactly one of all, any, not or question")
+	}
+
+	switch {
+	case r.All != nil:
+		for i := range r.All {
+			if err := r.All[i].validate(); err != nil {
+				return err
+			}
+		}
+	case r.Any != nil:
+		for i := range r.Any {
+			if err := r.Any[i].validate(); err != nil {
+				return err
+			}
+		}
+	case r.Not != nil:
+		return r.Not.validate()
+	default:
+		if r.Equals == nil && r.In == nil && r.Gt == nil && r.Gte == nil && r.Lt == nil && r.Lte == nil {
+			return fmt.Errorf("condition on %q has no operator", r.Question)
+		}
+	}
+	return nil
+}
+
+// Match reports whether answers satisfy the rule.
+func (r *Rule) Match(answers Answers) bool {
+	switch {
+	case r.All != nil:
+		for i := range r.All {
+			if !r.All[i].Match(answers) {
+				return false
+			}
+		}
+		return true
+	case r.Any != nil:
+		for i := range r.Any {
+			if r.Any[i].Match(answers) {
+				return true
+			}
+		}
+		return false
+	case r.Not != nil:
+		return !r.Not.Match(answers)
+	}
+
+	value, ok := answers[r.Question]
+	if !ok || value == nil {
+		return false
+	}
+
+	if r.Equals != nil && !equal(value, r.Equals) {
+		return false
+	}
+	if r.In != nil && !member(value, r.In) {
+		return false
+	}
+	if r.Gt != nil || r.Gte != nil || r.Lt != nil || r.Lte != nil {
+		n, ok := value.(float64)
+		if !ok {
+			return false
+		}
+		if r.Gt != nil && !(n > *r.Gt) {
+			return false
+		}
+		if r.Gte != nil && !(n >= *r.Gte) {
+			return false
+		}
+		if r.Lt != nil && !(n < *r.Lt) {
+			return false
+		}
+		if r.Lte != nil && !(n <= *r.Lte) {
+			return false
+		}
+	}
+	return true
+}
+
+func equal(a, b any) bool {
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		return ok && strings.EqualFold(strings.TrimSpace(av), strings.TrimSpace(bv))
+	case float64:
+		bv, ok := b.(float64)
+		return ok && av == bv
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	}
+	return false
+}
+
+func member(value any, set []any) bool {
+	if values, ok := value.([]any); ok {
+		for _, v := range values {
+			if member(v, set) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, candidate := range set {
+		if equal(value, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate picks the single candidate whose logic matches answers.
+// It returns an *Error when a category's logic cannot be parsed, nothing matches,
+// several categories match or the matched category's required questions are
+// unanswered.
+func Evaluate(answers Answers, candidates []Candidate) (Candidate, error) {
+	type match struct {
+		candidate Candidate
+		required  []string
+	}
+	var matches []match
+
+	for _, candidate := range candidates {
+		if len(candidate.Logic) == 0 || string(candidate.Logic) == "null" {
+			continue
+		}
+		logic, err := ParseLogic(candidate.Logic)
+		if err != nil {
+			return Candidate{}, &Error{
+				Code:       CodeInvalidLogic,
+				Message:    fmt.Sprintf("category %s has invalid questionnaire logic: %v", candidate.Code, err),
+				Categories: []string{candidate.Code},
+			}
+		}
+
+		if logic.Rule.Match(answers) {
+			matches = append(matches, match{candidate: candidate, required: logic.Required})
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Candidate{}, &Error{Code: CodeNoMatch, Message: "answers do not match any category"}
+	case 1:
+		var missing []string
+		for _, question := range matches[0].required {
+			if isBlank(answers[question]) {
+				missing = append(missing, question)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return Candidate{}, &Error{
+				Code:       CodeMissingAnswers,
+				Message:    "required questions are unanswered",
+				Questions:  missing,
+				Categories: []string{matches[0].candidate.Code},
+			}
+		}
+		return matches[0].candidate, nil
+	default:
+		codes := make([]string, 0, len(matches))
+		for _, m := range matches {
+			codes = append(codes, m.candidate.Code)
+		}
+		sort.Strings(codes)
+		return Candidate{}, &Error{
+			Code:       CodeAmbiguous,
+			Message:    "answers match several categories",
+			Categories: codes,
+		}
+	}
+}
+
+func isBlank(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
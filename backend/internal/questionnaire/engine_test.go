@@ -0,0 +1,197 @@
+package questionnaire
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func decodeAnswers(t *testing.T, raw string) Answers {
+	t.Helper()
+	var answers Answers
+	if err := json.Unmarshal([]byte(raw), &answers); err != nil {
+		t.Fatalf("decode answers: %v", err)
+	}
+	return answers
+}
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		answers string
+		want    bool
+	}{
+		{"equals string", `{"question":"status","equals":"alternant"}`, `{"status":"alternant"}`, true},
+		{"equals folds case and spaces", `{"question":"status","equals":"alternant"}`, `{"status":" Alternant "}`, true},
+		{"equals other value", `{"question":"status","equals":"alternant"}`, `{"status":"initial"}`, false},
+		{"equals number", `{"question":"year","equals":2}`, `{"year":2}`, true},
+		{"equals bool", `{"question":"remote","equals":true}`, `{"remote":true}`, true},
+		{"equals type mismatch", `{"question":"year","equals":2}`, `{"year":"2"}`, false},
+		{"in single value", `{"question":"campus","in":["nice","paris"]}`, `{"campus":"Paris"}`, true},
+		{"in outside set", `{"question":"campus","in":["nice","paris"]}`, `{"campus":"lyon"}`, false},
+		{"in multi-choice", `{"question":"langs","in":["c","go"]}`, `{"langs":["python","go"]}`, true},
+		{"in multi-choice without overlap", `{"question":"langs","in":["c","go"]}`, `{"langs":["python"]}`, false},
+		{"gt", `{"question":"age","gt":18}`, `{"age":19}`, true},
+		{"gt boundary", `{"question":"age","gt":18}`, `{"age":18}`, false},
+		{"gte boundary", `{"question":"age","gte":18}`, `{"age":18}`, true},
+		{"lt boundary", `{"question":"age","lt":26}`, `{"age":26}`, false},
+		{"lte boundary", `{"question":"age","lte":26}`, `{"age":26}`, true},
+		{"range inside", `{"question":"age","gte":18,"lt":26}`, `{"age":20}`, true},
+		{"range outside", `{"question":"age","gte":18,"lt":26}`, `{"age":30}`, false},
+		{"numeric bound on text", `{"question":"age","gte":18}`, `{"age":"20"}`, false},
+		{"operators combine", `{"question":"status","equals":"alternant","in":["initial"]}`, `{"status":"alternant"}`, false},
+		{"all", `{"all":[{"question":"a","equals":"x"},{"question":"b","equals":"y"}]}`, `{"a":"x","b":"y"}`, true},
+		{"all with one failing", `{"all":[{"question":"a","equals":"x"},{"question":"b","equals":"y"}]}`, `{"a":"x","b":"z"}`, false},
+		{"empty all", `{"all":[]}`, `{}`, true},
+		{"any", `{"any":[{"question":"a","equals":"x"},{"question":"b","equals":"y"}]}`, `{"a":"no","b":"y"}`, true},
+		{"any with none", `{"any":[{"question":"a","equals":"x"},{"question":"b","equals":"y"}]}`, `{"a":"no","b":"no"}`, false},
+		{"empty any", `{"any":[]}`, `{}`, false},
+		{"not", `{"not":{"question":"campus","in":["remote"]}}`, `{"campus":"nice"}`, true},
+		{"not matching", `{"not":{"question":"campus","in":["remote"]}}`, `{"campus":"remote"}`, false},
+		{
+			"nested",
+			`{"all":[{"question":"status","equals":"alternant"},{"any":[{"question":"age","lt":26},{"not":{"question":"rqth","equals":false}}]}]}`,
+			`{"status":"alternant","age":30,"rqth":true}`,
+			true,
+		},
+		{
+			"nested failing deep",
+			`{"all":[{"question":"status","equals":"alternant"},{"any":[{"question":"age","lt":26},{"not":{"question":"rqth","equals":false}}]}]}`,
+			`{"status":"alternant","age":30,"rqth":false}`,
+			false,
+		},
+		{"missing answer", `{"question":"age","gte":18}`, `{}`, false},
+		{"null answer", `{"question":"age","gte":18}`, `{"age":null}`, false},
+		// A missing answer fails its condition, so negating it matches.
+		{"not on missing answer", `{"not":{"question":"campus","equals":"remote"}}`, `{}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logic, err := ParseLogic(json.RawMessage(`{"rule":` + tt.rule + `}`))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := logic.Rule.Match(decodeAnswers(t, tt.answers)); got != tt.want {
+				t.Fatalf("match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLogicRejectsMalformedRules(t *testing.T) {
+	tests := map[string]string{
+		"not json":           `{`,
+		"no rule":            `{"required":["a"]}`,
+		"two kinds":          `{"rule":{"all":[],"question":"a","equals":"x"}}`,
+		"empty node":         `{"rule":{}}`,
+		"no operator":        `{"rule":{"question":"a"}}`,
+		"nested no operator": `{"rule":{"any":[{"not":{"question":"a"}}]}}`,
+	}
+	for name, raw := range tests {
+		if _, err := ParseLogic(json.RawMessage(raw)); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	alternant := Candidate{ID: "c1", Code: "alternant", Logic: json.RawMessage(`{
+		"required": ["status", "employer"],
+		"rule": {"question": "status", "equals": "alternant"}
+	}`)}
+	initial := Candidate{ID: "c2", Code: "initial", Logic: json.RawMessage(`{
+		"required": ["status"],
+		"rule": {"question": "status", "equals": "initial"}
+	}`)}
+	anyStudent := Candidate{ID: "c3", Code: "any_student", Logic: json.RawMessage(`{"rule": {"question": "status", "in": ["initial", "alternant"]}}`)}
+	noLogic := Candidate{ID: "c4", Code: "manual"}
+	nullLogic := Candidate{ID: "c5", Code: "manual_null", Logic: json.RawMessage(`null`)}
+	broken := Candidate{ID: "c6", Code: "broken", Logic: json.RawMessage(`{"rule":{"question":"status"}}`)}
+
+	tests := []struct {
+		name       string
+		answers    string
+		candidates []Candidate
+		want       string
+		err        *Error
+	}{
+		{
+			name:       "single match",
+			answers:    `{"status":"initial"}`,
+			candidates: []Candidate{alternant, initial, noLogic, nullLogic},
+			want:       "initial",
+		},
+		{
+			// The employer question only concerns alternants.
+			name:       "required questions of other categories are ignored",
+			answers:    `{"status":"initial","employer":""}`,
+			candidates: []Candidate{alternant, initial},
+			want:       "initial",
+		},
+		{
+			name:       "matched category with missing answers",
+			answers:    `{"status":"alternant","employer":"  "}`,
+			candidates: []Candidate{alternant, initial},
+			err:        &Error{Code: CodeMissingAnswers, Questions: []string{"employer"}, Categories: []string{"alternant"}},
+		},
+		{
+			name:       "no match",
+			answers:    `{"status":"auditeur"}`,
+			candidates: []Candidate{alternant, initial},
+			err:        &Error{Code: CodeNoMatch},
+		},
+		{
+			name:       "unanswered discriminating question",
+			answers:    `{}`,
+			candidates: []Candidate{alternant, initial},
+			err:        &Error{Code: CodeNoMatch},
+		},
+		{
+			name:       "categories without logic never match",
+			answers:    `{"status":"initial"}`,
+			candidates: []Candidate{noLogic, nullLogic},
+			err:        &Error{Code: CodeNoMatch},
+		},
+		{
+			name:       "ambiguous",
+			answers:    `{"status":"initial"}`,
+			candidates: []Candidate{initial, anyStudent, alternant},
+			err:        &Error{Code: CodeAmbiguous, Categories: []string{"any_student", "initial"}},
+		},
+		{
+			name:       "invalid logic",
+			answers:    `{"status":"initial"}`,
+			candidates: []Candidate{initial, broken},
+			err:        &Error{Code: CodeInvalidLogic, Categories: []string{"broken"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(decodeAnswers(t, tt.answers), tt.candidates)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("evaluate: %v", err)
+				}
+				if got.Code != tt.want {
+					t.Fatalf("category = %q, want %q", got.Code, tt.want)
+				}
+				return
+			}
+
+			var evalErr *Error
+			if !errors.As(err, &evalErr) {
+				t.Fatalf("err = %v, want *Error %s", err, tt.err.Code)
+			}
+			if evalErr.Code != tt.err.Code ||
+				!reflect.DeepEqual(evalErr.Questions, tt.err.Questions) ||
+				!reflect.DeepEqual(evalErr.Categories, tt.err.Categories) {
+				t.Fatalf("err = %+v, want %+v", evalErr, tt.err)
+			}
+			if evalErr.Message == "" {
+				t.Fatalf("error has no message")
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package roster builds the list of student sessions of an ADM session from the
+// Pan-Bagnat user directory. Syncing never deletes students: logins that left the
+// directory are reported and optionally archived, because their student sessions may
+// hold questionnaire answers, uploads or decisions.
+package roster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"adm-backend/internal/eligibility"
+	"adm-backend/internal/panbagnat"
+	"adm-backend/internal/store"
+)
+
+// ErrDirectoryUnavailable wraps failures to read users from Pan-Bagnat.
+var ErrDirectoryUnavailable = errors.New("pan bagnat directory unavailable")
+
+// Options tune a sync.
+type Options struct {
+	// DryRun computes the diff without writing anything.
+	DryRun bool
+	// ArchiveDeparted archives the student sessions of logins Pan-Bagnat no longer returns.
+	ArchiveDeparted bool
+}
+
+// Result describes one sync of an ADM session roster. Logins are compared
+// case-insensitively and reported with the casing stored in the roster, or the one
+// returned by Pan-Bagnat for additions.
+type Result struct {
+	SessionID string
+	DryRun    bool
+	Added     []string
+	// Removed lists enrolled logins Pan-Bagnat no longer returns.
+	Removed   []string
+	Unchanged []string
+	// Restored lists archived logins that are back in the directory; they are un-archived.
+	Restored []string
+	// Archived is the subset of Removed that this sync archived.
+	Archived []string
+}
+
+// Store is the part of *store.SessionStore a Syncer uses.
+type Store interface {
+	GetRoster(ctx context.Context, sessionID string) (store.Roster, error)
+	ApplyRoster(ctx context.Context, sessionID string, changes store.RosterChanges) (store.RosterApplied, error)
+}
+
+type Syncer struct {
+	Sessions Store
+	Client   *panbagnat.Client
+	// ServiceToken is the Authorization header used when the caller supplies none,
+	// which is always the case for the scheduler.
+	ServiceToken string
+}
+
+// Sync compares the session roster with the Pan-Bagnat users matching the session's
+// eligibility rule and, unless opts.DryRun is set, applies the additions in one
+// transaction.
+func (s *Syncer) Sync(ctx context.Context, sessionID, authHeader string, opts Options) (Result, error) {
+	current, err := s.Sessions.GetRoster(ctx, sessionID)
+	if err != nil {
+		return Result{}, err
+	}
+
+	rule, err := eligibility.FromConfiguration(current.Configuration)
+	if err != nil {
+		return Result{}, err
+	}
+	users, err := s.listUsers(ctx, authHeader)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := diff(sessionID, current.Students, dedupeLogins(rule.Filter(users)))
+	result.DryRun = opts.DryRun
+	if opts.DryRun {
+		if opts.ArchiveDeparted {
+			result.Archived = result.Removed
+		}
+		return result, nil
+	}
+
+	changes := store.RosterChanges{Add: result.Added, Restore: result.Restored}
+	if opts.ArchiveDeparted {
+		changes.Archive = result.Removed
+	}
+	applied, err := s.Sessions.ApplyRoster(ctx, sessionID, changes)
+	if err != nil {
+		return Result{}, err
+	}
+	// A concurrent sync may have applied some of the same changes first.
+	result.Added = applied.Added
+	result.Restored = applied.Restored
+	result.Archived = applied.Archived
+	return result, nil
+}
+
+// Preview is how many directory users an eligibility rule would enrol.
+type Preview struct {
+	Total    int
+	Matching int
+	// Sample holds the first matching logins in alphabetical order.
+	Sample []string
+}
+
+const previewSampleSize = 20
+
+// Preview evaluates rule against the current directory without touching any session.
+func (s *Syncer) Preview(ctx context.Context, rule eligibility.Rule, authHeader string) (Preview, error) {
+	users, err := s.listUsers(ctx, authHeader)
+	if err != nil {
+		return Preview{}, err
+	}
+	all := dedupeLogins(users)
+	matching := dedupeLogins(rule.Filter(users))
+
+	preview := Preview{Total: len(all), Matching: len(matching), Sample: matching}
+	if len(preview.Sample) > previewSampleSize {
+		preview.Sample = preview.Sample[:previewSampleSize]
+	}
+	return preview, nil
+}
+
+func (s *Syncer) listUsers(ctx context.Context, authHeader string) ([]panbagnat.User, error) {
+	if authHeader == "" {
+		authHeader = s.ServiceToken
+	}
+	users, err := s.Client.ListAllUsers(ctx, authHeader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+	return users, nil
+}
+
+func diff(sessionID string, enrolled []store.RosterStudent, directory []string) Result {
+	result := Result{SessionID: sessionID}
+
+	inDirectory := make(map[string]struct{}, len(directory))
+	for _, login := range directory {
+		inDirectory[strings.ToLower(login)] = struct{}{}
+	}
+	isEnrolled := make(map[string]struct{}, len(enrolled))
+	for _, student := range enrolled {
+		key := strings.ToLower(student.Login)
+		isEnrolled[key] = struct{}{}
+		_, present := inDirectory[key]
+		switch {
+		case present && student.Archived:
+			result.Restored = append(result.Restored, student.Login)
+		case present:
+			result.Unchanged = append(result.Unchanged, student.Login)
+		case !student.Archived:
+			result.Removed = append(result.Removed, student.Login)
+		}
+	}
+	for _, login := range directory {
+		if _, ok := isEnrolled[strings.ToLower(login)]; !ok {
+			result.Added = append(result.Added, login)
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Unchanged)
+	sort.Strings(result.Restored)
+	return result
+}
+
+func dedupeLogins(users []panbagnat.User) []string {
+	seen := make(map[string]string)
+	for _, user := range users {
+		login := strings.TrimSpace(user.FtLogin)
+		if login == "" {
+			continue
+		}
+		seen[strings.ToLower(login)] = login
+	}
+
+	logins := make([]string, 0, len(seen))
+	for _, login := range seen {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	return logins
+}
@@ -0,0 +1,223 @@
+package roster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"adm-backend/internal/eligibility"
+	"adm-backend/internal/panbagnat"
+	"adm-backend/internal/panbagnat/fake"
+	"adm-backend/internal/store"
+)
+
+func TestDiff(t *testing.T) {
+	enrolled := []store.RosterStudent{
+		{Login: "JDoe"},
+		{Login: "left"},
+		{Login: "gone", Archived: true},
+		{Login: "Back", Archived: true},
+	}
+	directory := []string{"back", "jdoe", "newcomer", "Another"}
+
+	got := diff("s1", enrolled, directory)
+	want := Result{
+		SessionID: "s1",
+		// Additions keep the directory's casing, the rest the roster's.
+		Added:     []string{"Another", "newcomer"},
+		Removed:   []string{"left"},
+		Unchanged: []string{"JDoe"},
+		Restored:  []string{"Back"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diff = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffEmptyDirectoryRemovesOnlyActiveStudents(t *testing.T) {
+	got := diff("s1", []store.RosterStudent{{Login: "a"}, {Login: "b", Archived: true}}, nil)
+	want := Result{SessionID: "s1", Removed: []string{"a"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diff = %+v, want %+v", got, want)
+	}
+}
+
+func TestDedupeLogins(t *testing.T) {
+	users := []panbagnat.User{
+		{FtLogin: "zed"},
+		{FtLogin: " JDoe "},
+		{FtLogin: ""},
+		{FtLogin: "   "},
+		{FtLogin: "jdoe"},
+		{FtLogin: "adam"},
+	}
+	if got, want := dedupeLogins(users), []string{"adam", "jdoe", "zed"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("logins = %v, want %v", got, want)
+	}
+}
+
+// fakeStore serves a fixed roster and records the changes applied to it.
+type fakeStore struct {
+	roster  store.Roster
+	applied []store.RosterChanges
+}
+
+func (f *fakeStore) GetRoster(context.Context, string) (store.Roster, error) {
+	return f.roster, nil
+}
+
+func (f *fakeStore) ApplyRoster(_ context.Context, _ string, changes store.RosterChanges) (store.RosterApplied, error) {
+	f.applied = append(f.applied, changes)
+	return store.RosterApplied{Added: changes.Add, Restored: changes.Restore, Archived: changes.Archive}, nil
+}
+
+func newSyncer(t *testing.T, sessions Store, users []panbagnat.User) (*Syncer, *fake.Server) {
+	t.Helper()
+	directory, ts := fake.NewTestServer(fake.Fixture{Users: users}, fake.Options{Tokens: []string{"service"}})
+	t.Cleanup(ts.Close)
+	client := panbagnat.New(panbagnat.Config{
+		BaseURL:     ts.URL,
+		MaxAttempts: 2,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+		RateLimit:   -1,
+	})
+	return &Syncer{Sessions: sessions, Client: client, ServiceToken: "Bearer service"}, directory
+}
+
+var directoryUsers = []panbagnat.User{
+	{FtLogin: "jdoe", Kind: "student", Active: true},
+	{FtLogin: "newcomer", Kind: "student", Active: true},
+	{FtLogin: "Back", Kind: "student", Active: true},
+	{FtLogin: "staffer", Kind: "staff", Active: true},
+}
+
+func studentsOnly() json.RawMessage {
+	return json.RawMessage(`{"eligibility":{"kinds":["student"]}}`)
+}
+
+func TestSyncDryRunWritesNothing(t *testing.T) {
+	sessions := &fakeStore{roster: store.Roster{
+		SessionID:     "s1",
+		Configuration: studentsOnly(),
+		Students:      []store.RosterStudent{{Login: "JDOE"}, {Login: "left"}, {Login: "back", Archived: true}},
+	}}
+	syncer, _ := newSyncer(t, sessions, directoryUsers)
+
+	for _, archive := range []bool{false, true} {
+		result, err := syncer.Sync(context.Background(), "s1", "", Options{DryRun: true, ArchiveDeparted: archive})
+		if err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+		want := Result{
+			SessionID: "s1",
+			DryRun:    true,
+			Added:     []string{"newcomer"},
+			Removed:   []string{"left"},
+			Unchanged: []string{"JDOE"},
+			Restored:  []string{"back"},
+		}
+		// A dry run reports what archiving would do.
+		if archive {
+			want.Archived = []string{"left"}
+		}
+		if !reflect.DeepEqual(result, want) {
+			t.Fatalf("archive=%v: result = %+v, want %+v", archive, result, want)
+		}
+	}
+	if len(sessions.applied) != 0 {
+		t.Fatalf("dry run applied %+v", sessions.applied)
+	}
+}
+
+func TestSyncAppliesChanges(t *testing.T) {
+	tests := []struct {
+		archive bool
+		want    store.RosterChanges
+	}{
+		{false, store.RosterChanges{Add: []string{"newcomer"}, Restore: []string{"back"}}},
+		{true, store.RosterChanges{Add: []string{"newcomer"}, Restore: []string{"back"}, Archive: []string{"left"}}},
+	}
+	for _, tt := range tests {
+		sessions := &fakeStore{roster: store.Roster{
+			SessionID:     "s1",
+			Configuration: studentsOnly(),
+			Students:      []store.RosterStudent{{Login: "jdoe"}, {Login: "left"}, {Login: "back", Archived: true}},
+		}}
+		syncer, _ := newSyncer(t, sessions, directoryUsers)
+		result, err := syncer.Sync(context.Background(), "s1", "", Options{ArchiveDeparted: tt.archive})
+		if err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+		if !reflect.DeepEqual(sessions.applied, []store.RosterChanges{tt.want}) {
+			t.Fatalf("archive=%v: applied %+v, want %+v", tt.archive, sessions.applied, tt.want)
+		}
+		if result.DryRun || !reflect.DeepEqual(result.Archived, tt.want.Archive) {
+			t.Fatalf("archive=%v: result = %+v", tt.archive, result)
+		}
+	}
+}
+
+func TestSyncReportsAppliedChanges(t *testing.T) {
+	// A concurrent sync already added the newcomer.
+	sessions := &concurrentStore{fakeStore: fakeStore{roster: store.Roster{SessionID: "s1"}}}
+	syncer, _ := newSyncer(t, sessions, []panbagnat.User{{FtLogin: "jdoe"}, {FtLogin: "newcomer"}})
+	result, err := syncer.Sync(context.Background(), "s1", "", Options{})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if want := []string{"jdoe"}; !reflect.DeepEqual(result.Added, want) {
+		t.Fatalf("added = %v, want %v", result.Added, want)
+	}
+}
+
+type concurrentStore struct{ fakeStore }
+
+func (c *concurrentStore) ApplyRoster(_ context.Context, _ string, changes store.RosterChanges) (store.RosterApplied, error) {
+	return store.RosterApplied{Added: changes.Add[:1]}, nil
+}
+
+func TestSyncErrors(t *testing.T) {
+	sessions := &fakeStore{roster: store.Roster{SessionID: "s1", Configuration: json.RawMessage(`{"eligibility":{"campus":"paris"}}`)}}
+	syncer, _ := newSyncer(t, sessions, directoryUsers)
+	if _, err := syncer.Sync(context.Background(), "s1", "", Options{}); err == nil {
+		t.Fatalf("sync with an invalid eligibility rule succeeded")
+	}
+
+	sessions.roster.Configuration = nil
+	syncer, directory := newSyncer(t, sessions, directoryUsers)
+	directory.FailNext(fake.Failure{Status: http.StatusBadGateway}, fake.Failure{Status: http.StatusBadGateway})
+	_, err := syncer.Sync(context.Background(), "s1", "", Options{})
+	if !errors.Is(err, ErrDirectoryUnavailable) {
+		t.Fatalf("err = %v, want ErrDirectoryUnavailable", err)
+	}
+	if len(sessions.applied) != 0 {
+		t.Fatalf("failed sync applied %+v", sessions.applied)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	users := []panbagnat.User{{FtLogin: "STAFFER", Kind: "staff"}}
+	for i := previewSampleSize + 4; i > 0; i-- {
+		users = append(users, panbagnat.User{FtLogin: fmt.Sprintf("student%02d", i), Kind: "student"})
+	}
+	// Duplicate logins count once.
+	users = append(users, panbagnat.User{FtLogin: "Student01", Kind: "student"})
+	syncer, _ := newSyncer(t, &fakeStore{}, users)
+
+	preview, err := syncer.Preview(context.Background(), eligibility.Rule{Kinds: []string{"student"}}, "Bearer service")
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if preview.Total != previewSampleSize+5 || preview.Matching != previewSampleSize+4 {
+		t.Fatalf("preview total=%d matching=%d", preview.Total, preview.Matching)
+	}
+	if len(preview.Sample) != previewSampleSize || preview.Sample[0] != "Student01" || preview.Sample[1] != "student02" {
+		t.Fatalf("sample = %v", preview.Sample)
+	}
+}
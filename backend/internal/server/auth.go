@@ -0,0 +1,268 @@
+package server
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"adm-backend/internal/api"
+)
+
+var (
+	errMissingToken     = errors.New("missing bearer token")
+	errMalformedToken   = errors.New("malformed token")
+	errUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	errUnknownKey       = errors.New("no key matches token")
+	errInvalidSignature = errors.New("invalid token signature")
+	errTokenExpired     = errors.New("token expired")
+	errMissingExpiry    = errors.New("token has no exp claim")
+	errTokenNotYetValid = errors.New("token not yet valid")
+	errMissingClaims    = errors.New("token lacks login or role claim")
+)
+
+// clockSkew tolerates small clock differences between Pan-Bagnat and this service.
+const clockSkew = 30 * time.Second
+
+// AuthConfig describes how Pan-Bagnat tokens are verified.
+type AuthConfig struct {
+	// HMACSecret verifies HS256 tokens when non-empty.
+	HMACSecret []byte
+	// JWKSFile points at a local JSON Web Key Set used to verify RS256 tokens.
+	JWKSFile string
+}
+
+// Authenticator verifies Pan-Bagnat JWTs and exposes role-gating middlewares.
+type Authenticator struct {
+	hmacSecret []byte
+	rsaKeys    map[string]*rsa.PublicKey
+	now        func() time.Time
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Login   string       `json:"login"`
+	FtLogin string       `json:"ft_login"`
+	Role    string       `json:"role"`
+	Exp     *json.Number `json:"exp"`
+	Nbf     *json.Number `json:"nbf"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// NewAuthenticator loads the verification material described by cfg.
+func NewAuthenticator(cfg AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		hmacSecret: cfg.HMACSecret,
+		rsaKeys:    make(map[string]*rsa.PublicKey),
+		now:        time.Now,
+	}
+
+	if cfg.JWKSFile != "" {
+		raw, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwks file: %w", err)
+		}
+		keys, err := parseJWKS(raw)
+		if err != nil {
+			return nil, err
+		}
+		a.rsaKeys = keys
+	}
+
+	return a, nil
+}
+
+func parseJWKS(raw []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwks
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+		if key.Alg != "" && key.Alg != "RS256" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode jwk %q modulus: %w", key.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode jwk %q exponent: %w", key.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 {
+			return nil, fmt.Errorf("jwk %q has an invalid exponent", key.Kid)
+		}
+		keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	return keys, nil
+}
+
+// Authenticate rejects requests without a valid bearer token and stores the
+// caller identity in the request context.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := a.identityFromRequest(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(api.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// RequireRole authenticates the request and only lets callers holding role through.
+func (a *Authenticator) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		gated := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, _ := api.IdentityFromContext(r.Context())
+			if identity.Role != role {
+				http.Error(w, "insufficient role", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+		return a.Authenticate(gated)
+	}
+}
+
+func (a *Authenticator) identityFromRequest(r *http.Request) (api.Identity, error) {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return api.Identity{}, errMissingToken
+	}
+	return a.Verify(strings.TrimSpace(token))
+}
+
+// Verify checks the token signature and validity window, then extracts the identity.
+func (a *Authenticator) Verify(token string) (api.Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return api.Identity{}, errMalformedToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return api.Identity{}, errMalformedToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return api.Identity{}, errMalformedToken
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := a.verifySignature(header, signed, signature); err != nil {
+		return api.Identity{}, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return api.Identity{}, errMalformedToken
+	}
+
+	// A token without exp would stay valid forever once leaked.
+	if claims.Exp == nil {
+		return api.Identity{}, errMissingExpiry
+	}
+	exp, err := claims.Exp.Int64()
+	if err != nil {
+		return api.Identity{}, errMalformedToken
+	}
+	now := a.now()
+	if now.After(time.Unix(exp, 0).Add(clockSkew)) {
+		return api.Identity{}, errTokenExpired
+	}
+	if claims.Nbf != nil {
+		nbf, err := claims.Nbf.Int64()
+		if err != nil {
+			return api.Identity{}, errMalformedToken
+		}
+		if now.Add(clockSkew).Before(time.Unix(nbf, 0)) {
+			return api.Identity{}, errTokenNotYetValid
+		}
+	}
+
+	login := strings.TrimSpace(claims.Login)
+	if login == "" {
+		login = strings.TrimSpace(claims.FtLogin)
+	}
+	role := strings.TrimSpace(claims.Role)
+	if login == "" || role == "" {
+		return api.Identity{}, errMissingClaims
+	}
+
+	return api.Identity{Login: login, Role: role}, nil
+}
+
+func (a *Authenticator) verifySignature(header jwtHeader, signed, signature []byte) error {
+	switch header.Alg {
+	case "HS256":
+		if len(a.hmacSecret) == 0 {
+			return errUnsupportedAlg
+		}
+		mac := hmac.New(sha256.New, a.hmacSecret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errInvalidSignature
+		}
+		return nil
+	case "RS256":
+		key := a.rsaKeys[header.Kid]
+		if key == nil && header.Kid == "" && len(a.rsaKeys) == 1 {
+			for _, only := range a.rsaKeys {
+				key = only
+			}
+		}
+		if key == nil {
+			return errUnknownKey
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errInvalidSignature
+		}
+		return nil
+	default:
+		return errUnsupportedAlg
+	}
+}
+
+func decodeSegment(segment string, dest any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	return decoder.Decode(dest)
+}
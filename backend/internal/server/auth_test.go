@@ -0,0 +1,246 @@
+package server
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"adm-backend/internal/api"
+)
+
+var testSecret = []byte("test-secret")
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func signHS256(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+	unsigned := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	unsigned := encodeSegment(t, map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign rs256: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+	set := map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	raw, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("marshal jwks: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	return path
+}
+
+func claimsFor(login, role string, exp time.Time) map[string]any {
+	return map[string]any{"login": login, "role": role, "exp": exp.Unix()}
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+
+	auth, err := NewAuthenticator(AuthConfig{
+		HMACSecret: testSecret,
+		JWKSFile:   writeJWKS(t, "pb-1", &rsaKey.PublicKey),
+	})
+	if err != nil {
+		t.Fatalf("new authenticator: %v", err)
+	}
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+
+	valid := now.Add(time.Hour)
+	tests := []struct {
+		name    string
+		token   string
+		want    api.Identity
+		wantErr error
+	}{
+		{
+			name:  "hs256 valid",
+			token: signHS256(t, testSecret, claimsFor("jdoe", "student", valid)),
+			want:  api.Identity{Login: "jdoe", Role: "student"},
+		},
+		{
+			name:  "rs256 valid",
+			token: signRS256(t, rsaKey, "pb-1", claimsFor("staff", "admin", valid)),
+			want:  api.Identity{Login: "staff", Role: "admin"},
+		},
+		{
+			name:  "ft_login fallback",
+			token: signHS256(t, testSecret, map[string]any{"ft_login": "jdoe", "role": "student", "exp": valid.Unix()}),
+			want:  api.Identity{Login: "jdoe", Role: "student"},
+		},
+		{
+			name:    "hs256 expired",
+			token:   signHS256(t, testSecret, claimsFor("jdoe", "student", now.Add(-time.Hour))),
+			wantErr: errTokenExpired,
+		},
+		{
+			name:    "rs256 expired",
+			token:   signRS256(t, rsaKey, "pb-1", claimsFor("staff", "admin", now.Add(-time.Hour))),
+			wantErr: errTokenExpired,
+		},
+		{
+			name:    "not yet valid",
+			token:   signHS256(t, testSecret, map[string]any{"login": "jdoe", "role": "student", "nbf": now.Add(time.Hour).Unix(), "exp": valid.Add(time.Hour).Unix()}),
+			wantErr: errTokenNotYetValid,
+		},
+		{
+			name:    "hs256 without exp",
+			token:   signHS256(t, testSecret, map[string]any{"login": "jdoe", "role": "student"}),
+			wantErr: errMissingExpiry,
+		},
+		{
+			name:    "rs256 without exp",
+			token:   signRS256(t, rsaKey, "pb-1", map[string]any{"login": "staff", "role": "admin", "nbf": now.Add(-time.Minute).Unix()}),
+			wantErr: errMissingExpiry,
+		},
+		{
+			name:    "non-numeric exp",
+			token:   signHS256(t, testSecret, map[string]any{"login": "jdoe", "role": "student", "exp": "tomorrow"}),
+			wantErr: errMalformedToken,
+		},
+		{
+			name:    "hs256 bad signature",
+			token:   signHS256(t, []byte("other-secret"), claimsFor("jdoe", "student", valid)),
+			wantErr: errInvalidSignature,
+		},
+		{
+			name:    "rs256 bad signature",
+			token:   signRS256(t, otherKey, "pb-1", claimsFor("staff", "admin", valid)),
+			wantErr: errInvalidSignature,
+		},
+		{
+			name:    "rs256 unknown kid",
+			token:   signRS256(t, rsaKey, "pb-2", claimsFor("staff", "admin", valid)),
+			wantErr: errUnknownKey,
+		},
+		{
+			name:    "unsigned token",
+			token:   encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, claimsFor("jdoe", "admin", valid)) + ".",
+			wantErr: errUnsupportedAlg,
+		},
+		{
+			name:    "missing role",
+			token:   signHS256(t, testSecret, map[string]any{"login": "jdoe", "exp": valid.Unix()}),
+			wantErr: errMissingClaims,
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-jwt",
+			wantErr: errMalformedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auth.Verify(tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	auth, err := NewAuthenticator(AuthConfig{HMACSecret: testSecret})
+	if err != nil {
+		t.Fatalf("new authenticator: %v", err)
+	}
+
+	var seen api.Identity
+	protected := auth.RequireRole(api.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = api.IdentityFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	valid := time.Now().Add(time.Hour)
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "admin allowed", header: "Bearer " + signHS256(t, testSecret, claimsFor("staff", "admin", valid)), status: http.StatusNoContent},
+		{name: "student forbidden", header: "Bearer " + signHS256(t, testSecret, claimsFor("jdoe", "student", valid)), status: http.StatusForbidden},
+		{name: "expired rejected", header: "Bearer " + signHS256(t, testSecret, claimsFor("staff", "admin", time.Now().Add(-time.Hour))), status: http.StatusUnauthorized},
+		{name: "bad signature rejected", header: "Bearer " + signHS256(t, []byte("nope"), claimsFor("staff", "admin", valid)), status: http.StatusUnauthorized},
+		{name: "missing header", header: "", status: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic Zm9vOmJhcg==", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = api.Identity{}
+			req := httptest.NewRequest(http.MethodGet, "/admin/sessions", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			protected.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusNoContent && seen.Login != "staff" {
+				t.Fatalf("identity not propagated: %+v", seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// The chi package is a vendored subset of chi; these tests pin the routing
+// behaviour the API relies on.
+
+func TestRouterMatching(t *testing.T) {
+	r := chi.NewRouter()
+	reply := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(name + " id=" + chi.URLParam(req, "id") + " doc=" + chi.URLParam(req, "docId")))
+		}
+	}
+	r.Get("/sessions", reply("list"))
+	r.Post("/sessions", reply("create"))
+	r.Get("/sessions/{id}", reply("get"))
+	r.Patch("/sessions/{id}", reply("patch"))
+	r.Get("/sessions/search", reply("search"))
+	r.Delete("/sessions/{id}/documents/{docId}", reply("delete-doc"))
+	r.Put("/sessions/{id}/documents/{docId}", reply("put-doc"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodGet, "/sessions", http.StatusOK, "list id= doc="},
+		{http.MethodGet, "/sessions/", http.StatusOK, "list id= doc="},
+		{http.MethodPost, "/sessions", http.StatusOK, "create id= doc="},
+		{http.MethodGet, "/sessions/adm_session_1", http.StatusOK, "get id=adm_session_1 doc="},
+		{http.MethodPatch, "/sessions/adm_session_1", http.StatusOK, "patch id=adm_session_1 doc="},
+		// Static routes win over parameters.
+		{http.MethodGet, "/sessions/search", http.StatusOK, "search id= doc="},
+		{http.MethodDelete, "/sessions/s1/documents/d1", http.StatusOK, "delete-doc id=s1 doc=d1"},
+		{http.MethodPut, "/sessions/s1/documents/d1", http.StatusOK, "put-doc id=s1 doc=d1"},
+		// Parameters never match empty or several segments.
+		{http.MethodGet, "/sessions//documents/d1", http.StatusNotFound, ""},
+		{http.MethodDelete, "/sessions/s1/documents", http.StatusNotFound, ""},
+		{http.MethodGet, "/sessions/s1/extra", http.StatusNotFound, ""},
+		{http.MethodGet, "/unknown", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.code)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Fatalf("body = %q, want %q", rec.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	r := chi.NewRouter()
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+	r.Get("/sessions", ok)
+	r.Post("/sessions", ok)
+	r.Get("/sessions/{id}", ok)
+	r.Delete("/sessions/{id}", ok)
+
+	tests := map[string]string{
+		"/sessions":    "GET, POST",
+		"/sessions/s1": "DELETE, GET",
+	}
+	for path, allow := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("PUT %s: status = %d, want 405", path, rec.Code)
+		}
+		if got := rec.Header().Get("Allow"); got != allow {
+			t.Fatalf("PUT %s: Allow = %q, want %q", path, got, allow)
+		}
+	}
+}
+
+func TestRouterNestedRoutesAndMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	var calls []string
+	tag := func(name string) chi.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+	r.Use(tag("outer"))
+	r.Route("/admin", func(ar chi.Router) {
+		ar.Use(tag("admin"))
+		ar.Route("/sessions/{id}", func(sr chi.Router) {
+			sr.Get("/", func(w http.ResponseWriter, req *http.Request) {
+				_, _ = w.Write([]byte(chi.URLParam(req, "id")))
+			})
+		})
+	})
+	r.Get("/healthz", func(http.ResponseWriter, *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/sessions/s1", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "s1" {
+		t.Fatalf("nested route: %d %q", rec.Code, rec.Body.String())
+	}
+	if len(calls) != 2 || calls[0] != "outer" || calls[1] != "admin" {
+		t.Fatalf("middleware calls = %v, want [outer admin]", calls)
+	}
+
+	// Middleware added to a sub-router does not leak to its parent.
+	calls = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if len(calls) != 1 || calls[0] != "outer" {
+		t.Fatalf("middleware calls = %v, want [outer]", calls)
+	}
+
+	if got := chi.URLParam(httptest.NewRequest(http.MethodGet, "/", nil), "id"); got != "" {
+		t.Fatalf("URLParam outside a route = %q", got)
+	}
+}
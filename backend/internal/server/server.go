@@ -13,7 +13,7 @@ import (
 )
 
 // NewRouter assembles the HTTP handlers for the ADM backend using chi.
-func NewRouter(adminHandler *api.AdminHandler, allowedOrigins []string) http.Handler {
+func NewRouter(adminHandler *api.AdminHandler, studentHandler *api.StudentHandler, jobsHandler *api.JobsHandler, filesHandler *api.FilesHandler, auth *Authenticator, allowedOrigins []string) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
@@ -31,7 +31,7 @@ func NewRouter(adminHandler *api.AdminHandler, allowedOrigins []string) http.Han
 			http.MethodDelete,
 			http.MethodOptions,
 		},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-User-Login"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -55,12 +55,25 @@ func NewRouter(adminHandler *api.AdminHandler, allowedOrigins []string) http.Han
 		})
 
 		router.Route("/student", func(sr chi.Router) {
-			api.RegisterStudentRoutes(sr)
+			sr.Use(auth.RequireRole(api.RoleStudent))
+			api.RegisterStudentRoutes(sr, studentHandler)
 		})
 
 		router.Route("/admin", func(ar chi.Router) {
+			ar.Use(auth.RequireRole(api.RoleAdmin))
 			api.RegisterAdminRoutes(ar, adminHandler)
 		})
+
+		// Signed download links carry their own authorization.
+		if filesHandler != nil {
+			api.RegisterFileRoutes(router, filesHandler)
+		}
+
+		// Job triggers are meant for operators and cron; they use the admin role.
+		router.Route("/internal", func(ir chi.Router) {
+			ir.Use(auth.RequireRole(api.RoleAdmin))
+			api.RegisterJobRoutes(ir, jobsHandler)
+		})
 	}
 
 	registerAPIRoutes(r)
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filesystem stores objects as files below a root directory.
+type Filesystem struct {
+	root          string
+	publicBaseURL string
+	secret        []byte
+}
+
+// NewFilesystem creates the root directory if needed and returns a backend rooted there.
+func NewFilesystem(root, publicBaseURL string, secret []byte) (*Filesystem, error) {
+	if strings.TrimSpace(root) == "" {
+		return nil, errors.New("filesystem storage root is required")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve storage root: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0o750); err != nil {
+		return nil, fmt.Errorf("create storage root: %w", err)
+	}
+	return &Filesystem{
+		root:          abs,
+		publicBaseURL: strings.TrimSuffix(publicBaseURL, "/"),
+		secret:        secret,
+	}, nil
+}
+
+func (f *Filesystem) path(key string) (string, string, error) {
+	clean, err := cleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return clean, filepath.Join(f.root, filepath.FromSlash(clean)), nil
+}
+
+// Put writes the object to a temporary file and renames it into place so
+// readers never observe partial content.
+func (f *Filesystem) Put(ctx context.Context, key string, r io.Reader, _ int64, _ string) error {
+	_, path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("create object directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("create temp object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, readerWithContext(ctx, r)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("commit object: %w", err)
+	}
+	return nil
+}
+
+func (f *Filesystem) Get(_ context.Context, key string) (io.ReadCloser, Object, error) {
+	clean, path, err := f.path(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, Object{}, ErrNotFound
+		}
+		return nil, Object{}, fmt.Errorf("open object: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, Object{}, fmt.Errorf("stat object: %w", err)
+	}
+	return file, objectFromInfo(clean, info), nil
+}
+
+func (f *Filesystem) Delete(_ context.Context, key string) error {
+	_, path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete object: %w", err)
+	}
+	return nil
+}
+
+func (f *Filesystem) Stat(_ context.Context, key string) (Object, error) {
+	clean, path, err := f.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Object{}, ErrNotFound
+		}
+		return Object{}, fmt.Errorf("stat object: %w", err)
+	}
+	return objectFromInfo(clean, info), nil
+}
+
+// SignedURL returns PublicBaseURL with the key, an expiry and an HMAC signature
+// that VerifySignature accepts as query parameters. PublicBaseURL is expected to
+// point at the API's /files download route.
+func (f *Filesystem) SignedURL(_ context.Context, key string, ttl time.Duration) (string, error) {
+	clean, _, err := f.path(key)
+	if err != nil {
+		return "", err
+	}
+	if f.publicBaseURL == "" || len(f.secret) == 0 {
+		return "", errors.New("filesystem storage has no public base url or signing secret")
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	query := url.Values{}
+	query.Set("key", clean)
+	query.Set("expires", expires)
+	query.Set("signature", f.sign(clean, expires))
+	return f.publicBaseURL + "?" + query.Encode(), nil
+}
+
+// VerifySignature checks a signature produced by SignedURL.
+func (f *Filesystem) VerifySignature(key, expires, signature string) bool {
+	clean, err := cleanKey(key)
+	if err != nil || len(f.secret) == 0 {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(f.sign(clean, expires)), []byte(signature))
+}
+
+func (f *Filesystem) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func objectFromInfo(key string, info os.FileInfo) Object {
+	return Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		ModTime:     info.ModTime(),
+	}
+}
+
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+func readerWithContext(ctx context.Context, r io.Reader) io.Reader {
+	return ctxReader{ctx: ctx, r: r}
+}
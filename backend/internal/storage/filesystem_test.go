@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFilesystem(t *testing.T) *Filesystem {
+	t.Helper()
+	fs, err := NewFilesystem(t.TempDir(), "https://adm.example/api/files/", []byte("secret"))
+	if err != nil {
+		t.Fatalf("new filesystem: %v", err)
+	}
+	return fs
+}
+
+func TestFilesystemRoundTrip(t *testing.T) {
+	fs := newTestFilesystem(t)
+	ctx := context.Background()
+	key := "submissions/adm_student_session_1/r1/scan.pdf"
+
+	if err := fs.Put(ctx, key, strings.NewReader("first"), -1, "application/pdf"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := fs.Put(ctx, key, strings.NewReader("second version"), -1, "application/pdf"); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+
+	body, obj, err := fs.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	content, _ := io.ReadAll(body)
+	body.Close()
+	if string(content) != "second version" {
+		t.Fatalf("content = %q", content)
+	}
+	if obj.Key != key || obj.Size != int64(len("second version")) || obj.ContentType != "application/pdf" {
+		t.Fatalf("object = %+v", obj)
+	}
+
+	stat, err := fs.Stat(ctx, "/"+key)
+	if err != nil || stat.Size != obj.Size {
+		t.Fatalf("stat = %+v, %v", stat, err)
+	}
+
+	// No temporary files are left next to the object.
+	entries, _ := os.ReadDir(filepath.Join(fs.root, "submissions/adm_student_session_1/r1"))
+	if len(entries) != 1 {
+		t.Fatalf("directory holds %d entries, want 1", len(entries))
+	}
+
+	if err := fs.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := fs.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("stat after delete: %v, want ErrNotFound", err)
+	}
+	if _, _, err := fs.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get after delete: %v, want ErrNotFound", err)
+	}
+	if err := fs.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting a missing object: %v", err)
+	}
+}
+
+func TestFilesystemRejectsTraversal(t *testing.T) {
+	fs := newTestFilesystem(t)
+	ctx := context.Background()
+	for _, key := range []string{"", "/", "../outside", "a/../../outside", "a/./b", "a//b", "a/.."} {
+		if err := fs.Put(ctx, key, strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("put %q: %v, want ErrInvalidKey", key, err)
+		}
+		if _, _, err := fs.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("get %q: %v, want ErrInvalidKey", key, err)
+		}
+		if err := fs.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("delete %q: %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(fs.root), "outside")); !os.IsNotExist(err) {
+		t.Fatalf("a file was written outside the root")
+	}
+}
+
+func TestFilesystemPutHonoursCancellation(t *testing.T) {
+	fs := newTestFilesystem(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := fs.Put(ctx, "doc.pdf", strings.NewReader("content"), 7, ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("put: %v, want context.Canceled", err)
+	}
+	if _, err := fs.Stat(context.Background(), "doc.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cancelled upload left an object: %v", err)
+	}
+}
+
+func TestFilesystemSignedURL(t *testing.T) {
+	fs := newTestFilesystem(t)
+	signed, err := fs.SignedURL(context.Background(), "/generated/a b.pdf", time.Minute)
+	if err != nil {
+		t.Fatalf("signed url: %v", err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("parse signed url: %v", err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != "https://adm.example/api/files" {
+		t.Fatalf("signed url points at %q", got)
+	}
+	query := u.Query()
+	key, expires, signature := query.Get("key"), query.Get("expires"), query.Get("signature")
+	if key != "generated/a b.pdf" {
+		t.Fatalf("key = %q", key)
+	}
+	if !fs.VerifySignature(key, expires, signature) {
+		t.Fatalf("signature rejected")
+	}
+
+	past := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	tests := map[string][3]string{
+		"other key":       {"generated/other.pdf", expires, signature},
+		"extended expiry": {key, expires + "0", signature},
+		"expired":         {key, past, fs.sign(key, past)},
+		"bad expiry":      {key, "soon", signature},
+		"traversal":       {"../" + key, expires, signature},
+	}
+	for name, args := range tests {
+		if fs.VerifySignature(args[0], args[1], args[2]) {
+			t.Errorf("%s: signature accepted", name)
+		}
+	}
+
+	unsigned, _ := NewFilesystem(t.TempDir(), "", nil)
+	if _, err := unsigned.SignedURL(context.Background(), "doc.pdf", time.Minute); err == nil {
+		t.Fatalf("signed url without a secret succeeded")
+	}
+}
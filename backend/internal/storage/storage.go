@@ -0,0 +1,81 @@
+// Package storage abstracts the object store holding student uploads and
+// generated documents. The database only keeps storage keys and metadata.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when no object exists under the requested key.
+	ErrNotFound = errors.New("storage object not found")
+	// ErrInvalidKey is returned for keys that are empty or escape the storage root.
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// Object describes a stored blob.
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Backend is implemented by every storage provider.
+type Backend interface {
+	// Put stores r under key. size may be -1 when the length is unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key. Callers must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Delete removes the object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Stat returns the object metadata without reading its content.
+	Stat(ctx context.Context, key string) (Object, error)
+	// SignedURL returns a time-limited URL granting read access to the object.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Config selects and configures a storage backend.
+type Config struct {
+	// Driver is "filesystem" (default) or "s3".
+	Driver string
+
+	// Root is the directory used by the filesystem driver.
+	Root string
+	// PublicBaseURL is the URL of the /files route serving the filesystem driver's signed URLs.
+	PublicBaseURL string
+	// SigningSecret signs filesystem URLs.
+	SigningSecret string
+
+	S3 S3Config
+}
+
+// New builds the backend described by cfg.
+func New(cfg Config) (Backend, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Driver)) {
+	case "", "filesystem", "fs":
+		return NewFilesystem(cfg.Root, cfg.PublicBaseURL, []byte(cfg.SigningSecret))
+	case "s3":
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+func cleanKey(key string) (string, error) {
+	key = strings.TrimLeft(strings.TrimSpace(key), "/")
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
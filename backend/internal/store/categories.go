@@ -0,0 +1,496 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/workflow"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrConfigurationLocked is returned when editing an active ADM session without opting in.
+	ErrConfigurationLocked = errors.New("the ADM session is active; its configuration can only be edited with force=true")
+	// ErrCodeTaken is returned when another category or requirement of the session uses the code.
+	ErrCodeTaken = errors.New("this code is already used in the ADM session")
+	// ErrInUse is returned when deleting a category or requirement students already depend on.
+	ErrInUse = errors.New("still referenced by student sessions")
+	// ErrIncompleteOrder is returned when a reorder does not list every requirement exactly once.
+	ErrIncompleteOrder = errors.New("the order must list every requirement of the ADM session exactly once")
+)
+
+// UnknownRequirementsError lists requirement IDs that do not belong to the ADM session.
+type UnknownRequirementsError struct {
+	IDs []string
+}
+
+func (e *UnknownRequirementsError) Error() string {
+	return "unknown requirements for this ADM session: " + strings.Join(e.IDs, ", ")
+}
+
+// ConfigEdit scopes a configuration change to an ADM session. Draft sessions are
+// editable, active ones only with Force and closed ones never. A DryRun edit runs
+// every check and computes its impact, then rolls back.
+type ConfigEdit struct {
+	SessionID string
+	Force     bool
+	DryRun    bool
+	// EditedBy is the admin login recorded on the timeline of re-synced students.
+	EditedBy string
+}
+
+type Category struct {
+	ID                 string
+	Code               string
+	Label              string
+	Description        sql.NullString
+	QuestionnaireLogic json.RawMessage
+	IsActive           bool
+	// RequirementIDs is only filled by the admin listing.
+	RequirementIDs []string
+}
+
+type DocumentRequirement struct {
+	ID                string
+	Code              string
+	Title             string
+	Description       sql.NullString
+	AcceptedMimeTypes []string
+	MaxFileSizeBytes  sql.NullInt64
+	ReminderOrder     sql.NullInt64
+	IsMandatory       bool
+}
+
+type CategoryStore struct {
+	db *sql.DB
+	// students applies the transitions of student sessions re-synced after a
+	// requirement change.
+	students *StudentSessionStore
+}
+
+func NewCategoryStore(db *sql.DB) *CategoryStore {
+	return &CategoryStore{db: db, students: NewStudentSessionStore(db)}
+}
+
+// ListActive returns the active categories of an ADM session, including their questionnaire logic.
+func (s *CategoryStore) ListActive(ctx context.Context, admSessionID string) ([]Category, error) {
+	const query = `
+        SELECT id, code, label, description, questionnaire_logic, is_active
+        FROM adm_categories
+        WHERE adm_session_id = $1 AND is_active
+        ORDER BY code;
+    `
+
+	rows, err := s.db.QueryContext(ctx, query, admSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var (
+			category Category
+			logic    []byte
+		)
+		if err := rows.Scan(
+			&category.ID,
+			&category.Code,
+			&category.Label,
+			&category.Description,
+			&logic,
+			&category.IsActive,
+		); err != nil {
+			return nil, fmt.Errorf("scan category: %w", err)
+		}
+		category.QuestionnaireLogic = logic
+		categories = append(categories, category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// CategoryParams holds the editable fields of a category.
+type CategoryParams struct {
+	Code               string
+	Label              string
+	Description        sql.NullString
+	QuestionnaireLogic json.RawMessage
+	IsActive           bool
+}
+
+// List returns every category of an ADM session, active or not, with the IDs of
+// their requirements.
+func (s *CategoryStore) List(ctx context.Context, admSessionID string) ([]Category, error) {
+	return listCategories(ctx, s.db, admSessionID)
+}
+
+func listCategories(ctx context.Context, q queryer, admSessionID string) ([]Category, error) {
+	const query = `
+        SELECT
+            c.id, c.code, c.label, c.description, c.questionnaire_logic, c.is_active,
+            COALESCE(
+                ARRAY_AGG(cr.document_requirement_id ORDER BY r.reminder_order NULLS LAST, r.code)
+                    FILTER (WHERE cr.document_requirement_id IS NOT NULL),
+                ARRAY[]::TEXT[]
+            )
+        FROM adm_categories c
+        LEFT JOIN adm_category_requirements cr ON cr.category_id = c.id
+        LEFT JOIN adm_document_requirements r ON r.id = cr.document_requirement_id
+        WHERE c.adm_session_id = $1
+        GROUP BY c.id
+        ORDER BY c.code;
+    `
+	rows, err := q.QueryContext(ctx, query, admSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var (
+			category Category
+			logic    []byte
+		)
+		if err := rows.Scan(
+			&category.ID,
+			&category.Code,
+			&category.Label,
+			&category.Description,
+			&logic,
+			&category.IsActive,
+			pq.Array(&category.RequirementIDs),
+		); err != nil {
+			return nil, fmt.Errorf("scan category: %w", err)
+		}
+		category.QuestionnaireLogic = logic
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate categories: %w", err)
+	}
+	return categories, nil
+}
+
+// Get returns one category of an ADM session, or sql.ErrNoRows.
+func (s *CategoryStore) Get(ctx context.Context, admSessionID, categoryID string) (Category, error) {
+	categories, err := s.List(ctx, admSessionID)
+	if err != nil {
+		return Category{}, err
+	}
+	for _, category := range categories {
+		if category.ID == categoryID {
+			return category, nil
+		}
+	}
+	return Category{}, sql.ErrNoRows
+}
+
+// CreateCategory inserts a category linked to requirementIDs and returns its ID.
+func (s *CategoryStore) CreateCategory(ctx context.Context, edit ConfigEdit, params CategoryParams, requirementIDs []string) (string, error) {
+	id, err := ids.New("adm_category")
+	if err != nil {
+		return "", fmt.Errorf("generate category id: %w", err)
+	}
+
+	err = s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		const insert = `
+            INSERT INTO adm_categories (
+                id, adm_session_id, code, label, description, questionnaire_logic, is_active,
+                created_at, updated_at
+            ) VALUES ($1,$2,$3,$4,$5,$6,$7,NOW(),NOW());
+        `
+		if _, err := tx.ExecContext(ctx, insert, id, edit.SessionID, params.Code, params.Label, params.Description, nullJSON(params.QuestionnaireLogic), params.IsActive); err != nil {
+			if isUniqueViolation(err, "adm_categories_code_session_uniq") {
+				return ErrCodeTaken
+			}
+			return fmt.Errorf("insert category: %w", err)
+		}
+		// A new category has no students yet, so there is nothing to re-sync.
+		_, _, err := setCategoryRequirements(ctx, tx, edit.SessionID, id, requirementIDs)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// UpdateCategory replaces the editable fields of a category.
+func (s *CategoryStore) UpdateCategory(ctx context.Context, edit ConfigEdit, categoryID string, params CategoryParams) error {
+	return s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		const update = `
+            UPDATE adm_categories
+            SET code = $3, label = $4, description = $5, questionnaire_logic = $6, is_active = $7
+            WHERE id = $1 AND adm_session_id = $2;
+        `
+		res, err := tx.ExecContext(ctx, update, categoryID, edit.SessionID, params.Code, params.Label, params.Description, nullJSON(params.QuestionnaireLogic), params.IsActive)
+		if err != nil {
+			if isUniqueViolation(err, "adm_categories_code_session_uniq") {
+				return ErrCodeTaken
+			}
+			return fmt.Errorf("update category: %w", err)
+		}
+		return expectOneRow(res, "update category")
+	})
+}
+
+// DeleteCategory removes a category no student session is assigned to. Categories in
+// use should be deactivated instead.
+func (s *CategoryStore) DeleteCategory(ctx context.Context, edit ConfigEdit, categoryID string) error {
+	return s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		var used bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM adm_student_sessions WHERE category_id = $1)`, categoryID).Scan(&used); err != nil {
+			return fmt.Errorf("check category usage: %w", err)
+		}
+		if used {
+			return ErrInUse
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM adm_categories WHERE id = $1 AND adm_session_id = $2`, categoryID, edit.SessionID)
+		if err != nil {
+			return fmt.Errorf("delete category: %w", err)
+		}
+		return expectOneRow(res, "delete category")
+	})
+}
+
+// SetCategoryRequirements replaces the requirements linked to a category and
+// re-syncs the student sessions of the category when mandatory requirements were added.
+func (s *CategoryStore) SetCategoryRequirements(ctx context.Context, edit ConfigEdit, categoryID string, requirementIDs []string) (RequirementChange, error) {
+	change := RequirementChange{CategoryID: categoryID}
+	err := s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		if err := categoryExists(ctx, tx, edit.SessionID, categoryID); err != nil {
+			return err
+		}
+		var err error
+		if change.Added, change.Removed, err = setCategoryRequirements(ctx, tx, edit.SessionID, categoryID, requirementIDs); err != nil {
+			return err
+		}
+		return s.resyncCategory(ctx, tx, &change, edit.EditedBy)
+	})
+	return change, err
+}
+
+// AttachRequirement links one requirement to a category and, when it is mandatory,
+// re-syncs the student sessions of the category; attaching twice is a no-op.
+func (s *CategoryStore) AttachRequirement(ctx context.Context, edit ConfigEdit, categoryID, requirementID string) (RequirementChange, error) {
+	change := RequirementChange{CategoryID: categoryID}
+	err := s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		if err := categoryExists(ctx, tx, edit.SessionID, categoryID); err != nil {
+			return err
+		}
+		if err := checkRequirements(ctx, tx, edit.SessionID, []string{requirementID}); err != nil {
+			return err
+		}
+		const insert = `
+            INSERT INTO adm_category_requirements (category_id, document_requirement_id, created_at)
+            VALUES ($1,$2,NOW())
+            ON CONFLICT DO NOTHING;
+        `
+		res, err := tx.ExecContext(ctx, insert, categoryID, requirementID)
+		if err != nil {
+			return fmt.Errorf("attach requirement: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("attach requirement: %w", err)
+		}
+		if n > 0 {
+			change.Added = []string{requirementID}
+		}
+		return s.resyncCategory(ctx, tx, &change, edit.EditedBy)
+	})
+	return change, err
+}
+
+// DetachRequirement unlinks a requirement from a category. No student session moves:
+// submissions already made for it stay in their revision.
+func (s *CategoryStore) DetachRequirement(ctx context.Context, edit ConfigEdit, categoryID, requirementID string) (RequirementChange, error) {
+	change := RequirementChange{CategoryID: categoryID}
+	err := s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		if err := categoryExists(ctx, tx, edit.SessionID, categoryID); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM adm_category_requirements WHERE category_id = $1 AND document_requirement_id = $2`, categoryID, requirementID)
+		if err != nil {
+			return fmt.Errorf("detach requirement: %w", err)
+		}
+		if err := expectOneRow(res, "detach requirement"); err != nil {
+			return err
+		}
+		change.Removed = []string{requirementID}
+		return s.resyncCategory(ctx, tx, &change, edit.EditedBy)
+	})
+	return change, err
+}
+
+// withConfigEdit runs fn in a transaction holding the ADM session row lock, after
+// checking that the session's status allows configuration changes, and bumps the
+// session's configuration version when fn succeeds. Dry runs roll back after fn.
+func (s *CategoryStore) withConfigEdit(ctx context.Context, edit ConfigEdit, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockSessionConfiguration(ctx, tx, edit); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if edit.DryRun {
+		return nil
+	}
+	if err := bumpConfigurationVersion(ctx, tx, edit.SessionID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit configuration change: %w", err)
+	}
+	return nil
+}
+
+func lockSessionConfiguration(ctx context.Context, tx *sql.Tx, edit ConfigEdit) error {
+	var status SessionStatus
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM adm_sessions WHERE id = $1 FOR UPDATE`, edit.SessionID).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return err
+		}
+		return fmt.Errorf("lock session: %w", err)
+	}
+	switch status {
+	case workflow.SessionClosed:
+		return &workflow.TransitionError{Machine: "ADM session", From: string(status), Event: "edit_configuration"}
+	case workflow.SessionActive:
+		if !edit.Force {
+			return ErrConfigurationLocked
+		}
+	}
+	return nil
+}
+
+func categoryExists(ctx context.Context, tx *sql.Tx, admSessionID, categoryID string) error {
+	var exists bool
+	const query = `SELECT EXISTS (SELECT 1 FROM adm_categories WHERE id = $1 AND adm_session_id = $2)`
+	if err := tx.QueryRowContext(ctx, query, categoryID, admSessionID).Scan(&exists); err != nil {
+		return fmt.Errorf("check category: %w", err)
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// checkRequirements returns an *UnknownRequirementsError unless every ID is a
+// requirement of the ADM session.
+func checkRequirements(ctx context.Context, tx *sql.Tx, admSessionID string, requirementIDs []string) error {
+	if len(requirementIDs) == 0 {
+		return nil
+	}
+	const query = `
+        SELECT id FROM UNNEST($2::text[]) AS wanted(id)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM adm_document_requirements r
+            WHERE r.id = wanted.id AND r.adm_session_id = $1
+        )
+        ORDER BY id;
+    `
+	rows, err := tx.QueryContext(ctx, query, admSessionID, pq.Array(requirementIDs))
+	if err != nil {
+		return fmt.Errorf("check requirements: %w", err)
+	}
+	defer rows.Close()
+
+	var unknown []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("scan requirement id: %w", err)
+		}
+		unknown = append(unknown, id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate requirement ids: %w", err)
+	}
+	if len(unknown) > 0 {
+		return &UnknownRequirementsError{IDs: unknown}
+	}
+	return nil
+}
+
+// setCategoryRequirements replaces the links of a category and returns the
+// requirement IDs it added and removed.
+func setCategoryRequirements(ctx context.Context, tx *sql.Tx, admSessionID, categoryID string, requirementIDs []string) (added, removed []string, err error) {
+	if err := checkRequirements(ctx, tx, admSessionID, requirementIDs); err != nil {
+		return nil, nil, err
+	}
+	const prune = `
+        DELETE FROM adm_category_requirements
+        WHERE category_id = $1 AND NOT (document_requirement_id = ANY($2::text[]))
+        RETURNING document_requirement_id;
+    `
+	if removed, err = queryIDs(ctx, tx, prune, categoryID, pq.Array(nonNil(requirementIDs))); err != nil {
+		return nil, nil, fmt.Errorf("unlink requirements: %w", err)
+	}
+	const link = `
+        INSERT INTO adm_category_requirements (category_id, document_requirement_id, created_at)
+        SELECT $1, id, NOW() FROM UNNEST($2::text[]) AS wanted(id)
+        ON CONFLICT DO NOTHING
+        RETURNING document_requirement_id;
+    `
+	if added, err = queryIDs(ctx, tx, link, categoryID, pq.Array(requirementIDs)); err != nil {
+		return nil, nil, fmt.Errorf("link requirements: %w", err)
+	}
+	return added, removed, nil
+}
+
+// queryIDs runs a statement returning one text column and collects it.
+func queryIDs(ctx context.Context, q queryer, query string, args ...any) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// expectOneRow turns an UPDATE or DELETE that matched nothing into sql.ErrNoRows.
+func expectOneRow(res sql.Result, action string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx, so listings can run inside a
+// configuration transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
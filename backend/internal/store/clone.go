@@ -0,0 +1,182 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/workflow"
+
+	"github.com/lib/pq"
+)
+
+// CloneSessionParams describes the draft session created from an existing one.
+type CloneSessionParams struct {
+	SourceID       string
+	ID             string
+	Label          string
+	StartAt        time.Time
+	EndAt          time.Time
+	CreatedByLogin string
+}
+
+// CloneResult counts the configuration rows copied into the new session.
+type CloneResult struct {
+	Categories   int
+	Requirements int
+	Links        int
+}
+
+// Clone creates a draft ADM session with the configuration (eligibility included),
+// categories, document requirements and category links of SourceID, all under fresh
+// IDs, in one transaction. Students, submissions and timelines are not copied. It
+// returns sql.ErrNoRows for an unknown source and ErrLabelTaken for a used label.
+func (s *SessionStore) Clone(ctx context.Context, params CloneSessionParams) (CloneResult, error) {
+	var result CloneResult
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// FOR SHARE keeps the source configuration from being edited while it is copied.
+	var configuration []byte
+	if err := tx.QueryRowContext(ctx, `SELECT configuration FROM adm_sessions WHERE id = $1 FOR SHARE`, params.SourceID).Scan(&configuration); err != nil {
+		if err == sql.ErrNoRows {
+			return result, err
+		}
+		return result, fmt.Errorf("lock source session: %w", err)
+	}
+
+	const insertSession = `
+        INSERT INTO adm_sessions (
+            id, label, start_at, end_at, status, configuration,
+            created_by_login, created_at, updated_at
+        ) VALUES ($1,$2,$3,$4,$5,$6,$7,NOW(),NOW());
+    `
+	if _, err := tx.ExecContext(ctx, insertSession, params.ID, params.Label, params.StartAt, params.EndAt, workflow.SessionDraft, nullJSON(configuration), params.CreatedByLogin); err != nil {
+		if isUniqueViolation(err, "adm_sessions_label_uniq") {
+			return result, ErrLabelTaken
+		}
+		return result, fmt.Errorf("insert session: %w", err)
+	}
+
+	requirements, err := listRequirements(ctx, tx, params.SourceID)
+	if err != nil {
+		return result, err
+	}
+	categories, err := listCategories(ctx, tx, params.SourceID)
+	if err != nil {
+		return result, err
+	}
+
+	// newIDs maps every copied category and requirement ID to its replacement. All
+	// of them are allocated before any logic is rewritten, so rules may refer to
+	// categories as well as requirements.
+	newIDs := make(map[string]string, len(requirements)+len(categories))
+	for _, req := range requirements {
+		if newIDs[req.ID], err = ids.New("adm_document_requirement"); err != nil {
+			return result, fmt.Errorf("generate requirement id: %w", err)
+		}
+	}
+	for _, category := range categories {
+		if newIDs[category.ID], err = ids.New("adm_category"); err != nil {
+			return result, fmt.Errorf("generate category id: %w", err)
+		}
+	}
+
+	const insertRequirement = `
+        INSERT INTO adm_document_requirements (
+            id, adm_session_id, code, title, description, accepted_mime_types,
+            max_file_size_bytes, reminder_order, is_mandatory, created_at, updated_at
+        ) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,NOW(),NOW());
+    `
+	for _, req := range requirements {
+		if _, err := tx.ExecContext(ctx, insertRequirement, newIDs[req.ID], params.ID, req.Code, req.Title, req.Description, pq.Array(mimeTypes(req.AcceptedMimeTypes)), req.MaxFileSizeBytes, req.ReminderOrder, req.IsMandatory); err != nil {
+			return result, fmt.Errorf("insert requirement %s: %w", req.Code, err)
+		}
+	}
+	result.Requirements = len(requirements)
+
+	const insertCategory = `
+        INSERT INTO adm_categories (
+            id, adm_session_id, code, label, description, questionnaire_logic, is_active,
+            created_at, updated_at
+        ) VALUES ($1,$2,$3,$4,$5,$6,$7,NOW(),NOW());
+    `
+	const insertLink = `
+        INSERT INTO adm_category_requirements (category_id, document_requirement_id, created_at)
+        SELECT $1, old.id, NOW() FROM UNNEST($2::text[]) AS old(id);
+    `
+	for _, category := range categories {
+		logic, err := remapIDs(category.QuestionnaireLogic, newIDs)
+		if err != nil {
+			return result, fmt.Errorf("remap questionnaire logic of %s: %w", category.Code, err)
+		}
+		if _, err := tx.ExecContext(ctx, insertCategory, newIDs[category.ID], params.ID, category.Code, category.Label, category.Description, nullJSON(logic), category.IsActive); err != nil {
+			return result, fmt.Errorf("insert category %s: %w", category.Code, err)
+		}
+
+		linked := make([]string, 0, len(category.RequirementIDs))
+		for _, requirementID := range category.RequirementIDs {
+			linked = append(linked, newIDs[requirementID])
+		}
+		if _, err := tx.ExecContext(ctx, insertLink, newIDs[category.ID], pq.Array(linked)); err != nil {
+			return result, fmt.Errorf("link requirements of %s: %w", category.Code, err)
+		}
+		result.Links += len(linked)
+	}
+	result.Categories = len(categories)
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("commit session clone: %w", err)
+	}
+	return result, nil
+}
+
+// remapIDs rewrites every string value of a JSON document that equals a key of
+// newIDs. Object keys and numbers are left untouched, so question identifiers and
+// bounds survive; only references to copied categories and requirements change.
+func remapIDs(raw json.RawMessage, newIDs map[string]string) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	changed := false
+	var walk func(value any) any
+	walk = func(value any) any {
+		switch v := value.(type) {
+		case string:
+			if id, ok := newIDs[v]; ok {
+				changed = true
+				return id
+			}
+		case []any:
+			for i := range v {
+				v[i] = walk(v[i])
+			}
+		case map[string]any:
+			for key := range v {
+				v[key] = walk(v[key])
+			}
+		}
+		return value
+	}
+	doc = walk(doc)
+
+	if !changed {
+		return raw, nil
+	}
+	return json.Marshal(doc)
+}
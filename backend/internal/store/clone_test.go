@@ -0,0 +1,39 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRemapIDsRewritesReferencesOnly(t *testing.T) {
+	raw := json.RawMessage(`{"required":["status"],"rule":{"all":[{"question":"status","equals":"adm_document_requirement_old"},{"question":"age","gte":18},{"question":"adm_category_old","in":["x","adm_category_old"]}]}}`)
+	newIDs := map[string]string{
+		"adm_document_requirement_old": "adm_document_requirement_new",
+		"adm_category_old":             "adm_category_new",
+	}
+
+	remapped, err := remapIDs(raw, newIDs)
+	if err != nil {
+		t.Fatalf("remap: %v", err)
+	}
+	want := `{"required":["status"],"rule":{"all":[{"equals":"adm_document_requirement_new","question":"status"},{"gte":18,"question":"age"},{"in":["x","adm_category_new"],"question":"adm_category_new"}]}}`
+	if string(remapped) != want {
+		t.Fatalf("remapped:\n%s\nwant:\n%s", remapped, want)
+	}
+}
+
+func TestRemapIDsKeepsUnrelatedLogicVerbatim(t *testing.T) {
+	raw := json.RawMessage(`{"rule": {"question": "campus", "equals": "paris", "gt": 1.50}}`)
+
+	remapped, err := remapIDs(raw, map[string]string{"adm_category_old": "adm_category_new"})
+	if err != nil {
+		t.Fatalf("remap: %v", err)
+	}
+	if string(remapped) != string(raw) {
+		t.Fatalf("logic without references was rewritten: %s", remapped)
+	}
+
+	if remapped, err := remapIDs(nil, nil); err != nil || remapped != nil {
+		t.Fatalf("nil logic: %s, %v", remapped, err)
+	}
+}
@@ -0,0 +1,399 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"adm-backend/internal/configbundle"
+	"adm-backend/internal/eligibility"
+	"adm-backend/internal/ids"
+
+	"github.com/lib/pq"
+)
+
+// errRollback aborts a configuration transaction that has nothing to write.
+var errRollback = errors.New("rollback")
+
+// ImportResult reports the outcome of ImportConfiguration.
+type ImportResult struct {
+	Diff configbundle.Diff
+	// Applied is false for dry runs and for bundles that change nothing.
+	Applied bool
+	// Version is the configuration version after the import.
+	Version int
+	// Requirements lists the categories whose requirements changed and the student
+	// sessions re-synced because of it.
+	Requirements []RequirementChange
+}
+
+// configurationState is an ADM session's configuration as stored.
+type configurationState struct {
+	label         string
+	configuration map[string]json.RawMessage
+	requirements  []DocumentRequirement
+	categories    []Category
+}
+
+// ExportConfiguration returns the configuration of an ADM session as a bundle, or
+// sql.ErrNoRows.
+func (s *CategoryStore) ExportConfiguration(ctx context.Context, admSessionID string) (*configbundle.Bundle, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := loadConfiguration(ctx, tx, admSessionID)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := state.bundle(admSessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit configuration export: %w", err)
+	}
+	return bundle, nil
+}
+
+// ImportConfiguration upserts the categories and requirements of bundle by code,
+// replaces the links of the categories it lists and sets the eligibility rule and
+// questionnaire schema it carries. Rows the bundle does not mention are kept. Student
+// sessions of categories whose requirements changed are re-synced. A dry run applies
+// the bundle and rolls back, so it reports the same diff and impact.
+func (s *CategoryStore) ImportConfiguration(ctx context.Context, edit ConfigEdit, bundle *configbundle.Bundle) (ImportResult, error) {
+	var result ImportResult
+	err := s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		state, err := loadConfiguration(ctx, tx, edit.SessionID)
+		if err != nil {
+			return err
+		}
+		current, err := state.bundle(edit.SessionID)
+		if err != nil {
+			return err
+		}
+		result.Diff = configbundle.Compare(current, bundle)
+		result.Version = current.Source.ConfigurationVersion
+		if result.Diff.Empty() {
+			return errRollback
+		}
+		changes, err := applyConfiguration(ctx, tx, edit.SessionID, state, current, bundle, result.Diff)
+		if err != nil {
+			return err
+		}
+		for i := range changes {
+			if err := s.resyncCategory(ctx, tx, &changes[i], edit.EditedBy); err != nil {
+				return err
+			}
+		}
+		result.Requirements = changes
+		return nil
+	})
+	switch {
+	case errors.Is(err, errRollback):
+		return result, nil
+	case err != nil:
+		return result, err
+	case edit.DryRun:
+		return result, nil
+	}
+	// withConfigEdit bumped the version while holding the session row lock.
+	result.Applied = true
+	result.Version++
+	return result, nil
+}
+
+func loadConfiguration(ctx context.Context, q queryer, admSessionID string) (configurationState, error) {
+	var (
+		state configurationState
+		raw   []byte
+	)
+	if err := q.QueryRowContext(ctx, `SELECT label, configuration FROM adm_sessions WHERE id = $1`, admSessionID).Scan(&state.label, &raw); err != nil {
+		if err == sql.ErrNoRows {
+			return state, err
+		}
+		return state, fmt.Errorf("query session configuration: %w", err)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &state.configuration); err != nil {
+			return state, fmt.Errorf("decode session configuration: %w", err)
+		}
+	}
+
+	var err error
+	if state.requirements, err = listRequirements(ctx, q, admSessionID); err != nil {
+		return state, err
+	}
+	if state.categories, err = listCategories(ctx, q, admSessionID); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+func (state configurationState) bundle(admSessionID string) (*configbundle.Bundle, error) {
+	bundle := &configbundle.Bundle{
+		Format:       configbundle.Format,
+		Version:      configbundle.Version,
+		Source:       &configbundle.Source{SessionID: admSessionID, Label: state.label},
+		Requirements: make([]configbundle.Requirement, 0, len(state.requirements)),
+		Categories:   make([]configbundle.Category, 0, len(state.categories)),
+	}
+
+	if raw, ok := state.configuration[configbundle.VersionKey]; ok {
+		if err := json.Unmarshal(raw, &bundle.Source.ConfigurationVersion); err != nil {
+			return nil, fmt.Errorf("decode configuration version: %w", err)
+		}
+	}
+	if raw, ok := state.configuration[eligibility.ConfigurationKey]; ok {
+		rule, err := eligibility.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		if bundle.Eligibility, err = json.Marshal(rule); err != nil {
+			return nil, fmt.Errorf("encode eligibility rule: %w", err)
+		}
+	}
+	if raw, ok := state.configuration[configbundle.QuestionnaireKey]; ok && string(raw) != "null" {
+		bundle.Questionnaire = raw
+	}
+
+	codes := make(map[string]string, len(state.requirements))
+	for _, req := range state.requirements {
+		codes[req.ID] = req.Code
+		mandatory := req.IsMandatory
+		bundle.Requirements = append(bundle.Requirements, configbundle.Requirement{
+			Code:              req.Code,
+			Title:             req.Title,
+			Description:       req.Description.String,
+			AcceptedMimeTypes: req.AcceptedMimeTypes,
+			MaxFileSizeBytes:  req.MaxFileSizeBytes.Int64,
+			IsMandatory:       &mandatory,
+		})
+	}
+	for _, category := range state.categories {
+		active := category.IsActive
+		linked := make([]string, 0, len(category.RequirementIDs))
+		for _, id := range category.RequirementIDs {
+			linked = append(linked, codes[id])
+		}
+		bundle.Categories = append(bundle.Categories, configbundle.Category{
+			Code:               category.Code,
+			Label:              category.Label,
+			Description:        category.Description.String,
+			IsActive:           &active,
+			QuestionnaireLogic: category.QuestionnaireLogic,
+			Requirements:       linked,
+		})
+	}
+	return bundle, nil
+}
+
+// applyConfiguration writes the items listed in diff and returns the requirement
+// changes of existing categories. The caller holds the session row lock.
+func applyConfiguration(ctx context.Context, tx *sql.Tx, admSessionID string, state configurationState, current, bundle *configbundle.Bundle, diff configbundle.Diff) ([]RequirementChange, error) {
+	changed := make(map[configbundle.Ref]bool, len(diff.Changes))
+	patch := make(map[string]json.RawMessage)
+	for _, change := range diff.Changes {
+		switch change.Kind {
+		case configbundle.KindEligibility:
+			patch[eligibility.ConfigurationKey] = bundle.Eligibility
+		case configbundle.KindQuestionnaire:
+			patch[configbundle.QuestionnaireKey] = bundle.Questionnaire
+		default:
+			changed[configbundle.Ref{Kind: change.Kind, Code: change.Code}] = true
+		}
+	}
+
+	if len(patch) > 0 {
+		raw, err := json.Marshal(patch)
+		if err != nil {
+			return nil, fmt.Errorf("encode configuration patch: %w", err)
+		}
+		const update = `
+            UPDATE adm_sessions
+            SET configuration = COALESCE(configuration, '{}'::jsonb) || $2::jsonb
+            WHERE id = $1;
+        `
+		if _, err := tx.ExecContext(ctx, update, admSessionID, string(raw)); err != nil {
+			return nil, fmt.Errorf("update session configuration: %w", err)
+		}
+	}
+
+	requirementIDs := make(map[string]string, len(state.requirements)+len(bundle.Requirements))
+	wasMandatory := make(map[string]bool, len(state.requirements))
+	for _, req := range state.requirements {
+		requirementIDs[req.Code] = req.ID
+		wasMandatory[req.ID] = req.IsMandatory
+	}
+	var madeMandatory []string
+	for _, req := range bundle.Requirements {
+		if !changed[configbundle.Ref{Kind: configbundle.KindRequirement, Code: req.Code}] {
+			continue
+		}
+		params := RequirementParams{
+			Code:              req.Code,
+			Title:             req.Title,
+			Description:       sql.NullString{String: req.Description, Valid: req.Description != ""},
+			AcceptedMimeTypes: req.AcceptedMimeTypes,
+			MaxFileSizeBytes:  sql.NullInt64{Int64: req.MaxFileSizeBytes, Valid: req.MaxFileSizeBytes > 0},
+			IsMandatory:       req.Mandatory(),
+		}
+		id, exists := requirementIDs[req.Code]
+		if !exists {
+			var err error
+			if id, err = ids.New("adm_document_requirement"); err != nil {
+				return nil, fmt.Errorf("generate requirement id: %w", err)
+			}
+			requirementIDs[req.Code] = id
+		}
+		if err := upsertRequirement(ctx, tx, admSessionID, id, params); err != nil {
+			return nil, fmt.Errorf("import requirement %s: %w", req.Code, err)
+		}
+		if exists && params.IsMandatory && !wasMandatory[id] {
+			madeMandatory = append(madeMandatory, id)
+		}
+	}
+
+	order := configbundle.RequirementOrder(current, bundle)
+	orderedIDs := make([]string, 0, len(order))
+	for _, code := range order {
+		orderedIDs = append(orderedIDs, requirementIDs[code])
+	}
+	if err := setReminderOrder(ctx, tx, admSessionID, orderedIDs); err != nil {
+		return nil, err
+	}
+
+	var changes []RequirementChange
+	byCategory := make(map[string]int)
+	categoryIDs := make(map[string]string, len(state.categories))
+	existing := make(map[string]bool, len(state.categories))
+	for _, category := range state.categories {
+		categoryIDs[category.Code] = category.ID
+		existing[category.ID] = true
+	}
+	for _, category := range bundle.Categories {
+		if !changed[configbundle.Ref{Kind: configbundle.KindCategory, Code: category.Code}] {
+			continue
+		}
+		params := CategoryParams{
+			Code:               category.Code,
+			Label:              category.Label,
+			Description:        sql.NullString{String: category.Description, Valid: category.Description != ""},
+			QuestionnaireLogic: category.QuestionnaireLogic,
+			IsActive:           category.Active(),
+		}
+		id, exists := categoryIDs[category.Code]
+		if !exists {
+			var err error
+			if id, err = ids.New("adm_category"); err != nil {
+				return nil, fmt.Errorf("generate category id: %w", err)
+			}
+		}
+		if err := upsertCategory(ctx, tx, admSessionID, id, params); err != nil {
+			return nil, fmt.Errorf("import category %s: %w", category.Code, err)
+		}
+
+		linked := make([]string, 0, len(category.Requirements))
+		for _, code := range category.Requirements {
+			linked = append(linked, requirementIDs[code])
+		}
+		added, removed, err := setCategoryRequirements(ctx, tx, admSessionID, id, linked)
+		if err != nil {
+			return nil, fmt.Errorf("import category %s: %w", category.Code, err)
+		}
+		// New categories have no students to re-sync.
+		if change := (RequirementChange{CategoryID: id, Added: added, Removed: removed}); exists && !change.Empty() {
+			byCategory[id] = len(changes)
+			changes = append(changes, change)
+		}
+	}
+
+	// Requirements switched to mandatory concern every existing category linked to
+	// them, including categories the bundle does not list.
+	if len(madeMandatory) > 0 {
+		const linked = `
+            SELECT category_id, document_requirement_id FROM adm_category_requirements
+            WHERE document_requirement_id = ANY($1::text[])
+            ORDER BY category_id, document_requirement_id;
+        `
+		rows, err := tx.QueryContext(ctx, linked, pq.Array(madeMandatory))
+		if err != nil {
+			return nil, fmt.Errorf("query requirement categories: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var categoryID, requirementID string
+			if err := rows.Scan(&categoryID, &requirementID); err != nil {
+				return nil, fmt.Errorf("scan requirement category: %w", err)
+			}
+			if !existing[categoryID] {
+				continue
+			}
+			i, ok := byCategory[categoryID]
+			if !ok {
+				i = len(changes)
+				byCategory[categoryID] = i
+				changes = append(changes, RequirementChange{CategoryID: categoryID})
+			}
+			changes[i].MadeMandatory = append(changes[i].MadeMandatory, requirementID)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("iterate requirement categories: %w", err)
+		}
+	}
+	return changes, nil
+}
+
+func upsertRequirement(ctx context.Context, tx *sql.Tx, admSessionID, id string, params RequirementParams) error {
+	const upsert = `
+        INSERT INTO adm_document_requirements (
+            id, adm_session_id, code, title, description, accepted_mime_types,
+            max_file_size_bytes, is_mandatory, created_at, updated_at
+        ) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NOW(),NOW())
+        ON CONFLICT (id) DO UPDATE
+        SET title = EXCLUDED.title,
+            description = EXCLUDED.description,
+            accepted_mime_types = EXCLUDED.accepted_mime_types,
+            max_file_size_bytes = EXCLUDED.max_file_size_bytes,
+            is_mandatory = EXCLUDED.is_mandatory;
+    `
+	_, err := tx.ExecContext(ctx, upsert, id, admSessionID, params.Code, params.Title, params.Description, pq.Array(mimeTypes(params.AcceptedMimeTypes)), params.MaxFileSizeBytes, params.IsMandatory)
+	return err
+}
+
+func upsertCategory(ctx context.Context, tx *sql.Tx, admSessionID, id string, params CategoryParams) error {
+	const upsert = `
+        INSERT INTO adm_categories (
+            id, adm_session_id, code, label, description, questionnaire_logic, is_active,
+            created_at, updated_at
+        ) VALUES ($1,$2,$3,$4,$5,$6,$7,NOW(),NOW())
+        ON CONFLICT (id) DO UPDATE
+        SET label = EXCLUDED.label,
+            description = EXCLUDED.description,
+            questionnaire_logic = EXCLUDED.questionnaire_logic,
+            is_active = EXCLUDED.is_active;
+    `
+	_, err := tx.ExecContext(ctx, upsert, id, admSessionID, params.Code, params.Label, params.Description, nullJSON(params.QuestionnaireLogic), params.IsActive)
+	return err
+}
+
+// bumpConfigurationVersion increments the configuration_version counter of an ADM
+// session, so exported bundles tell which state they were taken from.
+func bumpConfigurationVersion(ctx context.Context, tx *sql.Tx, admSessionID string) error {
+	const bump = `
+        UPDATE adm_sessions
+        SET configuration = COALESCE(configuration, '{}'::jsonb) || jsonb_build_object(
+            'configuration_version',
+            COALESCE((configuration->>'configuration_version')::int, 0) + 1
+        )
+        WHERE id = $1;
+    `
+	if _, err := tx.ExecContext(ctx, bump, admSessionID); err != nil {
+		return fmt.Errorf("bump configuration version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"adm-backend/internal/workflow"
+
+	"github.com/lib/pq"
+)
+
+// ExpireEnded invalidates every unfinished student session whose ADM session is closed,
+// recording a deadline_expired event for each. Each student session is expired in its
+// own transaction so a long roster never holds many row locks at once. It returns the
+// number of student sessions expired.
+func (s *StudentSessionStore) ExpireEnded(ctx context.Context) (int, error) {
+	sources := make([]string, 0, 3)
+	for _, status := range workflow.Sources(workflow.ExpireSession) {
+		sources = append(sources, string(status))
+	}
+
+	const query = `
+        SELECT ss.id
+        FROM adm_student_sessions ss
+        JOIN adm_sessions s ON s.id = ss.adm_session_id
+        WHERE s.status = 'closed' AND ss.archived_at IS NULL AND ss.status::text = ANY($1)
+        ORDER BY ss.id;
+    `
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(sources))
+	if err != nil {
+		return 0, fmt.Errorf("query unfinished student sessions: %w", err)
+	}
+	var candidates []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan student session id: %w", err)
+		}
+		candidates = append(candidates, id)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("iterate unfinished student sessions: %w", err)
+	}
+	rows.Close()
+
+	expired := 0
+	for _, id := range candidates {
+		ok, err := s.expire(ctx, id)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// expire reports false when the student session left an expirable status since it was listed.
+func (s *StudentSessionStore) expire(ctx context.Context, studentSessionID string) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockStudentSession(ctx, tx, studentSessionID)
+	if err != nil {
+		return false, err
+	}
+	outcome, err := workflow.Fire(locked.Status, workflow.ExpireSession, workflow.Input{})
+	if errors.Is(err, workflow.ErrIllegalTransition) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	payload := map[string]any{
+		"adm_session_id": locked.AdmSessionID,
+		"revision":       locked.CurrentRevision,
+	}
+	if err := s.applyTransition(ctx, tx, studentSessionID, outcome, payload, ""); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit expiration: %w", err)
+	}
+	return true, nil
+}
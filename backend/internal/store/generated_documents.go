@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"adm-backend/internal/workflow"
+)
+
+// DocumentContext holds what document templates print about a student session.
+type DocumentContext struct {
+	StudentSessionID string
+	StudentLogin     string
+	Status           StudentSessionStatus
+	CurrentRevision  int
+	SessionLabel     string
+	SessionStartAt   time.Time
+	SessionEndAt     time.Time
+	CategoryLabel    sql.NullString
+	// ValidatedAt is the last review, which is the validation for a validated session.
+	ValidatedAt sql.NullTime
+}
+
+type SaveGeneratedDocumentParams struct {
+	ID               string
+	StudentSessionID string
+	DocumentType     string
+	StorageKey       string
+	FileName         string
+	GeneratedBy      string
+}
+
+// GetDocumentContext returns the data printed on generated documents, or
+// sql.ErrNoRows when the student session does not exist.
+func (s *StudentSessionStore) GetDocumentContext(ctx context.Context, studentSessionID string) (DocumentContext, error) {
+	const query = `
+        SELECT
+            ss.id,
+            ss.student_login,
+            ss.status,
+            ss.current_revision,
+            s.label,
+            s.start_at,
+            s.end_at,
+            c.label,
+            ss.last_reviewed_at
+        FROM adm_student_sessions ss
+        JOIN adm_sessions s ON s.id = ss.adm_session_id
+        LEFT JOIN adm_categories c ON c.id = ss.category_id
+        WHERE ss.id = $1;
+    `
+	var doc DocumentContext
+	err := s.db.QueryRowContext(ctx, query, studentSessionID).Scan(
+		&doc.StudentSessionID,
+		&doc.StudentLogin,
+		&doc.Status,
+		&doc.CurrentRevision,
+		&doc.SessionLabel,
+		&doc.SessionStartAt,
+		&doc.SessionEndAt,
+		&doc.CategoryLabel,
+		&doc.ValidatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return DocumentContext{}, err
+		}
+		return DocumentContext{}, fmt.Errorf("query document context: %w", err)
+	}
+	return doc, nil
+}
+
+// SaveGeneratedDocument records a generated document, replacing the previous one of
+// the same type for the student session: the row keeps its ID, points at the new file
+// and is no longer invalidated. The student session must still be validated. The
+// storage key of the replaced file is returned so the caller can discard it.
+func (s *StudentSessionStore) SaveGeneratedDocument(ctx context.Context, params SaveGeneratedDocumentParams) (GeneratedDocument, string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return GeneratedDocument{}, "", fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockStudentSession(ctx, tx, params.StudentSessionID)
+	if err != nil {
+		return GeneratedDocument{}, "", err
+	}
+	outcome, err := workflow.Fire(locked.Status, workflow.GenerateDocuments, workflow.Input{})
+	if err != nil {
+		return GeneratedDocument{}, "", err
+	}
+
+	var replacedKey string
+	const previous = `
+        SELECT storage_key FROM adm_generated_documents
+        WHERE student_session_id = $1 AND document_type = $2;
+    `
+	if err := tx.QueryRowContext(ctx, previous, params.StudentSessionID, params.DocumentType).Scan(&replacedKey); err != nil && err != sql.ErrNoRows {
+		return GeneratedDocument{}, "", fmt.Errorf("query previous generated document: %w", err)
+	}
+
+	const upsert = `
+        INSERT INTO adm_generated_documents (
+            id, student_session_id, document_type, storage_key, file_name,
+            generated_by_login, generated_at, created_at
+        ) VALUES ($1,$2,$3,$4,$5,$6,NOW(),NOW())
+        ON CONFLICT (student_session_id, document_type) DO UPDATE
+        SET storage_key = EXCLUDED.storage_key,
+            file_name = EXCLUDED.file_name,
+            generated_by_login = EXCLUDED.generated_by_login,
+            generated_at = EXCLUDED.generated_at,
+            invalidated_at = NULL,
+            invalidated_by_login = NULL
+        RETURNING id, document_type, storage_key, file_name, generated_by_login, generated_at;
+    `
+	var doc GeneratedDocument
+	err = tx.QueryRowContext(ctx, upsert,
+		params.ID,
+		params.StudentSessionID,
+		params.DocumentType,
+		params.StorageKey,
+		params.FileName,
+		params.GeneratedBy,
+	).Scan(&doc.ID, &doc.DocumentType, &doc.StorageKey, &doc.FileName, &doc.GeneratedBy, &doc.GeneratedAt)
+	if err != nil {
+		return GeneratedDocument{}, "", fmt.Errorf("save generated document: %w", err)
+	}
+
+	payload := map[string]any{
+		"generated_document_id": doc.ID,
+		"document_type":         doc.DocumentType,
+		"file_name":             doc.FileName,
+		"revision":              locked.CurrentRevision,
+		"replaced":              replacedKey != "",
+	}
+	if err := s.applyTransition(ctx, tx, params.StudentSessionID, outcome, payload, params.GeneratedBy); err != nil {
+		return GeneratedDocument{}, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return GeneratedDocument{}, "", fmt.Errorf("commit generated document: %w", err)
+	}
+	return doc, replacedKey, nil
+}
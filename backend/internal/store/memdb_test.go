@@ -0,0 +1,307 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memDB answers the statements the student session transitions run, from memory. It
+// models just enough of adm_student_sessions, adm_document_submissions and
+// adm_storage_cleanup_queue to follow storage keys across revisions; every student
+// session belongs to category c1. Changes made in a transaction are only kept when it
+// commits.
+type memDB struct {
+	mu sync.Mutex
+	// mandatory lists the mandatory requirement IDs.
+	mandatory   map[string]bool
+	sessions    []memStudentSession
+	submissions []memSubmission
+	queue       []memQueueItem
+	events      []string
+}
+
+type memStudentSession struct {
+	id              string
+	status          StudentSessionStatus
+	lockedByStudent bool
+	revision        int64
+}
+
+type memSubmission struct {
+	id            string
+	session       string
+	requirement   string
+	revision      int64
+	status        SubmissionStatus
+	key           string
+	fileDeletedAt bool
+}
+
+type memQueueItem struct {
+	key       string
+	session   string
+	processed bool
+}
+
+func openMemDB(t *testing.T, m *memDB) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(m)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func (m *memDB) Connect(context.Context) (driver.Conn, error) { return &memConn{db: m}, nil }
+func (m *memDB) Driver() driver.Driver                        { return memDriver{} }
+
+// revision returns the submissions of a student session revision by requirement.
+func (m *memDB) revision(session string, revision int64) map[string]memSubmission {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := make(map[string]memSubmission)
+	for _, sub := range m.submissions {
+		if sub.session == session && sub.revision == revision {
+			found[sub.requirement] = sub
+		}
+	}
+	return found
+}
+
+// queued returns the storage keys waiting in the cleanup queue.
+func (m *memDB) queued() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for _, item := range m.queue {
+		if !item.processed {
+			keys = append(keys, item.key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *memDB) session(id string) memStudentSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, session := range m.sessions {
+		if session.id == id {
+			return session
+		}
+	}
+	return memStudentSession{}
+}
+
+type memDriver struct{}
+
+func (memDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use the connector") }
+
+type memConn struct {
+	db       *memDB
+	snapshot *memDB
+}
+
+func (c *memConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *memConn) Close() error                        { return nil }
+
+func (c *memConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.snapshot = &memDB{
+		sessions:    append([]memStudentSession(nil), c.db.sessions...),
+		submissions: append([]memSubmission(nil), c.db.submissions...),
+		queue:       append([]memQueueItem(nil), c.db.queue...),
+		events:      append([]string(nil), c.db.events...),
+	}
+	return c, nil
+}
+
+func (c *memConn) Commit() error { return nil }
+
+func (c *memConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.sessions = c.snapshot.sessions
+	c.db.submissions = c.snapshot.submissions
+	c.db.queue = c.snapshot.queue
+	c.db.events = c.snapshot.events
+	return nil
+}
+
+func (c *memConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	m := c.db
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case strings.Contains(query, "SELECT id, adm_session_id, status, locked_by_student, current_revision, category_id"):
+		rows := &memRows{columns: []string{"id", "adm_session_id", "status", "locked_by_student", "current_revision", "category_id"}}
+		for _, session := range m.sessions {
+			if session.id == args[0].Value {
+				rows.values = append(rows.values, []driver.Value{session.id, "s1", string(session.status), session.lockedByStudent, session.revision, "c1"})
+			}
+		}
+		return rows, nil
+	case strings.Contains(query, "FROM adm_document_requirements") && strings.Contains(query, "is_mandatory"):
+		rows := &memRows{columns: []string{"id"}}
+		// pq.Array encodes the IDs as {"a","b"}.
+		ids := strings.Split(strings.Trim(args[0].Value.(string), "{}"), ",")
+		sort.Strings(ids)
+		for _, id := range ids {
+			if id = strings.Trim(id, `"`); m.mandatory[id] {
+				rows.values = append(rows.values, []driver.Value{id})
+			}
+		}
+		return rows, nil
+	case strings.Contains(query, "WHERE category_id = $1"):
+		rows := &memRows{columns: []string{"id", "student_login", "status", "current_revision"}}
+		for _, session := range m.sessions {
+			rows.values = append(rows.values, []driver.Value{session.id, session.id, string(session.status), session.revision})
+		}
+		return rows, nil
+	case strings.Contains(query, "SELECT id, status, storage_key"):
+		rows := &memRows{columns: []string{"id", "status", "storage_key"}}
+		for _, sub := range m.submissions {
+			if sub.session == args[0].Value && sub.requirement == args[1].Value && sub.revision == args[2].Value {
+				rows.values = append(rows.values, []driver.Value{sub.id, string(sub.status), sub.key})
+			}
+		}
+		return rows, nil
+	case strings.Contains(query, "INSERT INTO adm_document_submissions") && strings.Contains(query, "VALUES"):
+		m.submissions = append(m.submissions, memSubmission{
+			id:          args[0].Value.(string),
+			session:     args[1].Value.(string),
+			requirement: args[2].Value.(string),
+			revision:    args[3].Value.(int64),
+			key:         args[4].Value.(string),
+			status:      SubmissionStatus(args[9].Value.(string)),
+		})
+		return &memRows{columns: []string{"uploaded_at"}, values: [][]driver.Value{{time.Now()}}}, nil
+	case strings.Contains(query, "UPDATE adm_document_submissions") && strings.Contains(query, "storage_key = $2"):
+		for i := range m.submissions {
+			sub := &m.submissions[i]
+			if sub.id == args[0].Value {
+				sub.key = args[1].Value.(string)
+				sub.status = SubmissionStatus(args[6].Value.(string))
+			}
+		}
+		return &memRows{columns: []string{"uploaded_at"}, values: [][]driver.Value{{time.Now()}}}, nil
+	case strings.Contains(query, "SELECT id, document_requirement_id FROM adm_document_submissions"):
+		rows := &memRows{columns: []string{"id", "document_requirement_id"}}
+		var found []memSubmission
+		for _, sub := range m.submissions {
+			if sub.session != args[0].Value || sub.revision != args[1].Value {
+				continue
+			}
+			if strings.Contains(query, "status = 'valid'") && sub.status != "valid" {
+				continue
+			}
+			if strings.Contains(query, "file_deleted_at IS NULL") && sub.fileDeletedAt {
+				continue
+			}
+			found = append(found, sub)
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].requirement < found[j].requirement })
+		for _, sub := range found {
+			rows.values = append(rows.values, []driver.Value{sub.id, sub.requirement})
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected query " + query)
+}
+
+func (c *memConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	m := c.db
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case strings.Contains(query, "DELETE FROM adm_storage_cleanup_queue"):
+		keys := make(map[string]bool)
+		for _, sub := range m.submissions {
+			if sub.session == args[0].Value {
+				keys[sub.key] = true
+			}
+		}
+		kept := m.queue[:0:0]
+		for _, item := range m.queue {
+			if item.processed || !keys[item.key] {
+				kept = append(kept, item)
+			}
+		}
+		m.queue = kept
+		return driver.RowsAffected(0), nil
+	case strings.Contains(query, "INSERT INTO adm_document_submissions") && strings.Contains(query, "WHERE id = $1"):
+		// A copy of submission $1 as $2 into revision $3, with status $4 when given.
+		for _, sub := range m.submissions {
+			if sub.id != args[0].Value {
+				continue
+			}
+			sub.id = args[1].Value.(string)
+			sub.revision = args[2].Value.(int64)
+			if len(args) == 4 {
+				sub.status = SubmissionStatus(args[3].Value.(string))
+			}
+			if !strings.Contains(query, "file_deleted_at") {
+				sub.fileDeletedAt = false
+			}
+			m.submissions = append(m.submissions, sub)
+			return driver.RowsAffected(1), nil
+		}
+		return nil, fmt.Errorf("no submission %v", args[0].Value)
+	case strings.Contains(query, "UPDATE adm_student_sessions"):
+		for i := range m.sessions {
+			session := &m.sessions[i]
+			if session.id != args[0].Value {
+				continue
+			}
+			session.status = StudentSessionStatus(args[1].Value.(string))
+			session.lockedByStudent = args[2].Value.(bool)
+			if args[4].Value.(bool) {
+				session.revision++
+			}
+		}
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "INSERT INTO adm_storage_cleanup_queue") && strings.Contains(query, "NOT EXISTS"):
+		// releaseStorageKey: key $1 is queued unless a submission or queue item has it.
+		for _, sub := range m.submissions {
+			if sub.key == args[0].Value {
+				return driver.RowsAffected(0), nil
+			}
+		}
+		for _, item := range m.queue {
+			if item.key == args[0].Value && !item.processed {
+				return driver.RowsAffected(0), nil
+			}
+		}
+		m.queue = append(m.queue, memQueueItem{key: args[0].Value.(string), session: args[1].Value.(string)})
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "INSERT INTO adm_timeline_events"):
+		m.events = append(m.events, fmt.Sprintf("%v %v", args[1].Value, args[2].Value))
+		return driver.RowsAffected(1), nil
+	}
+	return nil, errors.New("unexpected statement " + query)
+}
+
+type memRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *memRows) Columns() []string { return r.columns }
+func (r *memRows) Close() error      { return nil }
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/workflow"
+)
+
+type ReopenParams struct {
+	StudentSessionID string
+	Reason           string
+	// InvalidateGeneratedDocuments flags the documents generated for the validated file
+	// as no longer current.
+	InvalidateGeneratedDocuments bool
+	ReopenedBy                   string
+}
+
+type ReopenResult struct {
+	Status   StudentSessionStatus
+	Revision int
+	// Reopened lists the requirement IDs whose valid submission was copied as pending.
+	Reopened                      []string
+	InvalidatedGeneratedDocuments int
+}
+
+// Reopen sends a validated student session back to waiting_for_documents in a new
+// revision. Every valid submission whose file still exists is copied into the new
+// revision as pending so the student sees what was accepted; the rows of the validated
+// revision are left untouched.
+func (s *StudentSessionStore) Reopen(ctx context.Context, params ReopenParams) (ReopenResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ReopenResult{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockStudentSession(ctx, tx, params.StudentSessionID)
+	if err != nil {
+		return ReopenResult{}, err
+	}
+	outcome, err := workflow.Fire(locked.Status, workflow.ReopenSession, workflow.Input{Reason: params.Reason})
+	if err != nil {
+		return ReopenResult{}, err
+	}
+	revision := locked.CurrentRevision
+
+	// Validation queued the uploads for deletion; the new revision needs them back. This
+	// runs first so a worker holding some of the queue rows finishes, and flags the
+	// files it deleted, before the submissions are copied.
+	if err := cancelStudentSessionCleanup(ctx, tx, params.StudentSessionID); err != nil {
+		return ReopenResult{}, err
+	}
+
+	result := ReopenResult{Status: outcome.To, Revision: revision + 1}
+	if result.Reopened, err = reopenValidSubmissions(ctx, tx, params.StudentSessionID, revision, revision+1); err != nil {
+		return ReopenResult{}, err
+	}
+
+	if params.InvalidateGeneratedDocuments {
+		const invalidate = `
+            UPDATE adm_generated_documents
+            SET invalidated_at = NOW(), invalidated_by_login = $2
+            WHERE student_session_id = $1 AND invalidated_at IS NULL;
+        `
+		res, err := tx.ExecContext(ctx, invalidate, params.StudentSessionID, params.ReopenedBy)
+		if err != nil {
+			return ReopenResult{}, fmt.Errorf("invalidate generated documents: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return ReopenResult{}, fmt.Errorf("invalidate generated documents: %w", err)
+		}
+		result.InvalidatedGeneratedDocuments = int(n)
+	}
+
+	payload := map[string]any{
+		"revision":                        revision,
+		"new_revision":                    revision + 1,
+		"reason":                          params.Reason,
+		"reopened_requirements":           result.Reopened,
+		"invalidated_generated_documents": result.InvalidatedGeneratedDocuments,
+	}
+	if err := s.applyTransition(ctx, tx, params.StudentSessionID, outcome, payload, params.ReopenedBy); err != nil {
+		return ReopenResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ReopenResult{}, fmt.Errorf("commit reopen: %w", err)
+	}
+	return result, nil
+}
+
+// reopenValidSubmissions copies the valid submissions of fromRevision into toRevision
+// with the status ReopenDocument leads to and no decision. Submissions whose file was
+// already deleted by the storage cleanup are left out: there is nothing left to
+// review, so the student uploads the document again.
+func reopenValidSubmissions(ctx context.Context, tx *sql.Tx, studentSessionID string, fromRevision, toRevision int) ([]string, error) {
+	status, err := workflow.FireSubmission(workflow.SubmissionValid, workflow.ReopenDocument, workflow.Input{})
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+        SELECT id, document_requirement_id FROM adm_document_submissions
+        WHERE student_session_id = $1 AND revision_number = $2 AND status = 'valid'
+          AND file_deleted_at IS NULL
+        ORDER BY document_requirement_id;
+    `
+	rows, err := tx.QueryContext(ctx, query, studentSessionID, fromRevision)
+	if err != nil {
+		return nil, fmt.Errorf("query valid submissions: %w", err)
+	}
+	var sourceIDs, requirementIDs []string
+	for rows.Next() {
+		var id, requirementID string
+		if err := rows.Scan(&id, &requirementID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan valid submission: %w", err)
+		}
+		sourceIDs = append(sourceIDs, id)
+		requirementIDs = append(requirementIDs, requirementID)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("iterate valid submissions: %w", err)
+	}
+	rows.Close()
+
+	const copySubmission = `
+        INSERT INTO adm_document_submissions (
+            id, student_session_id, document_requirement_id, revision_number, status,
+            storage_key, file_name, file_size_bytes, checksum_sha256, uploaded_at,
+            uploaded_by_login, created_at, updated_at
+        )
+        SELECT $2, student_session_id, document_requirement_id, $3, $4,
+               storage_key, file_name, file_size_bytes, checksum_sha256, uploaded_at,
+               uploaded_by_login, NOW(), NOW()
+        FROM adm_document_submissions
+        WHERE id = $1;
+    `
+	for _, sourceID := range sourceIDs {
+		newID, err := ids.New("adm_document_submission")
+		if err != nil {
+			return nil, fmt.Errorf("generate submission id: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, copySubmission, sourceID, newID, toRevision, status); err != nil {
+			return nil, fmt.Errorf("reopen submission %s: %w", sourceID, err)
+		}
+	}
+	return requirementIDs, nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"adm-backend/internal/workflow"
+)
+
+func TestReopenTakesUploadsBackFromCleanup(t *testing.T) {
+	m := &memDB{
+		sessions: []memStudentSession{
+			{id: "ss1", status: workflow.Validated, revision: 1},
+		},
+		submissions: []memSubmission{
+			{id: "sub_id", session: "ss1", requirement: "r_id_card", revision: 1, status: workflow.SubmissionValid, key: "k_id_card"},
+			// The worker already deleted this one.
+			{id: "sub_contract", session: "ss1", requirement: "r_contract", revision: 1, status: workflow.SubmissionValid, key: "k_contract", fileDeletedAt: true},
+			{id: "other", session: "ss2", requirement: "r_id_card", revision: 1, status: workflow.SubmissionValid, key: "k_other"},
+		},
+		queue: []memQueueItem{
+			{key: "k_id_card", session: "ss1"},
+			{key: "k_contract", session: "ss1", processed: true},
+			{key: "k_other", session: "ss2"},
+		},
+	}
+	students := NewStudentSessionStore(openMemDB(t, m))
+
+	result, err := students.Reopen(context.Background(), ReopenParams{StudentSessionID: "ss1", Reason: "wrong contract dates", ReopenedBy: "staff"})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if want := []string{"r_id_card"}; !reflect.DeepEqual(result.Reopened, want) {
+		t.Fatalf("reopened = %v, want %v", result.Reopened, want)
+	}
+
+	// The reopened upload leaves the queue, other sessions' uploads stay in it.
+	if want := []string{"k_other"}; !reflect.DeepEqual(m.queued(), want) {
+		t.Fatalf("queued = %v, want %v", m.queued(), want)
+	}
+	revision := m.revision("ss1", 2)
+	if len(revision) != 1 {
+		t.Fatalf("revision 2 = %+v, want only the id card", revision)
+	}
+	if sub := revision["r_id_card"]; sub.status != workflow.SubmissionPending || sub.key != "k_id_card" {
+		t.Fatalf("reopened submission = %+v", sub)
+	}
+	if session := m.session("ss1"); session.status != workflow.WaitingForDocuments || session.revision != 2 {
+		t.Fatalf("student session = %+v", session)
+	}
+}
@@ -0,0 +1,277 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"adm-backend/internal/ids"
+
+	"github.com/lib/pq"
+)
+
+// RequirementParams holds the editable fields of a document requirement.
+type RequirementParams struct {
+	Code              string
+	Title             string
+	Description       sql.NullString
+	AcceptedMimeTypes []string
+	MaxFileSizeBytes  sql.NullInt64
+	IsMandatory       bool
+}
+
+// ListRequirements returns the document requirements of an ADM session in reminder order.
+func (s *CategoryStore) ListRequirements(ctx context.Context, admSessionID string) ([]DocumentRequirement, error) {
+	return listRequirements(ctx, s.db, admSessionID)
+}
+
+func listRequirements(ctx context.Context, q queryer, admSessionID string) ([]DocumentRequirement, error) {
+	const query = `
+        SELECT id, code, title, description, accepted_mime_types, max_file_size_bytes, reminder_order, is_mandatory
+        FROM adm_document_requirements
+        WHERE adm_session_id = $1
+        ORDER BY reminder_order NULLS LAST, code;
+    `
+	rows, err := q.QueryContext(ctx, query, admSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query requirements: %w", err)
+	}
+	defer rows.Close()
+
+	var requirements []DocumentRequirement
+	for rows.Next() {
+		var req DocumentRequirement
+		if err := rows.Scan(
+			&req.ID,
+			&req.Code,
+			&req.Title,
+			&req.Description,
+			pq.Array(&req.AcceptedMimeTypes),
+			&req.MaxFileSizeBytes,
+			&req.ReminderOrder,
+			&req.IsMandatory,
+		); err != nil {
+			return nil, fmt.Errorf("scan requirement: %w", err)
+		}
+		requirements = append(requirements, req)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate requirements: %w", err)
+	}
+	return requirements, nil
+}
+
+// GetRequirement returns one requirement of an ADM session, or sql.ErrNoRows.
+func (s *CategoryStore) GetRequirement(ctx context.Context, admSessionID, requirementID string) (DocumentRequirement, error) {
+	const query = `
+        SELECT id, code, title, description, accepted_mime_types, max_file_size_bytes, reminder_order, is_mandatory
+        FROM adm_document_requirements
+        WHERE id = $1 AND adm_session_id = $2;
+    `
+	var req DocumentRequirement
+	err := s.db.QueryRowContext(ctx, query, requirementID, admSessionID).Scan(
+		&req.ID,
+		&req.Code,
+		&req.Title,
+		&req.Description,
+		pq.Array(&req.AcceptedMimeTypes),
+		&req.MaxFileSizeBytes,
+		&req.ReminderOrder,
+		&req.IsMandatory,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return DocumentRequirement{}, err
+		}
+		return DocumentRequirement{}, fmt.Errorf("query requirement: %w", err)
+	}
+	return req, nil
+}
+
+// CreateRequirement inserts a requirement at the end of the reminder order and returns its ID.
+func (s *CategoryStore) CreateRequirement(ctx context.Context, edit ConfigEdit, params RequirementParams) (string, error) {
+	id, err := ids.New("adm_document_requirement")
+	if err != nil {
+		return "", fmt.Errorf("generate requirement id: %w", err)
+	}
+
+	err = s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		const insert = `
+            INSERT INTO adm_document_requirements (
+                id, adm_session_id, code, title, description, accepted_mime_types,
+                max_file_size_bytes, reminder_order, is_mandatory, created_at, updated_at
+            )
+            SELECT $1, $2, $3, $4, $5, $6, $7,
+                   COALESCE(MAX(reminder_order), 0) + 1, $8, NOW(), NOW()
+            FROM adm_document_requirements
+            WHERE adm_session_id = $2;
+        `
+		if _, err := tx.ExecContext(ctx, insert, id, edit.SessionID, params.Code, params.Title, params.Description, pq.Array(mimeTypes(params.AcceptedMimeTypes)), params.MaxFileSizeBytes, params.IsMandatory); err != nil {
+			if isUniqueViolation(err, "adm_document_requirements_code_session_uniq") {
+				return ErrCodeTaken
+			}
+			return fmt.Errorf("insert requirement: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// UpdateRequirement replaces the editable fields of a requirement. Making an optional
+// requirement mandatory re-syncs the student sessions of the categories it is linked
+// to, and the changes are returned; other edits return none.
+func (s *CategoryStore) UpdateRequirement(ctx context.Context, edit ConfigEdit, requirementID string, params RequirementParams) ([]RequirementChange, error) {
+	var changes []RequirementChange
+	err := s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		var wasMandatory bool
+		const current = `
+            SELECT is_mandatory FROM adm_document_requirements
+            WHERE id = $1 AND adm_session_id = $2
+            FOR UPDATE;
+        `
+		if err := tx.QueryRowContext(ctx, current, requirementID, edit.SessionID).Scan(&wasMandatory); err != nil {
+			if err == sql.ErrNoRows {
+				return err
+			}
+			return fmt.Errorf("lock requirement: %w", err)
+		}
+
+		const update = `
+            UPDATE adm_document_requirements
+            SET code = $3,
+                title = $4,
+                description = $5,
+                accepted_mime_types = $6,
+                max_file_size_bytes = $7,
+                is_mandatory = $8
+            WHERE id = $1 AND adm_session_id = $2;
+        `
+		res, err := tx.ExecContext(ctx, update, requirementID, edit.SessionID, params.Code, params.Title, params.Description, pq.Array(mimeTypes(params.AcceptedMimeTypes)), params.MaxFileSizeBytes, params.IsMandatory)
+		if err != nil {
+			if isUniqueViolation(err, "adm_document_requirements_code_session_uniq") {
+				return ErrCodeTaken
+			}
+			return fmt.Errorf("update requirement: %w", err)
+		}
+		if err := expectOneRow(res, "update requirement"); err != nil {
+			return err
+		}
+		if wasMandatory || !params.IsMandatory {
+			return nil
+		}
+
+		const linked = `
+            SELECT category_id FROM adm_category_requirements
+            WHERE document_requirement_id = $1
+            ORDER BY category_id;
+        `
+		categoryIDs, err := queryIDs(ctx, tx, linked, requirementID)
+		if err != nil {
+			return fmt.Errorf("query requirement categories: %w", err)
+		}
+		for _, categoryID := range categoryIDs {
+			change := RequirementChange{CategoryID: categoryID, MadeMandatory: []string{requirementID}}
+			if err := s.resyncCategory(ctx, tx, &change, edit.EditedBy); err != nil {
+				return err
+			}
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// DeleteRequirement removes a requirement nobody uploaded for, together with its
+// category links, and returns the change for each category it was linked to; a
+// removal moves no student session. Deleting one with submissions would cascade to
+// student files.
+func (s *CategoryStore) DeleteRequirement(ctx context.Context, edit ConfigEdit, requirementID string) ([]RequirementChange, error) {
+	var changes []RequirementChange
+	err := s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		var used bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM adm_document_submissions WHERE document_requirement_id = $1)`, requirementID).Scan(&used); err != nil {
+			return fmt.Errorf("check requirement usage: %w", err)
+		}
+		if used {
+			return ErrInUse
+		}
+		const linked = `
+            SELECT category_id FROM adm_category_requirements
+            WHERE document_requirement_id = $1
+            ORDER BY category_id;
+        `
+		categoryIDs, err := queryIDs(ctx, tx, linked, requirementID)
+		if err != nil {
+			return fmt.Errorf("query requirement categories: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM adm_document_requirements WHERE id = $1 AND adm_session_id = $2`, requirementID, edit.SessionID)
+		if err != nil {
+			return fmt.Errorf("delete requirement: %w", err)
+		}
+		if err := expectOneRow(res, "delete requirement"); err != nil {
+			return err
+		}
+
+		for _, categoryID := range categoryIDs {
+			change := RequirementChange{CategoryID: categoryID, Removed: []string{requirementID}}
+			if err := s.resyncCategory(ctx, tx, &change, edit.EditedBy); err != nil {
+				return err
+			}
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// ReorderRequirements sets reminder_order to the position of each requirement in
+// requirementIDs, which must list every requirement of the session exactly once.
+func (s *CategoryStore) ReorderRequirements(ctx context.Context, edit ConfigEdit, requirementIDs []string) error {
+	seen := make(map[string]struct{}, len(requirementIDs))
+	for _, id := range requirementIDs {
+		if _, dup := seen[id]; dup {
+			return ErrIncompleteOrder
+		}
+		seen[id] = struct{}{}
+	}
+
+	return s.withConfigEdit(ctx, edit, func(tx *sql.Tx) error {
+		if err := checkRequirements(ctx, tx, edit.SessionID, requirementIDs); err != nil {
+			return err
+		}
+		var total int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM adm_document_requirements WHERE adm_session_id = $1`, edit.SessionID).Scan(&total); err != nil {
+			return fmt.Errorf("count requirements: %w", err)
+		}
+		if total != len(requirementIDs) {
+			return ErrIncompleteOrder
+		}
+
+		return setReminderOrder(ctx, tx, edit.SessionID, requirementIDs)
+	})
+}
+
+// setReminderOrder numbers requirementIDs from 1 in the order given.
+func setReminderOrder(ctx context.Context, tx *sql.Tx, admSessionID string, requirementIDs []string) error {
+	const reorder = `
+        UPDATE adm_document_requirements r
+        SET reminder_order = wanted.position
+        FROM UNNEST($2::text[]) WITH ORDINALITY AS wanted(id, position)
+        WHERE r.id = wanted.id AND r.adm_session_id = $1;
+    `
+	if _, err := tx.ExecContext(ctx, reorder, admSessionID, pq.Array(requirementIDs)); err != nil {
+		return fmt.Errorf("reorder requirements: %w", err)
+	}
+	return nil
+}
+
+// mimeTypes keeps accepted_mime_types NOT NULL when no restriction is given.
+func mimeTypes(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
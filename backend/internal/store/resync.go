@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/workflow"
+
+	"github.com/lib/pq"
+)
+
+// RequirementChange describes how the requirements of one category changed and which
+// student sessions of that category were sent back to waiting_for_documents.
+type RequirementChange struct {
+	CategoryID string
+	Added      []string
+	Removed    []string
+	// MadeMandatory lists linked requirements switched from optional to mandatory.
+	MadeMandatory []string
+	// NewlyMandatory is filled by the re-sync: the mandatory requirements among Added
+	// and MadeMandatory, which students now have to provide. Only these move students.
+	NewlyMandatory  []string
+	StudentSessions []ResyncedStudentSession
+}
+
+// Empty reports whether the category's requirements are unchanged.
+func (c RequirementChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.MadeMandatory) == 0
+}
+
+// ResyncedStudentSession is a student session moved by a requirement change.
+type ResyncedStudentSession struct {
+	ID           string
+	StudentLogin string
+	From         StudentSessionStatus
+	To           StudentSessionStatus
+	// Revision is the revision opened for the student.
+	Revision int
+}
+
+// resyncStep is a student session to move, with the transition to apply.
+type resyncStep struct {
+	session ResyncedStudentSession
+	outcome workflow.Outcome
+	// fromRevision is the revision the session was at before the move.
+	fromRevision int
+}
+
+// planResync picks the student sessions a change moves. Nothing moves unless the
+// change makes new documents mandatory: optional additions ask nothing of students,
+// and removals leave what they already submitted or had validated complete. Not
+// started and invalidated sessions are skipped since their missing documents are
+// computed from the category when they submit.
+func planResync(change RequirementChange, sessions []ResyncedStudentSession) []resyncStep {
+	if len(change.NewlyMandatory) == 0 {
+		return nil
+	}
+	var steps []resyncStep
+	for _, session := range sessions {
+		outcome, err := workflow.Fire(session.From, workflow.ChangeRequirements, workflow.Input{})
+		if err != nil {
+			continue
+		}
+		step := resyncStep{session: session, outcome: outcome, fromRevision: session.Revision}
+		step.session.To = outcome.To
+		if outcome.Effects.NewRevision {
+			step.session.Revision++
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// resyncCategory fills change.NewlyMandatory and, when it is not empty, fires
+// ChangeRequirements on the student sessions of the category picked by planResync,
+// filling change.StudentSessions. Each moved session gets a new revision holding a
+// copy of its current submissions, decisions included, except those of removed
+// requirements.
+func (s *CategoryStore) resyncCategory(ctx context.Context, tx *sql.Tx, change *RequirementChange, actor string) error {
+	if change.Empty() {
+		return nil
+	}
+
+	const mandatory = `
+        SELECT id FROM adm_document_requirements
+        WHERE id = ANY($1::text[]) AND is_mandatory
+        ORDER BY id;
+    `
+	candidates := append(append([]string{}, change.Added...), change.MadeMandatory...)
+	newlyMandatory, err := queryIDs(ctx, tx, mandatory, pq.Array(candidates))
+	if err != nil {
+		return fmt.Errorf("query mandatory requirements: %w", err)
+	}
+	change.NewlyMandatory = newlyMandatory
+	if len(newlyMandatory) == 0 {
+		return nil
+	}
+
+	const query = `
+        SELECT id, student_login, status, current_revision
+        FROM adm_student_sessions
+        WHERE category_id = $1 AND archived_at IS NULL
+        ORDER BY student_login
+        FOR UPDATE;
+    `
+	rows, err := tx.QueryContext(ctx, query, change.CategoryID)
+	if err != nil {
+		return fmt.Errorf("lock category student sessions: %w", err)
+	}
+	var sessions []ResyncedStudentSession
+	for rows.Next() {
+		var session ResyncedStudentSession
+		if err := rows.Scan(&session.ID, &session.StudentLogin, &session.From, &session.Revision); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan student session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("iterate student sessions: %w", err)
+	}
+	rows.Close()
+
+	for _, step := range planResync(*change, sessions) {
+		if step.outcome.Effects.NewRevision {
+			if err := carrySubmissions(ctx, tx, step.session.ID, step.fromRevision, step.session.Revision, change.Removed); err != nil {
+				return err
+			}
+		}
+
+		payload := map[string]any{
+			"revision":                     step.fromRevision,
+			"new_revision":                 step.session.Revision,
+			"category_id":                  change.CategoryID,
+			"added_requirements":           nonNil(change.Added),
+			"removed_requirements":         nonNil(change.Removed),
+			"newly_mandatory_requirements": nonNil(change.NewlyMandatory),
+		}
+		if err := s.students.applyTransition(ctx, tx, step.session.ID, step.outcome, payload, actor); err != nil {
+			return err
+		}
+		change.StudentSessions = append(change.StudentSessions, step.session)
+	}
+	return nil
+}
+
+// submissionRef is a submission of the revision being carried over.
+type submissionRef struct {
+	ID            string
+	RequirementID string
+}
+
+// carriedSubmissions returns the IDs of the submissions to copy into the new
+// revision: every one except those of the dropped requirements.
+func carriedSubmissions(submissions []submissionRef, dropped []string) []string {
+	skip := make(map[string]bool, len(dropped))
+	for _, id := range dropped {
+		skip[id] = true
+	}
+	var carried []string
+	for _, submission := range submissions {
+		if !skip[submission.RequirementID] {
+			carried = append(carried, submission.ID)
+		}
+	}
+	return carried
+}
+
+// carrySubmissions copies the submissions of fromRevision into toRevision unchanged,
+// skipping the requirements listed in dropped. Accepted documents stay accepted, so
+// the student only has to upload what was added. Copies share the storage key of their
+// source row; replacing one in SaveUpload leaves that file to the earlier revision.
+func carrySubmissions(ctx context.Context, tx *sql.Tx, studentSessionID string, fromRevision, toRevision int, dropped []string) error {
+	const query = `
+        SELECT id, document_requirement_id FROM adm_document_submissions
+        WHERE student_session_id = $1 AND revision_number = $2
+        ORDER BY document_requirement_id;
+    `
+	rows, err := tx.QueryContext(ctx, query, studentSessionID, fromRevision)
+	if err != nil {
+		return fmt.Errorf("query submissions: %w", err)
+	}
+	var submissions []submissionRef
+	for rows.Next() {
+		var submission submissionRef
+		if err := rows.Scan(&submission.ID, &submission.RequirementID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan submission: %w", err)
+		}
+		submissions = append(submissions, submission)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("iterate submissions: %w", err)
+	}
+	rows.Close()
+
+	const copySubmission = `
+        INSERT INTO adm_document_submissions (
+            id, student_session_id, document_requirement_id, revision_number, status,
+            storage_key, file_name, file_size_bytes, checksum_sha256, uploaded_at,
+            uploaded_by_login, decision_by_login, decision_at, admin_comment,
+            file_deleted_at, created_at, updated_at
+        )
+        SELECT $2, student_session_id, document_requirement_id, $3, status,
+               storage_key, file_name, file_size_bytes, checksum_sha256, uploaded_at,
+               uploaded_by_login, decision_by_login, decision_at, admin_comment,
+               file_deleted_at, NOW(), NOW()
+        FROM adm_document_submissions
+        WHERE id = $1;
+    `
+	for _, sourceID := range carriedSubmissions(submissions, dropped) {
+		newID, err := ids.New("adm_document_submission")
+		if err != nil {
+			return fmt.Errorf("generate submission id: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, copySubmission, sourceID, newID, toRevision); err != nil {
+			return fmt.Errorf("copy submission %s: %w", sourceID, err)
+		}
+	}
+	return nil
+}
+
+// nonNil keeps empty ID lists encoded as [] rather than null.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"adm-backend/internal/workflow"
+)
+
+func TestPlanResyncMovesReviewableStatusesOnly(t *testing.T) {
+	sessions := []ResyncedStudentSession{
+		{ID: "not_started", From: workflow.NotStarted, Revision: 1},
+		{ID: "documents", From: workflow.WaitingForDocuments, Revision: 1},
+		{ID: "validation", From: workflow.WaitingForValidation, Revision: 2},
+		{ID: "validated", From: workflow.Validated, Revision: 3},
+		{ID: "invalidated", From: workflow.Invalidated, Revision: 2},
+	}
+	change := RequirementChange{CategoryID: "c1", Added: []string{"r_new"}, NewlyMandatory: []string{"r_new"}}
+
+	steps := planResync(change, sessions)
+	want := []ResyncedStudentSession{
+		{ID: "documents", From: workflow.WaitingForDocuments, To: workflow.WaitingForDocuments, Revision: 2},
+		{ID: "validation", From: workflow.WaitingForValidation, To: workflow.WaitingForDocuments, Revision: 3},
+		{ID: "validated", From: workflow.Validated, To: workflow.WaitingForDocuments, Revision: 4},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, want %d: %+v", len(steps), len(want), steps)
+	}
+	for i, step := range steps {
+		if step.session != want[i] {
+			t.Errorf("step %d = %+v, want %+v", i, step.session, want[i])
+		}
+		if step.fromRevision != want[i].Revision-1 {
+			t.Errorf("step %d: from revision %d, want %d", i, step.fromRevision, want[i].Revision-1)
+		}
+		if step.outcome.Event != workflow.ChangeRequirements || !step.outcome.Effects.NewRevision {
+			t.Errorf("step %d: outcome %+v does not open a revision", i, step.outcome)
+		}
+	}
+}
+
+func TestPlanResyncNeedsNewMandatoryRequirements(t *testing.T) {
+	sessions := []ResyncedStudentSession{
+		{ID: "documents", From: workflow.WaitingForDocuments, Revision: 1},
+		{ID: "validated", From: workflow.Validated, Revision: 2},
+	}
+	tests := map[string]RequirementChange{
+		"optional addition": {CategoryID: "c1", Added: []string{"r_optional"}},
+		"removal":           {CategoryID: "c1", Removed: []string{"r_old"}},
+		"made optional":     {CategoryID: "c1"},
+	}
+	for name, change := range tests {
+		if steps := planResync(change, sessions); len(steps) != 0 {
+			t.Errorf("%s: moved %d student sessions", name, len(steps))
+		}
+	}
+
+	madeMandatory := RequirementChange{CategoryID: "c1", MadeMandatory: []string{"r1"}, NewlyMandatory: []string{"r1"}}
+	if steps := planResync(madeMandatory, sessions); len(steps) != 2 {
+		t.Fatalf("made mandatory: moved %d student sessions, want 2", len(steps))
+	}
+}
+
+func TestRequirementChangeEmpty(t *testing.T) {
+	if !(RequirementChange{CategoryID: "c1"}).Empty() {
+		t.Errorf("change without requirements is not empty")
+	}
+	for name, change := range map[string]RequirementChange{
+		"added":          {Added: []string{"r1"}},
+		"removed":        {Removed: []string{"r1"}},
+		"made mandatory": {MadeMandatory: []string{"r1"}},
+	} {
+		if change.Empty() {
+			t.Errorf("%s: change is empty", name)
+		}
+	}
+}
+
+func TestCarriedSubmissionsExcludesDroppedRequirements(t *testing.T) {
+	submissions := []submissionRef{
+		{ID: "s1", RequirementID: "r_id_card"},
+		{ID: "s2", RequirementID: "r_contract"},
+		{ID: "s3", RequirementID: "r_photo"},
+		{ID: "s4", RequirementID: "r_contract"},
+	}
+	tests := []struct {
+		name    string
+		dropped []string
+		want    []string
+	}{
+		{"nothing dropped", nil, []string{"s1", "s2", "s3", "s4"}},
+		{"one requirement dropped", []string{"r_contract"}, []string{"s1", "s3"}},
+		{"unknown requirement dropped", []string{"r_other"}, []string{"s1", "s2", "s3", "s4"}},
+		{"everything dropped", []string{"r_id_card", "r_contract", "r_photo"}, nil},
+	}
+	for _, tt := range tests {
+		if got := carriedSubmissions(submissions, tt.dropped); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: carried %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResyncedSubmissionsKeepTheirFilesOnReupload(t *testing.T) {
+	m := &memDB{
+		mandatory: map[string]bool{"r_new": true},
+		sessions:  []memStudentSession{{id: "ss1", status: workflow.WaitingForValidation, lockedByStudent: true, revision: 1}},
+		submissions: []memSubmission{
+			{id: "sub_id", session: "ss1", requirement: "r_id_card", revision: 1, status: workflow.SubmissionUnderReview, key: "k_id_card"},
+			{id: "sub_contract", session: "ss1", requirement: "r_contract", revision: 1, status: workflow.SubmissionUnderReview, key: "k_contract"},
+		},
+	}
+	db := openMemDB(t, m)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	change := RequirementChange{CategoryID: "c1", Added: []string{"r_new"}}
+	if err := NewCategoryStore(db).resyncCategory(ctx, tx, &change, "staff"); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if session := m.session("ss1"); session.status != workflow.WaitingForDocuments || session.revision != 2 {
+		t.Fatalf("student session = %+v", session)
+	}
+	carried := m.revision("ss1", 2)
+	if carried["r_contract"].key != "k_contract" || carried["r_contract"].status != workflow.SubmissionUnderReview {
+		t.Fatalf("carried contract = %+v", carried["r_contract"])
+	}
+
+	students := NewStudentSessionStore(db)
+	upload := func(key string) {
+		t.Helper()
+		_, err := students.SaveUpload(ctx, SaveUploadParams{
+			SubmissionID:     "sub_" + key,
+			StudentSessionID: "ss1",
+			RequirementID:    "r_contract",
+			Revision:         2,
+			StorageKey:       key,
+			FileName:         "contract.pdf",
+			FileSizeBytes:    10,
+			UploadedBy:       "jdoe",
+		})
+		if err != nil {
+			t.Fatalf("upload %s: %v", key, err)
+		}
+	}
+
+	// The carried copy still shares revision 1's file, which must not be released.
+	upload("k_contract_2")
+	if queued := m.queued(); len(queued) != 0 {
+		t.Fatalf("replacing a carried submission queued %v", queued)
+	}
+	if sub := m.revision("ss1", 1)["r_contract"]; sub.key != "k_contract" {
+		t.Fatalf("revision 1 contract = %+v", sub)
+	}
+	if sub := m.revision("ss1", 2)["r_contract"]; sub.key != "k_contract_2" || sub.status != workflow.SubmissionUnderReview {
+		t.Fatalf("revision 2 contract = %+v", sub)
+	}
+
+	// A file only the replaced row pointed at goes to the cleanup worker.
+	upload("k_contract_3")
+	if want := []string{"k_contract_2"}; !reflect.DeepEqual(m.queued(), want) {
+		t.Fatalf("queued = %v, want %v", m.queued(), want)
+	}
+}
@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/workflow"
+)
+
+// ReviewDecision is an admin verdict on the submission made for one requirement.
+type ReviewDecision struct {
+	RequirementID string
+	Valid         bool
+	Reason        string
+}
+
+// ReviewResult summarises the outcome of a review.
+type ReviewResult struct {
+	Status      StudentSessionStatus
+	Revision    int
+	Validated   []string
+	Invalidated []string
+}
+
+// IncompleteReviewError is returned when decisions do not cover exactly the submissions under review.
+type IncompleteReviewError struct {
+	Undecided  []string
+	Unexpected []string
+}
+
+func (e *IncompleteReviewError) Error() string {
+	var parts []string
+	if len(e.Undecided) > 0 {
+		parts = append(parts, "undecided requirements: "+strings.Join(e.Undecided, ", "))
+	}
+	if len(e.Unexpected) > 0 {
+		parts = append(parts, "no submission under review for: "+strings.Join(e.Unexpected, ", "))
+	}
+	return "incomplete review (" + strings.Join(parts, "; ") + ")"
+}
+
+type reviewedSubmission struct {
+	id            string
+	requirementID string
+	status        SubmissionStatus
+}
+
+// ReviewSubmissions applies one decision per submission under review in the current revision.
+// When every submission is valid the student session becomes validated; otherwise it is
+// invalidated, a new revision is opened and valid submissions are carried into it unchanged.
+// Validation also queues every raw upload of the student session for deletion.
+func (s *StudentSessionStore) ReviewSubmissions(ctx context.Context, studentSessionID string, decisions []ReviewDecision, reviewer string) (ReviewResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ReviewResult{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockStudentSession(ctx, tx, studentSessionID)
+	if err != nil {
+		return ReviewResult{}, err
+	}
+	if locked.Status != workflow.WaitingForValidation {
+		return ReviewResult{}, &workflow.TransitionError{Machine: "student session", From: string(locked.Status), Event: workflow.ValidateSession}
+	}
+	revision := locked.CurrentRevision
+
+	submissions, err := lockRevisionSubmissions(ctx, tx, studentSessionID, revision)
+	if err != nil {
+		return ReviewResult{}, err
+	}
+
+	byRequirement := make(map[string]reviewedSubmission, len(submissions))
+	for _, sub := range submissions {
+		byRequirement[sub.requirementID] = sub
+	}
+
+	decided := make(map[string]ReviewDecision, len(decisions))
+	var incomplete IncompleteReviewError
+	for _, decision := range decisions {
+		sub, ok := byRequirement[decision.RequirementID]
+		if !ok {
+			incomplete.Unexpected = append(incomplete.Unexpected, decision.RequirementID)
+			continue
+		}
+		if sub.status != workflow.SubmissionUnderReview {
+			return ReviewResult{}, &workflow.TransitionError{Machine: "submission", From: string(sub.status), Event: workflow.MarkValid}
+		}
+		decided[decision.RequirementID] = decision
+	}
+	for _, sub := range submissions {
+		if sub.status != workflow.SubmissionUnderReview {
+			continue
+		}
+		if _, ok := decided[sub.requirementID]; !ok {
+			incomplete.Undecided = append(incomplete.Undecided, sub.requirementID)
+		}
+	}
+	if len(incomplete.Undecided) > 0 || len(incomplete.Unexpected) > 0 {
+		sort.Strings(incomplete.Undecided)
+		sort.Strings(incomplete.Unexpected)
+		return ReviewResult{}, &incomplete
+	}
+
+	result := ReviewResult{Revision: revision}
+	const decide = `
+        UPDATE adm_document_submissions
+        SET status = $2,
+            decision_by_login = $3,
+            decision_at = NOW(),
+            admin_comment = NULLIF($4, '')
+        WHERE id = $1;
+    `
+	for _, sub := range submissions {
+		decision, ok := decided[sub.requirementID]
+		if !ok {
+			continue
+		}
+		event, eventType := workflow.MarkValid, TimelineDocumentValidated
+		if !decision.Valid {
+			event, eventType = workflow.MarkInvalid, TimelineDocumentInvalidated
+		}
+		newStatus, err := workflow.FireSubmission(sub.status, event, workflow.Input{Reason: decision.Reason})
+		if err != nil {
+			return ReviewResult{}, err
+		}
+		if _, err := tx.ExecContext(ctx, decide, sub.id, newStatus, reviewer, decision.Reason); err != nil {
+			return ReviewResult{}, fmt.Errorf("record decision for %s: %w", sub.requirementID, err)
+		}
+
+		payload := map[string]any{
+			"submission_id":  sub.id,
+			"requirement_id": sub.requirementID,
+			"revision":       revision,
+		}
+		if decision.Reason != "" {
+			payload["reason"] = decision.Reason
+		}
+		entry := TimelineEntry{StudentSessionID: studentSessionID, Type: eventType, Payload: payload, CreatedBy: reviewer}
+		if err := s.timeline.Record(ctx, tx, entry); err != nil {
+			return ReviewResult{}, err
+		}
+
+		if decision.Valid {
+			result.Validated = append(result.Validated, sub.requirementID)
+		} else {
+			result.Invalidated = append(result.Invalidated, sub.requirementID)
+		}
+	}
+
+	event := workflow.ValidateSession
+	payload := map[string]any{"revision": revision}
+	input := workflow.Input{InvalidDocuments: len(result.Invalidated)}
+	if len(result.Invalidated) > 0 {
+		event = workflow.InvalidateSession
+		input.Reason = fmt.Sprintf("%d document(s) were rejected", len(result.Invalidated))
+		payload["new_revision"] = revision + 1
+		payload["invalid_requirements"] = result.Invalidated
+		payload["invalidation_reason"] = input.Reason
+	}
+	outcome, err := workflow.Fire(locked.Status, event, input)
+	if err != nil {
+		return ReviewResult{}, err
+	}
+	if err := s.applyTransition(ctx, tx, studentSessionID, outcome, payload, reviewer); err != nil {
+		return ReviewResult{}, err
+	}
+	result.Status = outcome.To
+	if outcome.Effects.NewRevision {
+		if err := carryValidSubmissions(ctx, tx, studentSessionID, revision, revision+1); err != nil {
+			return ReviewResult{}, err
+		}
+		result.Revision = revision + 1
+	}
+	if outcome.To == workflow.Validated {
+		// Raw uploads must not outlive validation; the cleanup worker deletes them.
+		if _, err := enqueueStudentSessionUploads(ctx, tx, studentSessionID); err != nil {
+			return ReviewResult{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ReviewResult{}, fmt.Errorf("commit review: %w", err)
+	}
+	return result, nil
+}
+
+func lockRevisionSubmissions(ctx context.Context, tx *sql.Tx, studentSessionID string, revision int) ([]reviewedSubmission, error) {
+	const query = `
+        SELECT id, document_requirement_id, status
+        FROM adm_document_submissions
+        WHERE student_session_id = $1 AND revision_number = $2
+        ORDER BY document_requirement_id
+        FOR UPDATE;
+    `
+	rows, err := tx.QueryContext(ctx, query, studentSessionID, revision)
+	if err != nil {
+		return nil, fmt.Errorf("query submissions: %w", err)
+	}
+	defer rows.Close()
+
+	var submissions []reviewedSubmission
+	for rows.Next() {
+		var sub reviewedSubmission
+		if err := rows.Scan(&sub.id, &sub.requirementID, &sub.status); err != nil {
+			return nil, fmt.Errorf("scan submission: %w", err)
+		}
+		submissions = append(submissions, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate submissions: %w", err)
+	}
+	return submissions, nil
+}
+
+// carryValidSubmissions copies the valid submissions of one revision into the next so
+// students only re-upload what was rejected. Source rows are left untouched.
+func carryValidSubmissions(ctx context.Context, tx *sql.Tx, studentSessionID string, fromRevision, toRevision int) error {
+	const query = `
+        SELECT id FROM adm_document_submissions
+        WHERE student_session_id = $1 AND revision_number = $2 AND status = 'valid';
+    `
+	rows, err := tx.QueryContext(ctx, query, studentSessionID, fromRevision)
+	if err != nil {
+		return fmt.Errorf("query valid submissions: %w", err)
+	}
+	var sourceIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan valid submission: %w", err)
+		}
+		sourceIDs = append(sourceIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("iterate valid submissions: %w", err)
+	}
+	rows.Close()
+
+	const copySubmission = `
+        INSERT INTO adm_document_submissions (
+            id, student_session_id, document_requirement_id, revision_number, status,
+            storage_key, file_name, file_size_bytes, checksum_sha256, uploaded_at,
+            uploaded_by_login, decision_by_login, decision_at, admin_comment,
+            created_at, updated_at
+        )
+        SELECT $2, student_session_id, document_requirement_id, $3, status,
+               storage_key, file_name, file_size_bytes, checksum_sha256, uploaded_at,
+               uploaded_by_login, decision_by_login, decision_at, admin_comment,
+               NOW(), NOW()
+        FROM adm_document_submissions
+        WHERE id = $1;
+    `
+	for _, sourceID := range sourceIDs {
+		newID, err := ids.New("adm_document_submission")
+		if err != nil {
+			return fmt.Errorf("generate submission id: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, copySubmission, sourceID, newID, toRevision); err != nil {
+			return fmt.Errorf("carry submission %s: %w", sourceID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"adm-backend/internal/workflow"
+
+	"github.com/lib/pq"
+)
+
+// Roster is the set of student logins enrolled in an ADM session.
+type Roster struct {
+	SessionID      string
+	Status         SessionStatus
+	RosterSyncedAt sql.NullTime
+	Configuration  json.RawMessage
+	Students       []RosterStudent
+}
+
+type RosterStudent struct {
+	Login    string
+	Archived bool
+}
+
+// RosterChanges is applied by ApplyRoster in a single transaction.
+type RosterChanges struct {
+	Add     []string
+	Restore []string
+	Archive []string
+}
+
+// RosterApplied reports what ApplyRoster actually changed; logins handled by a
+// concurrent sync are left out.
+type RosterApplied struct {
+	Added    []string
+	Restored []string
+	Archived []string
+}
+
+// GetRoster returns the enrolled students of an ADM session, archived ones included,
+// or sql.ErrNoRows.
+func (s *SessionStore) GetRoster(ctx context.Context, sessionID string) (Roster, error) {
+	var (
+		roster        = Roster{SessionID: sessionID}
+		configuration []byte
+	)
+	const session = `SELECT status, roster_synced_at, configuration FROM adm_sessions WHERE id = $1;`
+	if err := s.db.QueryRowContext(ctx, session, sessionID).Scan(&roster.Status, &roster.RosterSyncedAt, &configuration); err != nil {
+		if err == sql.ErrNoRows {
+			return Roster{}, err
+		}
+		return Roster{}, fmt.Errorf("get session: %w", err)
+	}
+	roster.Configuration = configuration
+
+	const query = `
+        SELECT student_login, archived_at IS NOT NULL
+        FROM adm_student_sessions
+        WHERE adm_session_id = $1
+        ORDER BY student_login;
+    `
+	rows, err := s.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return Roster{}, fmt.Errorf("query roster: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var student RosterStudent
+		if err := rows.Scan(&student.Login, &student.Archived); err != nil {
+			return Roster{}, fmt.Errorf("scan roster login: %w", err)
+		}
+		roster.Students = append(roster.Students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return Roster{}, fmt.Errorf("iterate roster: %w", err)
+	}
+	return roster, nil
+}
+
+// ApplyRoster creates a not_started student session for every login in changes.Add,
+// un-archives changes.Restore, archives changes.Archive and stamps roster_synced_at.
+// Student sessions are never deleted, and replaying the same changes is a no-op.
+func (s *SessionStore) ApplyRoster(ctx context.Context, sessionID string, changes RosterChanges) (RosterApplied, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RosterApplied{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialises concurrent syncs of the same session.
+	var status SessionStatus
+	const lock = `SELECT status FROM adm_sessions WHERE id = $1 FOR UPDATE;`
+	if err := tx.QueryRowContext(ctx, lock, sessionID).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return RosterApplied{}, err
+		}
+		return RosterApplied{}, fmt.Errorf("lock session: %w", err)
+	}
+	if status == workflow.SessionClosed {
+		return RosterApplied{}, &workflow.TransitionError{Machine: "ADM session", From: string(status), Event: "rebuild_roster"}
+	}
+
+	var applied RosterApplied
+	if len(changes.Add) > 0 {
+		const insertStudent = `
+            INSERT INTO adm_student_sessions (
+                id, adm_session_id, student_login, status, current_revision,
+                locked_by_student, locked_by_admin, created_at, updated_at
+            ) VALUES ($1,$2,$3,'not_started',1,false,false,NOW(),NOW())
+            ON CONFLICT (adm_session_id, student_login) DO NOTHING;
+        `
+		stmt, err := tx.PrepareContext(ctx, insertStudent)
+		if err != nil {
+			return RosterApplied{}, fmt.Errorf("prepare student insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, login := range changes.Add {
+			if login == "" {
+				continue
+			}
+			studentID, err := generateStudentSessionID()
+			if err != nil {
+				return RosterApplied{}, fmt.Errorf("generate student session id: %w", err)
+			}
+			res, err := stmt.ExecContext(ctx, studentID, sessionID, login)
+			if err != nil {
+				return RosterApplied{}, fmt.Errorf("insert student session for %s: %w", login, err)
+			}
+			if n, err := res.RowsAffected(); err == nil && n > 0 {
+				applied.Added = append(applied.Added, login)
+			}
+		}
+	}
+
+	const restore = `
+        UPDATE adm_student_sessions
+        SET archived_at = NULL
+        WHERE adm_session_id = $1 AND student_login = ANY($2) AND archived_at IS NOT NULL
+        RETURNING student_login;
+    `
+	if applied.Restored, err = updateRosterLogins(ctx, tx, restore, sessionID, changes.Restore); err != nil {
+		return RosterApplied{}, fmt.Errorf("restore students: %w", err)
+	}
+	const archive = `
+        UPDATE adm_student_sessions
+        SET archived_at = NOW()
+        WHERE adm_session_id = $1 AND student_login = ANY($2) AND archived_at IS NULL
+        RETURNING student_login;
+    `
+	if applied.Archived, err = updateRosterLogins(ctx, tx, archive, sessionID, changes.Archive); err != nil {
+		return RosterApplied{}, fmt.Errorf("archive students: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE adm_sessions SET roster_synced_at = NOW() WHERE id = $1`, sessionID); err != nil {
+		return RosterApplied{}, fmt.Errorf("stamp roster sync: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RosterApplied{}, fmt.Errorf("commit roster: %w", err)
+	}
+	return applied, nil
+}
+
+func updateRosterLogins(ctx context.Context, tx *sql.Tx, query, sessionID string, logins []string) ([]string, error) {
+	if len(logins) == 0 {
+		return nil, nil
+	}
+	rows, err := tx.QueryContext(ctx, query, sessionID, pq.Array(logins))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var updated []string
+	for rows.Next() {
+		var login string
+		if err := rows.Scan(&login); err != nil {
+			return nil, err
+		}
+		updated = append(updated, login)
+	}
+	return updated, rows.Err()
+}
+
+// ListUnsyncedActive returns the ids of active sessions whose roster was never built.
+func (s *SessionStore) ListUnsyncedActive(ctx context.Context) ([]string, error) {
+	const query = `
+        SELECT id FROM adm_sessions
+        WHERE status = 'active' AND roster_synced_at IS NULL
+        ORDER BY start_at;
+    `
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query unsynced sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessionIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan session id: %w", err)
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate unsynced sessions: %w", err)
+	}
+	return sessionIDs, nil
+}
@@ -3,13 +3,24 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"adm-backend/internal/ids"
+	"adm-backend/internal/workflow"
+
+	"github.com/lib/pq"
 )
 
-type SessionStatus string
+type SessionStatus = workflow.AdmSessionStatus
+
+// ErrLabelTaken is returned when another ADM session already uses the label.
+var ErrLabelTaken = errors.New("an ADM session with this label already exists")
+
+// ErrInvalidSchedule is returned when an update would leave end_at at or before start_at.
+var ErrInvalidSchedule = errors.New("end_at must be after start_at")
 
 type SessionSummary struct {
 	ID             string
@@ -21,6 +32,7 @@ type SessionSummary struct {
 	UpdatedAt      time.Time
 	StudentCount   int
 	ValidatedCount int
+	Configuration  json.RawMessage
 }
 
 type Session struct {
@@ -43,6 +55,7 @@ type CreateSessionParams struct {
 	Status         SessionStatus
 	CreatedByLogin string
 	PublishedAt    sql.NullTime
+	Configuration  json.RawMessage
 }
 
 type SessionStore struct {
@@ -64,9 +77,10 @@ func (s *SessionStore) ListSummaries(ctx context.Context) ([]SessionSummary, err
             s.created_at,
             s.updated_at,
             COALESCE(COUNT(ss.id), 0) AS student_count,
-            COALESCE(SUM(CASE WHEN ss.status = 'validated' THEN 1 ELSE 0 END), 0) AS validated_count
+            COALESCE(SUM(CASE WHEN ss.status = 'validated' THEN 1 ELSE 0 END), 0) AS validated_count,
+            s.configuration
         FROM adm_sessions s
-        LEFT JOIN adm_student_sessions ss ON ss.adm_session_id = s.id
+        LEFT JOIN adm_student_sessions ss ON ss.adm_session_id = s.id AND ss.archived_at IS NULL
         GROUP BY s.id
         ORDER BY s.start_at DESC;
     `
@@ -79,7 +93,10 @@ func (s *SessionStore) ListSummaries(ctx context.Context) ([]SessionSummary, err
 
 	var sessions []SessionSummary
 	for rows.Next() {
-		var summary SessionSummary
+		var (
+			summary       SessionSummary
+			configuration []byte
+		)
 		if err := rows.Scan(
 			&summary.ID,
 			&summary.Label,
@@ -90,9 +107,11 @@ func (s *SessionStore) ListSummaries(ctx context.Context) ([]SessionSummary, err
 			&summary.UpdatedAt,
 			&summary.StudentCount,
 			&summary.ValidatedCount,
+			&configuration,
 		); err != nil {
 			return nil, fmt.Errorf("scan session summary: %w", err)
 		}
+		summary.Configuration = configuration
 		sessions = append(sessions, summary)
 	}
 
@@ -103,21 +122,17 @@ func (s *SessionStore) ListSummaries(ctx context.Context) ([]SessionSummary, err
 	return sessions, nil
 }
 
-func (s *SessionStore) InsertSessionWithStudents(ctx context.Context, params CreateSessionParams, studentLogins []string) error {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
-	}
-	defer tx.Rollback()
-
+// InsertSession creates an ADM session without students; its roster is added when the
+// session activates (see AddStudents).
+func (s *SessionStore) InsertSession(ctx context.Context, params CreateSessionParams) error {
 	const insertSession = `
         INSERT INTO adm_sessions (
             id, label, start_at, end_at, status, configuration,
             created_by_login, published_at, created_at, updated_at
-        ) VALUES ($1,$2,$3,$4,$5,NULL,$6,$7,NOW(),NOW());
+        ) VALUES ($1,$2,$3,$4,$5,$8,$6,$7,NOW(),NOW());
     `
 
-	if _, err := tx.ExecContext(
+	if _, err := s.db.ExecContext(
 		ctx,
 		insertSession,
 		params.ID,
@@ -127,44 +142,231 @@ func (s *SessionStore) InsertSessionWithStudents(ctx context.Context, params Cre
 		params.Status,
 		params.CreatedByLogin,
 		params.PublishedAt,
+		nullJSON(params.Configuration),
 	); err != nil {
+		if isUniqueViolation(err, "adm_sessions_label_uniq") {
+			return ErrLabelTaken
+		}
 		return fmt.Errorf("insert session: %w", err)
 	}
+	return nil
+}
+
+// UpdateSessionParams holds the fields of a PATCH; nil fields are left unchanged.
+type UpdateSessionParams struct {
+	Label   *string
+	StartAt *time.Time
+	EndAt   *time.Time
+	Status  *SessionStatus
+	// Configuration is a JSON object whose top-level keys replace those stored; it
+	// bumps the configuration version.
+	Configuration json.RawMessage
+}
 
-	if len(studentLogins) > 0 {
-		const insertStudent = `
-            INSERT INTO adm_student_sessions (
-                id, adm_session_id, student_login, status, current_revision,
-                locked_by_student, locked_by_admin, created_at, updated_at
-            ) VALUES ($1,$2,$3,'not_started',1,false,false,NOW(),NOW());
-        `
+// UpdateSession applies params to an ADM session. Status changes go through
+// workflow.MoveSession, which stamps published_at or closed_at; closed sessions reject
+// every change. It returns sql.ErrNoRows for an unknown id, ErrInvalidSchedule and
+// ErrLabelTaken for rejected values.
+func (s *SessionStore) UpdateSession(ctx context.Context, id string, params UpdateSessionParams) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
 
-		stmt, err := tx.PrepareContext(ctx, insertStudent)
-		if err != nil {
-			return fmt.Errorf("prepare student insert: %w", err)
+	var current Session
+	const lock = `
+        SELECT label, start_at, end_at, status
+        FROM adm_sessions
+        WHERE id = $1
+        FOR UPDATE;
+    `
+	if err := tx.QueryRowContext(ctx, lock, id).Scan(&current.Label, &current.StartAt, &current.EndAt, &current.Status); err != nil {
+		if err == sql.ErrNoRows {
+			return err
 		}
-		defer stmt.Close()
-
-		for _, login := range studentLogins {
-			if login == "" {
-				continue
-			}
-			studentID, err := generateStudentSessionID()
-			if err != nil {
-				return fmt.Errorf("generate student session id: %w", err)
-			}
-			if _, err := stmt.ExecContext(ctx, studentID, params.ID, login); err != nil {
-				return fmt.Errorf("insert student session for %s: %w", login, err)
-			}
+		return fmt.Errorf("lock session: %w", err)
+	}
+	if current.Status == workflow.SessionClosed {
+		return &workflow.TransitionError{Machine: "ADM session", From: string(current.Status), Event: "update"}
+	}
+
+	next := current
+	if params.Label != nil {
+		next.Label = *params.Label
+	}
+	if params.StartAt != nil {
+		next.StartAt = *params.StartAt
+	}
+	if params.EndAt != nil {
+		next.EndAt = *params.EndAt
+	}
+	if params.Status != nil {
+		next.Status = *params.Status
+	}
+	if !next.EndAt.After(next.StartAt) {
+		return ErrInvalidSchedule
+	}
+	effects, err := workflow.MoveSession(current.Status, next.Status)
+	if err != nil {
+		return err
+	}
+
+	const update = `
+        UPDATE adm_sessions
+        SET label = $2,
+            start_at = $3,
+            end_at = $4,
+            status = $5,
+            published_at = CASE WHEN $6 THEN COALESCE(published_at, NOW()) ELSE published_at END,
+            closed_at = CASE WHEN $7 THEN NOW() ELSE closed_at END,
+            configuration = CASE
+                WHEN $8::jsonb IS NULL THEN configuration
+                ELSE COALESCE(configuration, '{}'::jsonb) || $8::jsonb || jsonb_build_object(
+                    'configuration_version',
+                    COALESCE((configuration->>'configuration_version')::int, 0) + 1
+                )
+            END
+        WHERE id = $1;
+    `
+	if _, err := tx.ExecContext(
+		ctx,
+		update,
+		id,
+		next.Label,
+		next.StartAt,
+		next.EndAt,
+		next.Status,
+		effects.StampPublished,
+		effects.StampClosed,
+		nullJSON(params.Configuration),
+	); err != nil {
+		if isUniqueViolation(err, "adm_sessions_label_uniq") {
+			return ErrLabelTaken
 		}
+		return fmt.Errorf("update session: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit session creation: %w", err)
+		return fmt.Errorf("commit session update: %w", err)
 	}
 	return nil
 }
 
+// GetSummary returns the summary of one ADM session, or sql.ErrNoRows.
+func (s *SessionStore) GetSummary(ctx context.Context, id string) (SessionSummary, error) {
+	const query = `
+        SELECT
+            s.id,
+            s.label,
+            s.start_at,
+            s.end_at,
+            s.status,
+            s.created_at,
+            s.updated_at,
+            COALESCE(COUNT(ss.id), 0) AS student_count,
+            COALESCE(SUM(CASE WHEN ss.status = 'validated' THEN 1 ELSE 0 END), 0) AS validated_count,
+            s.configuration
+        FROM adm_sessions s
+        LEFT JOIN adm_student_sessions ss ON ss.adm_session_id = s.id AND ss.archived_at IS NULL
+        WHERE s.id = $1
+        GROUP BY s.id;
+    `
+	var (
+		summary       SessionSummary
+		configuration []byte
+	)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&summary.ID,
+		&summary.Label,
+		&summary.StartAt,
+		&summary.EndAt,
+		&summary.Status,
+		&summary.CreatedAt,
+		&summary.UpdatedAt,
+		&summary.StudentCount,
+		&summary.ValidatedCount,
+		&configuration,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return SessionSummary{}, err
+		}
+		return SessionSummary{}, fmt.Errorf("get session summary: %w", err)
+	}
+	summary.Configuration = configuration
+	return summary, nil
+}
+
+// nullJSON maps an empty document to SQL NULL.
+func nullJSON(raw json.RawMessage) sql.NullString {
+	if len(raw) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(raw), Valid: true}
+}
+
+// isUniqueViolation reports whether err is a Postgres unique violation on constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
 func generateStudentSessionID() (string, error) {
 	return ids.New("adm_student_session")
 }
+
+// ActivateDue publishes the sessions whose start time has arrived and returns their
+// ids. Candidates are the statuses workflow.MoveSession lets become active, i.e.
+// drafts; published_at is stamped as MoveSession's effects require.
+func (s *SessionStore) ActivateDue(ctx context.Context) ([]string, error) {
+	const query = `
+        UPDATE adm_sessions
+        SET status = $1,
+            published_at = COALESCE(published_at, NOW())
+        WHERE status::text = ANY($2) AND start_at <= NOW() AND end_at > NOW()
+        RETURNING id;
+    `
+	return s.updateReturningIDs(ctx, query, "activate sessions", workflow.SessionActive)
+}
+
+// CloseEnded closes the sessions whose end time has passed and returns their ids.
+// Candidates are the statuses workflow.MoveSession lets close, i.e. drafts and active
+// sessions; closed_at is stamped as MoveSession's effects require.
+func (s *SessionStore) CloseEnded(ctx context.Context) ([]string, error) {
+	const query = `
+        UPDATE adm_sessions
+        SET status = $1,
+            closed_at = NOW()
+        WHERE status::text = ANY($2) AND end_at <= NOW()
+        RETURNING id;
+    `
+	return s.updateReturningIDs(ctx, query, "close sessions", workflow.SessionClosed)
+}
+
+// updateReturningIDs runs a bulk status change to `to` over the sessions workflow
+// allows to make it; query takes the target status as $1 and the sources as $2.
+func (s *SessionStore) updateReturningIDs(ctx context.Context, query, action string, to workflow.AdmSessionStatus) ([]string, error) {
+	var sources []string
+	for _, status := range workflow.SessionSources(to) {
+		sources = append(sources, string(status))
+	}
+	rows, err := s.db.QueryContext(ctx, query, to, pq.Array(sources))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", action, err)
+	}
+	defer rows.Close()
+
+	var sessionIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: scan id: %w", action, err)
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", action, err)
+	}
+	return sessionIDs, nil
+}
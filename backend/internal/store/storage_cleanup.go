@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CleanupItem is a storage object waiting in adm_storage_cleanup_queue.
+type CleanupItem struct {
+	ID               int64
+	StorageKey       string
+	StudentSessionID sql.NullString
+	Attempts         int
+	// InUse is set when a submission of a student session that is not validated still
+	// points at the object, e.g. after a reopen: the object must not be deleted.
+	InUse bool
+}
+
+type StorageCleanupStore struct {
+	db       *sql.DB
+	timeline *TimelineStore
+}
+
+func NewStorageCleanupStore(db *sql.DB) *StorageCleanupStore {
+	return &StorageCleanupStore{db: db, timeline: NewTimelineStore(db)}
+}
+
+// enqueueStudentSessionUploads schedules the deletion of every raw upload of a student
+// session, across all revisions. Keys shared by carried-over submissions are queued
+// once, and keys already waiting in the queue are skipped.
+func enqueueStudentSessionUploads(ctx context.Context, tx *sql.Tx, studentSessionID string) (int64, error) {
+	const insert = `
+        INSERT INTO adm_storage_cleanup_queue (storage_key, student_session_id, scheduled_for)
+        SELECT DISTINCT d.storage_key, d.student_session_id, NOW()
+        FROM adm_document_submissions d
+        WHERE d.student_session_id = $1
+          AND d.file_deleted_at IS NULL
+          AND NOT EXISTS (
+              SELECT 1 FROM adm_storage_cleanup_queue q
+              WHERE q.storage_key = d.storage_key AND q.processed_at IS NULL
+          );
+    `
+	res, err := tx.ExecContext(ctx, insert, studentSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue uploads for cleanup: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("enqueue uploads for cleanup: %w", err)
+	}
+	return n, nil
+}
+
+// cancelStudentSessionCleanup takes the uploads of a student session back out of the
+// queue before the worker deletes them.
+func cancelStudentSessionCleanup(ctx context.Context, tx *sql.Tx, studentSessionID string) error {
+	const cancel = `
+        DELETE FROM adm_storage_cleanup_queue q
+        WHERE q.processed_at IS NULL
+          AND q.storage_key IN (
+              SELECT d.storage_key FROM adm_document_submissions d
+              WHERE d.student_session_id = $1
+          );
+    `
+	if _, err := tx.ExecContext(ctx, cancel, studentSessionID); err != nil {
+		return fmt.Errorf("cancel upload cleanup: %w", err)
+	}
+	return nil
+}
+
+// releaseStorageKey queues the deletion of an object no submission points at anymore.
+// Nothing is queued while a submission still references key, or when it is already
+// waiting in the queue.
+func releaseStorageKey(ctx context.Context, tx *sql.Tx, key, studentSessionID string) error {
+	const insert = `
+        INSERT INTO adm_storage_cleanup_queue (storage_key, student_session_id, scheduled_for)
+        SELECT $1, $2, NOW()
+        WHERE NOT EXISTS (
+                  SELECT 1 FROM adm_document_submissions d WHERE d.storage_key = $1
+              )
+          AND NOT EXISTS (
+                  SELECT 1 FROM adm_storage_cleanup_queue q
+                  WHERE q.storage_key = $1 AND q.processed_at IS NULL
+              );
+    `
+	if _, err := tx.ExecContext(ctx, insert, key, studentSessionID); err != nil {
+		return fmt.Errorf("queue replaced upload for cleanup: %w", err)
+	}
+	return nil
+}
+
+// CleanupBatch holds row locks on claimed queue items until Commit or Rollback.
+type CleanupBatch struct {
+	tx       *sql.Tx
+	timeline *TimelineStore
+	Items    []CleanupItem
+}
+
+// Claim locks up to limit due items. Rows claimed by another worker are skipped, so
+// several replicas can drain the queue concurrently.
+func (s *StorageCleanupStore) Claim(ctx context.Context, limit int) (*CleanupBatch, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+
+	const query = `
+        SELECT q.id, q.storage_key, q.student_session_id, q.attempts,
+               EXISTS (
+                   SELECT 1
+                   FROM adm_document_submissions d
+                   JOIN adm_student_sessions ss ON ss.id = d.student_session_id
+                   WHERE d.storage_key = q.storage_key AND ss.status <> 'validated'
+               )
+        FROM adm_storage_cleanup_queue q
+        WHERE q.processed_at IS NULL AND q.scheduled_for <= NOW()
+        ORDER BY q.scheduled_for, q.id
+        LIMIT $1
+        FOR UPDATE OF q SKIP LOCKED;
+    `
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("claim cleanup items: %w", err)
+	}
+	defer rows.Close()
+
+	batch := &CleanupBatch{tx: tx, timeline: s.timeline}
+	for rows.Next() {
+		var item CleanupItem
+		if err := rows.Scan(&item.ID, &item.StorageKey, &item.StudentSessionID, &item.Attempts, &item.InUse); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("scan cleanup item: %w", err)
+		}
+		batch.Items = append(batch.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("iterate cleanup items: %w", err)
+	}
+	return batch, nil
+}
+
+// Complete marks item processed, flags the submissions that pointed at the object and
+// records a document_deleted event on the owning student session.
+func (b *CleanupBatch) Complete(ctx context.Context, item CleanupItem) error {
+	const done = `
+        UPDATE adm_storage_cleanup_queue
+        SET processed_at = NOW(),
+            attempts = attempts + 1,
+            failure_reason = NULL
+        WHERE id = $1;
+    `
+	if _, err := b.tx.ExecContext(ctx, done, item.ID); err != nil {
+		return fmt.Errorf("complete cleanup item %d: %w", item.ID, err)
+	}
+
+	const flag = `
+        UPDATE adm_document_submissions
+        SET file_deleted_at = NOW()
+        WHERE storage_key = $1 AND file_deleted_at IS NULL
+        RETURNING id, document_requirement_id;
+    `
+	rows, err := b.tx.QueryContext(ctx, flag, item.StorageKey)
+	if err != nil {
+		return fmt.Errorf("flag deleted submissions: %w", err)
+	}
+	var (
+		submissionIDs []string
+		requirementID string
+	)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id, &requirementID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan deleted submission: %w", err)
+		}
+		submissionIDs = append(submissionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("iterate deleted submissions: %w", err)
+	}
+	rows.Close()
+
+	if !item.StudentSessionID.Valid {
+		return nil
+	}
+	payload := map[string]any{
+		"storage_key":    item.StorageKey,
+		"submission_ids": submissionIDs,
+	}
+	if requirementID != "" {
+		payload["requirement_id"] = requirementID
+	}
+	return b.timeline.Record(ctx, b.tx, TimelineEntry{
+		StudentSessionID: item.StudentSessionID.String,
+		Type:             TimelineDocumentDeleted,
+		Payload:          payload,
+	})
+}
+
+// Skip closes item without deleting its object, which a submission still needs.
+func (b *CleanupBatch) Skip(ctx context.Context, item CleanupItem) error {
+	const update = `
+        UPDATE adm_storage_cleanup_queue
+        SET processed_at = NOW(),
+            failure_reason = $2
+        WHERE id = $1;
+    `
+	if _, err := b.tx.ExecContext(ctx, update, item.ID, "skipped: still referenced by a submission"); err != nil {
+		return fmt.Errorf("skip cleanup item %d: %w", item.ID, err)
+	}
+	return nil
+}
+
+// Fail records cause and reschedules item at retryAt. When giveUp is set the item is
+// closed instead, keeping failure_reason for operators.
+func (b *CleanupBatch) Fail(ctx context.Context, item CleanupItem, cause error, retryAt time.Time, giveUp bool) error {
+	const update = `
+        UPDATE adm_storage_cleanup_queue
+        SET attempts = attempts + 1,
+            failure_reason = $2,
+            scheduled_for = $3,
+            processed_at = CASE WHEN $4 THEN NOW() ELSE NULL END
+        WHERE id = $1;
+    `
+	if _, err := b.tx.ExecContext(ctx, update, item.ID, cause.Error(), retryAt, giveUp); err != nil {
+		return fmt.Errorf("record cleanup failure %d: %w", item.ID, err)
+	}
+	return nil
+}
+
+func (b *CleanupBatch) Commit() error {
+	if err := b.tx.Commit(); err != nil {
+		return fmt.Errorf("commit cleanup batch: %w", err)
+	}
+	return nil
+}
+
+func (b *CleanupBatch) Rollback() error {
+	return b.tx.Rollback()
+}
@@ -0,0 +1,301 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// QuestionnaireResponse is one revision's answers to the questionnaire.
+type QuestionnaireResponse struct {
+	ID                 string
+	RevisionNumber     int
+	Answers            json.RawMessage
+	CalculatedCategory sql.NullString
+	SubmittedAt        time.Time
+}
+
+// RequirementHistory is a document requirement with every submission made for it,
+// newest revision first. Required is false for requirements that are no longer
+// linked to the student's category but still have submissions.
+type RequirementHistory struct {
+	Requirement DocumentRequirement
+	Required    bool
+	Submissions []DocumentSubmission
+}
+
+type GeneratedDocument struct {
+	ID           string
+	DocumentType string
+	StorageKey   string
+	FileName     string
+	GeneratedBy  string
+	GeneratedAt  time.Time
+	// InvalidatedAt is set when the student session was reopened after generation.
+	InvalidatedAt sql.NullTime
+	InvalidatedBy sql.NullString
+}
+
+// StudentSessionDetail is the reviewer's view of a student's file.
+type StudentSessionDetail struct {
+	StudentSessionListItem
+	CategoryDescription sql.NullString
+	// Questionnaire holds the two latest questionnaire responses, newest first. Answers
+	// are only written when the questionnaire is completed, so their revision numbers
+	// usually lag behind the student session's current revision.
+	Questionnaire      []QuestionnaireResponse
+	Requirements       []RequirementHistory
+	GeneratedDocuments []GeneratedDocument
+}
+
+// GetDetail loads a student session with its recent questionnaire answers, every
+// submission per requirement and its generated documents from one consistent
+// snapshot. It returns sql.ErrNoRows when the student session does not exist.
+func (s *StudentSessionStore) GetDetail(ctx context.Context, studentSessionID string) (*StudentSessionDetail, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	const sessionQuery = `
+        SELECT
+            ss.id,
+            ss.adm_session_id,
+            ss.student_login,
+            ss.category_id,
+            ss.status,
+            ss.current_revision,
+            ss.locked_by_student,
+            ss.locked_by_admin,
+            ss.last_questionnaire_at,
+            ss.last_submitted_at,
+            ss.last_reviewed_at,
+            ss.invalidation_reason,
+            ss.created_at,
+            ss.updated_at,
+            ss.archived_at,
+            s.label,
+            c.code,
+            c.label,
+            c.description
+        FROM adm_student_sessions ss
+        JOIN adm_sessions s ON s.id = ss.adm_session_id
+        LEFT JOIN adm_categories c ON c.id = ss.category_id
+        WHERE ss.id = $1;
+    `
+	var detail StudentSessionDetail
+	ss := &detail.StudentSession
+	err = tx.QueryRowContext(ctx, sessionQuery, studentSessionID).Scan(
+		&ss.ID,
+		&ss.AdmSessionID,
+		&ss.StudentLogin,
+		&ss.CategoryID,
+		&ss.Status,
+		&ss.CurrentRevision,
+		&ss.LockedByStudent,
+		&ss.LockedByAdmin,
+		&ss.LastQuestionnaireAt,
+		&ss.LastSubmittedAt,
+		&ss.LastReviewedAt,
+		&ss.InvalidationReason,
+		&ss.CreatedAt,
+		&ss.UpdatedAt,
+		&detail.ArchivedAt,
+		&detail.SessionLabel,
+		&detail.CategoryCode,
+		&detail.CategoryLabel,
+		&detail.CategoryDescription,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("query student session: %w", err)
+	}
+
+	if detail.Questionnaire, err = listQuestionnaireResponses(ctx, tx, ss.ID); err != nil {
+		return nil, err
+	}
+	if detail.Requirements, err = listRequirementHistory(ctx, tx, ss.ID, ss.AdmSessionID, ss.CategoryID); err != nil {
+		return nil, err
+	}
+	if detail.GeneratedDocuments, err = listGeneratedDocuments(ctx, tx, ss.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit student session detail: %w", err)
+	}
+	return &detail, nil
+}
+
+func listQuestionnaireResponses(ctx context.Context, tx *sql.Tx, studentSessionID string) ([]QuestionnaireResponse, error) {
+	const query = `
+        SELECT id, revision_number, answers, calculated_category, submitted_at
+        FROM adm_questionnaire_responses
+        WHERE student_session_id = $1
+        ORDER BY revision_number DESC
+        LIMIT 2;
+    `
+	rows, err := tx.QueryContext(ctx, query, studentSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query questionnaire responses: %w", err)
+	}
+	defer rows.Close()
+
+	var responses []QuestionnaireResponse
+	for rows.Next() {
+		var (
+			response QuestionnaireResponse
+			answers  []byte
+		)
+		if err := rows.Scan(&response.ID, &response.RevisionNumber, &answers, &response.CalculatedCategory, &response.SubmittedAt); err != nil {
+			return nil, fmt.Errorf("scan questionnaire response: %w", err)
+		}
+		response.Answers = answers
+		responses = append(responses, response)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate questionnaire responses: %w", err)
+	}
+	return responses, nil
+}
+
+// listRequirementHistory returns the requirements of the student's category plus any
+// other requirement of the ADM session the student uploaded for, in one query.
+func listRequirementHistory(ctx context.Context, tx *sql.Tx, studentSessionID, admSessionID string, categoryID sql.NullString) ([]RequirementHistory, error) {
+	const query = `
+        SELECT
+            r.id,
+            r.code,
+            r.title,
+            r.description,
+            r.accepted_mime_types,
+            r.max_file_size_bytes,
+            r.reminder_order,
+            r.is_mandatory,
+            cr.category_id IS NOT NULL,
+            d.id,
+            d.revision_number,
+            d.status,
+            d.storage_key,
+            d.file_name,
+            d.file_size_bytes,
+            d.checksum_sha256,
+            d.uploaded_at,
+            d.uploaded_by_login,
+            d.decision_by_login,
+            d.decision_at,
+            d.admin_comment,
+            d.file_deleted_at
+        FROM adm_document_requirements r
+        LEFT JOIN adm_category_requirements cr
+            ON cr.document_requirement_id = r.id
+           AND cr.category_id = $3
+        LEFT JOIN adm_document_submissions d
+            ON d.document_requirement_id = r.id
+           AND d.student_session_id = $1
+        WHERE r.adm_session_id = $2
+          AND (cr.category_id IS NOT NULL OR d.id IS NOT NULL)
+        ORDER BY r.reminder_order NULLS LAST, r.code, d.revision_number DESC;
+    `
+	rows, err := tx.QueryContext(ctx, query, studentSessionID, admSessionID, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("query requirement history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []RequirementHistory
+	for rows.Next() {
+		var (
+			req        DocumentRequirement
+			required   bool
+			subID      sql.NullString
+			subRev     sql.NullInt64
+			subStatus  sql.NullString
+			subKey     sql.NullString
+			subName    sql.NullString
+			subUpAt    sql.NullTime
+			subUpBy    sql.NullString
+			submission DocumentSubmission
+		)
+		if err := rows.Scan(
+			&req.ID,
+			&req.Code,
+			&req.Title,
+			&req.Description,
+			pq.Array(&req.AcceptedMimeTypes),
+			&req.MaxFileSizeBytes,
+			&req.ReminderOrder,
+			&req.IsMandatory,
+			&required,
+			&subID,
+			&subRev,
+			&subStatus,
+			&subKey,
+			&subName,
+			&submission.FileSizeBytes,
+			&submission.ChecksumSHA256,
+			&subUpAt,
+			&subUpBy,
+			&submission.DecisionBy,
+			&submission.DecisionAt,
+			&submission.AdminComment,
+			&submission.FileDeletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan requirement history: %w", err)
+		}
+
+		if n := len(history); n == 0 || history[n-1].Requirement.ID != req.ID {
+			history = append(history, RequirementHistory{Requirement: req, Required: required})
+		}
+		if subID.Valid {
+			submission.ID = subID.String
+			submission.RevisionNumber = int(subRev.Int64)
+			submission.Status = SubmissionStatus(subStatus.String)
+			submission.StorageKey = subKey.String
+			submission.FileName = subName.String
+			submission.UploadedAt = subUpAt.Time
+			submission.UploadedBy = subUpBy.String
+			last := &history[len(history)-1]
+			last.Submissions = append(last.Submissions, submission)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate requirement history: %w", err)
+	}
+	return history, nil
+}
+
+func listGeneratedDocuments(ctx context.Context, tx *sql.Tx, studentSessionID string) ([]GeneratedDocument, error) {
+	const query = `
+        SELECT id, document_type, storage_key, file_name, generated_by_login, generated_at,
+               invalidated_at, invalidated_by_login
+        FROM adm_generated_documents
+        WHERE student_session_id = $1
+        ORDER BY generated_at DESC, id;
+    `
+	rows, err := tx.QueryContext(ctx, query, studentSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query generated documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []GeneratedDocument
+	for rows.Next() {
+		var doc GeneratedDocument
+		if err := rows.Scan(&doc.ID, &doc.DocumentType, &doc.StorageKey, &doc.FileName, &doc.GeneratedBy, &doc.GeneratedAt, &doc.InvalidatedAt, &doc.InvalidatedBy); err != nil {
+			return nil, fmt.Errorf("scan generated document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate generated documents: %w", err)
+	}
+	return docs, nil
+}
@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// StudentSessionSort names a column the admin search can be ordered by.
+type StudentSessionSort string
+
+const (
+	SortByLogin       StudentSessionSort = "login"
+	SortBySubmittedAt StudentSessionSort = "submitted_at"
+	SortByReviewedAt  StudentSessionSort = "reviewed_at"
+	SortByUpdatedAt   StudentSessionSort = "updated_at"
+)
+
+// sortExpressions maps each sort to its SQL expression and the type of its cursor value.
+// Missing timestamps sort as -infinity so keyset comparisons never see NULL.
+var sortExpressions = map[StudentSessionSort]struct{ expr, cast string }{
+	SortByLogin:       {"ss.student_login", "text"},
+	SortBySubmittedAt: {"COALESCE(ss.last_submitted_at, '-infinity'::timestamptz)", "timestamptz"},
+	SortByReviewedAt:  {"COALESCE(ss.last_reviewed_at, '-infinity'::timestamptz)", "timestamptz"},
+	SortByUpdatedAt:   {"ss.updated_at", "timestamptz"},
+}
+
+// IsValid reports whether s is a supported sort.
+func (s StudentSessionSort) IsValid() bool {
+	_, ok := sortExpressions[s]
+	return ok
+}
+
+type SearchStudentSessionsParams struct {
+	AdmSessionID string
+	// LoginPrefix matches logins starting with the given text.
+	LoginPrefix string
+	Statuses    []StudentSessionStatus
+	CategoryID  string
+	// LockedByStudent and LockedByAdmin filter on the lock flags when set.
+	LockedByStudent *bool
+	LockedByAdmin   *bool
+	// Date ranges are inclusive of From and exclusive of To; zero bounds are open.
+	SubmittedFrom   time.Time
+	SubmittedTo     time.Time
+	ReviewedFrom    time.Time
+	ReviewedTo      time.Time
+	IncludeArchived bool
+
+	Sort       StudentSessionSort
+	Descending bool
+	Cursor     string
+	Limit      int
+}
+
+// StudentSessionListItem is one row of the admin search.
+type StudentSessionListItem struct {
+	StudentSession
+	SessionLabel  string
+	CategoryCode  sql.NullString
+	CategoryLabel sql.NullString
+	ArchivedAt    sql.NullTime
+}
+
+type StudentSessionPage struct {
+	Sessions []StudentSessionListItem
+	// NextCursor is empty on the last page.
+	NextCursor string
+	// StatusCounts counts every match of the filters other than Statuses, so
+	// dashboards can label one tab per status.
+	StatusCounts map[StudentSessionStatus]int
+}
+
+// Search lists student sessions across ADM sessions with keyset pagination.
+func (s *StudentSessionStore) Search(ctx context.Context, params SearchStudentSessionsParams) (StudentSessionPage, error) {
+	if params.Sort == "" {
+		params.Sort = SortByLogin
+	}
+	sort, ok := sortExpressions[params.Sort]
+	if !ok {
+		return StudentSessionPage{}, fmt.Errorf("unsupported sort %q", params.Sort)
+	}
+
+	var where searchConditions
+	where.add("ss.adm_session_id = $?", params.AdmSessionID, params.AdmSessionID != "")
+	where.add(`ss.student_login LIKE $? || '%'`, escapeLike(params.LoginPrefix), params.LoginPrefix != "")
+	where.add("ss.category_id = $?", params.CategoryID, params.CategoryID != "")
+	if params.LockedByStudent != nil {
+		where.add("ss.locked_by_student = $?", *params.LockedByStudent, true)
+	}
+	if params.LockedByAdmin != nil {
+		where.add("ss.locked_by_admin = $?", *params.LockedByAdmin, true)
+	}
+	where.add("ss.last_submitted_at >= $?", params.SubmittedFrom, !params.SubmittedFrom.IsZero())
+	where.add("ss.last_submitted_at < $?", params.SubmittedTo, !params.SubmittedTo.IsZero())
+	where.add("ss.last_reviewed_at >= $?", params.ReviewedFrom, !params.ReviewedFrom.IsZero())
+	where.add("ss.last_reviewed_at < $?", params.ReviewedTo, !params.ReviewedTo.IsZero())
+	if !params.IncludeArchived {
+		where.conds = append(where.conds, "ss.archived_at IS NULL")
+	}
+
+	counts, err := s.countByStatus(ctx, where)
+	if err != nil {
+		return StudentSessionPage{}, err
+	}
+
+	statuses := make([]string, 0, len(params.Statuses))
+	for _, status := range params.Statuses {
+		statuses = append(statuses, string(status))
+	}
+	where.add("ss.status::text = ANY($?)", pq.Array(statuses), len(statuses) > 0)
+
+	cmp, dir := ">", "ASC"
+	if params.Descending {
+		cmp, dir = "<", "DESC"
+	}
+	if params.Cursor != "" {
+		value, id, err := decodeSearchCursor(params.Cursor, params.Sort, params.Descending)
+		if err != nil {
+			return StudentSessionPage{}, err
+		}
+		valueArg := where.arg(value)
+		idArg := where.arg(id)
+		where.conds = append(where.conds, fmt.Sprintf("(%s, ss.id) %s ($%d::%s, $%d)", sort.expr, cmp, valueArg, sort.cast, idArg))
+	}
+	limitArg := where.arg(params.Limit + 1)
+
+	query := `
+        SELECT
+            ss.id,
+            ss.adm_session_id,
+            ss.student_login,
+            ss.category_id,
+            ss.status,
+            ss.current_revision,
+            ss.locked_by_student,
+            ss.locked_by_admin,
+            ss.last_questionnaire_at,
+            ss.last_submitted_at,
+            ss.last_reviewed_at,
+            ss.invalidation_reason,
+            ss.created_at,
+            ss.updated_at,
+            ss.archived_at,
+            s.label,
+            c.code,
+            c.label
+        FROM adm_student_sessions ss
+        JOIN adm_sessions s ON s.id = ss.adm_session_id
+        LEFT JOIN adm_categories c ON c.id = ss.category_id` +
+		where.clause() +
+		fmt.Sprintf("\n        ORDER BY %s %s, ss.id %s\n        LIMIT $%d;", sort.expr, dir, dir, limitArg)
+
+	rows, err := s.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return StudentSessionPage{}, fmt.Errorf("search student sessions: %w", err)
+	}
+	defer rows.Close()
+
+	page := StudentSessionPage{StatusCounts: counts}
+	for rows.Next() {
+		var item StudentSessionListItem
+		ss := &item.StudentSession
+		if err := rows.Scan(
+			&ss.ID,
+			&ss.AdmSessionID,
+			&ss.StudentLogin,
+			&ss.CategoryID,
+			&ss.Status,
+			&ss.CurrentRevision,
+			&ss.LockedByStudent,
+			&ss.LockedByAdmin,
+			&ss.LastQuestionnaireAt,
+			&ss.LastSubmittedAt,
+			&ss.LastReviewedAt,
+			&ss.InvalidationReason,
+			&ss.CreatedAt,
+			&ss.UpdatedAt,
+			&item.ArchivedAt,
+			&item.SessionLabel,
+			&item.CategoryCode,
+			&item.CategoryLabel,
+		); err != nil {
+			return StudentSessionPage{}, fmt.Errorf("scan student session: %w", err)
+		}
+		page.Sessions = append(page.Sessions, item)
+	}
+	if err := rows.Err(); err != nil {
+		return StudentSessionPage{}, fmt.Errorf("iterate student sessions: %w", err)
+	}
+
+	if len(page.Sessions) > params.Limit {
+		page.Sessions = page.Sessions[:params.Limit]
+		last := page.Sessions[len(page.Sessions)-1]
+		page.NextCursor = encodeSearchCursor(params.Sort, params.Descending, sortValue(last, params.Sort), last.ID)
+	}
+	return page, nil
+}
+
+func (s *StudentSessionStore) countByStatus(ctx context.Context, where searchConditions) (map[StudentSessionStatus]int, error) {
+	query := `
+        SELECT ss.status, COUNT(*)
+        FROM adm_student_sessions ss` +
+		where.clause() + `
+        GROUP BY ss.status;`
+
+	rows, err := s.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("count student sessions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[StudentSessionStatus]int)
+	for rows.Next() {
+		var (
+			status StudentSessionStatus
+			count  int
+		)
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scan status count: %w", err)
+		}
+		counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate status counts: %w", err)
+	}
+	return counts, nil
+}
+
+// searchConditions accumulates AND-ed predicates; "$?" in a condition is replaced
+// by the placeholder of its argument.
+type searchConditions struct {
+	conds []string
+	args  []any
+}
+
+func (w *searchConditions) add(cond string, value any, enabled bool) {
+	if !enabled {
+		return
+	}
+	n := w.arg(value)
+	w.conds = append(w.conds, strings.Replace(cond, "$?", "$"+strconv.Itoa(n), 1))
+}
+
+func (w *searchConditions) arg(value any) int {
+	w.args = append(w.args, value)
+	return len(w.args)
+}
+
+func (w *searchConditions) clause() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return "\n        WHERE " + strings.Join(w.conds, "\n          AND ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func sortValue(item StudentSessionListItem, sort StudentSessionSort) string {
+	formatTime := func(t sql.NullTime) string {
+		if !t.Valid {
+			return "-infinity"
+		}
+		return t.Time.UTC().Format(time.RFC3339Nano)
+	}
+	switch sort {
+	case SortBySubmittedAt:
+		return formatTime(item.LastSubmittedAt)
+	case SortByReviewedAt:
+		return formatTime(item.LastReviewedAt)
+	case SortByUpdatedAt:
+		return formatTime(sql.NullTime{Time: item.UpdatedAt, Valid: true})
+	default:
+		return item.StudentLogin
+	}
+}
+
+// Search cursors embed the sort so a cursor cannot be replayed against another ordering.
+func encodeSearchCursor(sort StudentSessionSort, desc bool, value, id string) string {
+	direction := "asc"
+	if desc {
+		direction = "desc"
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(string(sort) + "|" + direction + "|" + id + "|" + value))
+}
+
+func decodeSearchCursor(cursor string, sort StudentSessionSort, desc bool) (string, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 4)
+	if len(parts) != 4 || parts[2] == "" {
+		return "", "", ErrInvalidCursor
+	}
+	direction := "asc"
+	if desc {
+		direction = "desc"
+	}
+	if parts[0] != string(sort) || parts[1] != direction {
+		return "", "", ErrInvalidCursor
+	}
+	if sortExpressions[sort].cast == "timestamptz" && parts[3] != "-infinity" {
+		if _, err := time.Parse(time.RFC3339Nano, parts[3]); err != nil {
+			return "", "", ErrInvalidCursor
+		}
+	}
+	return parts[3], parts[2], nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	cursor := encodeSearchCursor(SortBySubmittedAt, true, "2026-03-01T10:00:00.123456Z", "adm_student_session_01")
+
+	value, id, err := decodeSearchCursor(cursor, SortBySubmittedAt, true)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if value != "2026-03-01T10:00:00.123456Z" || id != "adm_student_session_01" {
+		t.Fatalf("decoded (%q, %q)", value, id)
+	}
+}
+
+func TestSearchCursorRejectsOtherOrdering(t *testing.T) {
+	cursor := encodeSearchCursor(SortByLogin, false, "a|b", "adm_student_session_01")
+
+	if value, _, err := decodeSearchCursor(cursor, SortByLogin, false); err != nil || value != "a|b" {
+		t.Fatalf("same ordering: value %q, err %v", value, err)
+	}
+	for _, tc := range []struct {
+		sort StudentSessionSort
+		desc bool
+	}{{SortByLogin, true}, {SortByUpdatedAt, false}} {
+		if _, _, err := decodeSearchCursor(cursor, tc.sort, tc.desc); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s desc=%v: err = %v, want ErrInvalidCursor", tc.sort, tc.desc, err)
+		}
+	}
+	if _, _, err := decodeSearchCursor("not base64!", SortByLogin, false); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("garbage cursor: err = %v", err)
+	}
+}
+
+func TestSearchConditionsNumbersPlaceholders(t *testing.T) {
+	var where searchConditions
+	where.add("a = $?", 1, true)
+	where.add("b = $?", 2, false)
+	where.add(`c LIKE $? || '%'`, escapeLike("x_1%"), true)
+
+	want := "\n        WHERE a = $1\n          AND c LIKE $2 || '%'"
+	if got := where.clause(); got != want {
+		t.Fatalf("clause = %q, want %q", got, want)
+	}
+	if len(where.args) != 2 || where.args[1] != `x\_1\%` {
+		t.Fatalf("args = %#v", where.args)
+	}
+}
@@ -0,0 +1,587 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/workflow"
+
+	"github.com/lib/pq"
+)
+
+// ErrStaleRevision is returned when the student session moved to another revision
+// between the caller's read and its update.
+var ErrStaleRevision = errors.New("student session revision changed")
+
+type StudentSessionStatus = workflow.StudentSessionStatus
+
+type SubmissionStatus = workflow.SubmissionStatus
+
+type StudentSession struct {
+	ID                  string
+	AdmSessionID        string
+	StudentLogin        string
+	CategoryID          sql.NullString
+	Status              StudentSessionStatus
+	CurrentRevision     int
+	LockedByStudent     bool
+	LockedByAdmin       bool
+	LastQuestionnaireAt sql.NullTime
+	LastSubmittedAt     sql.NullTime
+	LastReviewedAt      sql.NullTime
+	InvalidationReason  sql.NullString
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type DocumentSubmission struct {
+	ID             string
+	RevisionNumber int
+	Status         SubmissionStatus
+	StorageKey     string
+	FileName       string
+	FileSizeBytes  sql.NullInt64
+	ChecksumSHA256 sql.NullString
+	UploadedAt     time.Time
+	UploadedBy     string
+	DecisionBy     sql.NullString
+	DecisionAt     sql.NullTime
+	AdminComment   sql.NullString
+	// FileDeletedAt is set once the storage cleanup job deleted the file.
+	FileDeletedAt sql.NullTime
+}
+
+// DocumentSlot pairs a requirement with the submission made for the current revision, if any,
+// and the decided submission of the previous revision so rejection reasons stay visible.
+type DocumentSlot struct {
+	Requirement        DocumentRequirement
+	Submission         *DocumentSubmission
+	PreviousSubmission *DocumentSubmission
+}
+
+// CurrentStudentSession is the student's view of their file in the active ADM session.
+type CurrentStudentSession struct {
+	StudentSession
+	SessionLabel   string
+	SessionStartAt time.Time
+	SessionEndAt   time.Time
+	Category       *Category
+	Slots          []DocumentSlot
+}
+
+type SubmitQuestionnaireParams struct {
+	StudentSessionID string
+	Revision         int
+	Answers          json.RawMessage
+	CategoryID       string
+	SubmittedBy      string
+}
+
+type SaveUploadParams struct {
+	SubmissionID     string
+	StudentSessionID string
+	RequirementID    string
+	Revision         int
+	StorageKey       string
+	FileName         string
+	FileSizeBytes    int64
+	ChecksumSHA256   string
+	UploadedBy       string
+}
+
+type StudentSessionStore struct {
+	db       *sql.DB
+	timeline *TimelineStore
+}
+
+func NewStudentSessionStore(db *sql.DB) *StudentSessionStore {
+	return &StudentSessionStore{db: db, timeline: NewTimelineStore(db)}
+}
+
+// GetCurrentForLogin returns the student session of login inside the ADM session whose
+// window is currently open. Logins are compared case-insensitively, like roster syncs
+// do. It returns sql.ErrNoRows when the student has none.
+func (s *StudentSessionStore) GetCurrentForLogin(ctx context.Context, login string) (*CurrentStudentSession, error) {
+	const query = `
+        SELECT
+            ss.id,
+            ss.adm_session_id,
+            ss.student_login,
+            ss.category_id,
+            ss.status,
+            ss.current_revision,
+            ss.locked_by_student,
+            ss.locked_by_admin,
+            ss.last_questionnaire_at,
+            ss.last_submitted_at,
+            ss.last_reviewed_at,
+            ss.invalidation_reason,
+            ss.created_at,
+            ss.updated_at,
+            s.label,
+            s.start_at,
+            s.end_at,
+            c.code,
+            c.label,
+            c.description
+        FROM adm_student_sessions ss
+        JOIN adm_sessions s ON s.id = ss.adm_session_id
+        LEFT JOIN adm_categories c ON c.id = ss.category_id
+        WHERE lower(ss.student_login) = lower($1)
+          AND ss.archived_at IS NULL
+          AND s.status = 'active'
+          AND s.start_at <= NOW()
+          AND s.end_at > NOW()
+        ORDER BY s.start_at DESC
+        LIMIT 1;
+    `
+
+	var (
+		current  CurrentStudentSession
+		catCode  sql.NullString
+		catLabel sql.NullString
+		catDesc  sql.NullString
+	)
+	studentSess := &current.StudentSession
+	err := s.db.QueryRowContext(ctx, query, login).Scan(
+		&studentSess.ID,
+		&studentSess.AdmSessionID,
+		&studentSess.StudentLogin,
+		&studentSess.CategoryID,
+		&studentSess.Status,
+		&studentSess.CurrentRevision,
+		&studentSess.LockedByStudent,
+		&studentSess.LockedByAdmin,
+		&studentSess.LastQuestionnaireAt,
+		&studentSess.LastSubmittedAt,
+		&studentSess.LastReviewedAt,
+		&studentSess.InvalidationReason,
+		&studentSess.CreatedAt,
+		&studentSess.UpdatedAt,
+		&current.SessionLabel,
+		&current.SessionStartAt,
+		&current.SessionEndAt,
+		&catCode,
+		&catLabel,
+		&catDesc,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("query current student session: %w", err)
+	}
+
+	if current.CategoryID.Valid {
+		current.Category = &Category{
+			ID:          current.CategoryID.String,
+			Code:        catCode.String,
+			Label:       catLabel.String,
+			Description: catDesc,
+		}
+
+		slots, err := s.listSlots(ctx, current.ID, current.CategoryID.String, current.CurrentRevision)
+		if err != nil {
+			return nil, err
+		}
+		current.Slots = slots
+	}
+
+	return &current, nil
+}
+
+func (s *StudentSessionStore) listSlots(ctx context.Context, studentSessionID, categoryID string, revision int) ([]DocumentSlot, error) {
+	const query = `
+        SELECT
+            r.id,
+            r.code,
+            r.title,
+            r.description,
+            r.accepted_mime_types,
+            r.max_file_size_bytes,
+            r.reminder_order,
+            r.is_mandatory,
+            d.id,
+            d.revision_number,
+            d.status,
+            d.storage_key,
+            d.file_name,
+            d.file_size_bytes,
+            d.checksum_sha256,
+            d.uploaded_at,
+            d.uploaded_by_login,
+            d.decision_by_login,
+            d.decision_at,
+            d.admin_comment,
+            d.file_deleted_at,
+            p.id,
+            p.status,
+            p.file_name,
+            p.decision_at,
+            p.admin_comment
+        FROM adm_category_requirements cr
+        JOIN adm_document_requirements r ON r.id = cr.document_requirement_id
+        LEFT JOIN adm_document_submissions d
+            ON d.document_requirement_id = r.id
+           AND d.student_session_id = $2
+           AND d.revision_number = $3
+        LEFT JOIN adm_document_submissions p
+            ON p.document_requirement_id = r.id
+           AND p.student_session_id = $2
+           AND p.revision_number = $3 - 1
+           AND p.status IN ('valid', 'invalid')
+        WHERE cr.category_id = $1
+        ORDER BY r.reminder_order NULLS LAST, r.code;
+    `
+
+	rows, err := s.db.QueryContext(ctx, query, categoryID, studentSessionID, revision)
+	if err != nil {
+		return nil, fmt.Errorf("query document slots: %w", err)
+	}
+	defer rows.Close()
+
+	var slots []DocumentSlot
+	for rows.Next() {
+		var (
+			slot       DocumentSlot
+			subID      sql.NullString
+			subRev     sql.NullInt64
+			subStatus  sql.NullString
+			subKey     sql.NullString
+			subName    sql.NullString
+			subUpAt    sql.NullTime
+			subUpBy    sql.NullString
+			submission DocumentSubmission
+			prevID     sql.NullString
+			prevStatus sql.NullString
+			prevName   sql.NullString
+			previous   DocumentSubmission
+		)
+		req := &slot.Requirement
+		if err := rows.Scan(
+			&req.ID,
+			&req.Code,
+			&req.Title,
+			&req.Description,
+			pq.Array(&req.AcceptedMimeTypes),
+			&req.MaxFileSizeBytes,
+			&req.ReminderOrder,
+			&req.IsMandatory,
+			&subID,
+			&subRev,
+			&subStatus,
+			&subKey,
+			&subName,
+			&submission.FileSizeBytes,
+			&submission.ChecksumSHA256,
+			&subUpAt,
+			&subUpBy,
+			&submission.DecisionBy,
+			&submission.DecisionAt,
+			&submission.AdminComment,
+			&submission.FileDeletedAt,
+			&prevID,
+			&prevStatus,
+			&prevName,
+			&previous.DecisionAt,
+			&previous.AdminComment,
+		); err != nil {
+			return nil, fmt.Errorf("scan document slot: %w", err)
+		}
+
+		if subID.Valid {
+			submission.ID = subID.String
+			submission.RevisionNumber = int(subRev.Int64)
+			submission.Status = SubmissionStatus(subStatus.String)
+			submission.StorageKey = subKey.String
+			submission.FileName = subName.String
+			submission.UploadedAt = subUpAt.Time
+			submission.UploadedBy = subUpBy.String
+			slot.Submission = &submission
+		}
+		if prevID.Valid {
+			previous.ID = prevID.String
+			previous.RevisionNumber = revision - 1
+			previous.Status = SubmissionStatus(prevStatus.String)
+			previous.FileName = prevName.String
+			slot.PreviousSubmission = &previous
+		}
+		slots = append(slots, slot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate document slots: %w", err)
+	}
+
+	return slots, nil
+}
+
+// SubmitQuestionnaire stores the answers for the current revision, assigns the calculated
+// category and moves a not_started student session to waiting_for_documents.
+func (s *StudentSessionStore) SubmitQuestionnaire(ctx context.Context, params SubmitQuestionnaireParams) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockStudentSession(ctx, tx, params.StudentSessionID)
+	if err != nil {
+		return err
+	}
+	if locked.CurrentRevision != params.Revision {
+		return ErrStaleRevision
+	}
+	outcome, err := workflow.Fire(locked.Status, workflow.CompleteQuestionnaire, workflow.Input{})
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE adm_student_sessions SET category_id = $2 WHERE id = $1`, params.StudentSessionID, params.CategoryID); err != nil {
+		return fmt.Errorf("assign category: %w", err)
+	}
+
+	responseID, err := ids.New("adm_questionnaire_response")
+	if err != nil {
+		return fmt.Errorf("generate questionnaire response id: %w", err)
+	}
+
+	const insertResponse = `
+        INSERT INTO adm_questionnaire_responses (
+            id, student_session_id, revision_number, answers, calculated_category, submitted_at
+        ) VALUES ($1,$2,$3,$4,$5,NOW());
+    `
+	if _, err := tx.ExecContext(
+		ctx,
+		insertResponse,
+		responseID,
+		params.StudentSessionID,
+		params.Revision,
+		string(params.Answers),
+		params.CategoryID,
+	); err != nil {
+		return fmt.Errorf("insert questionnaire response: %w", err)
+	}
+
+	payload := map[string]any{
+		"revision":    params.Revision,
+		"category_id": params.CategoryID,
+	}
+	if err := s.applyTransition(ctx, tx, params.StudentSessionID, outcome, payload, params.SubmittedBy); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit questionnaire: %w", err)
+	}
+	return nil
+}
+
+// SaveUpload records an uploaded file for a requirement in the current revision. A file
+// already uploaded for that slot is replaced as long as no admin decided on it; the
+// replaced file is queued for the cleanup worker unless another submission still
+// points at it.
+func (s *StudentSessionStore) SaveUpload(ctx context.Context, params SaveUploadParams) (DocumentSubmission, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return DocumentSubmission{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockStudentSession(ctx, tx, params.StudentSessionID)
+	if err != nil {
+		return DocumentSubmission{}, err
+	}
+	if locked.CurrentRevision != params.Revision {
+		return DocumentSubmission{}, ErrStaleRevision
+	}
+	guardInput := workflow.Input{LockedByStudent: locked.LockedByStudent}
+	if _, err := workflow.Fire(locked.Status, workflow.UploadDocument, guardInput); err != nil {
+		return DocumentSubmission{}, err
+	}
+
+	var (
+		existingID     string
+		existingStatus SubmissionStatus
+		existingKey    string
+	)
+	const findExisting = `
+        SELECT id, status, storage_key
+        FROM adm_document_submissions
+        WHERE student_session_id = $1 AND document_requirement_id = $2 AND revision_number = $3
+        FOR UPDATE;
+    `
+	err = tx.QueryRowContext(ctx, findExisting, params.StudentSessionID, params.RequirementID, params.Revision).
+		Scan(&existingID, &existingStatus, &existingKey)
+	event := workflow.ReplaceFile
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		existingID, existingStatus, event = "", workflow.SubmissionPending, workflow.UploadFile
+	case err != nil:
+		return DocumentSubmission{}, fmt.Errorf("query existing submission: %w", err)
+	case existingStatus == workflow.SubmissionPending:
+		event = workflow.UploadFile
+	}
+	newStatus, err := workflow.FireSubmission(existingStatus, event, guardInput)
+	if err != nil {
+		return DocumentSubmission{}, err
+	}
+
+	submission := DocumentSubmission{
+		ID:             params.SubmissionID,
+		RevisionNumber: params.Revision,
+		Status:         newStatus,
+		StorageKey:     params.StorageKey,
+		FileName:       params.FileName,
+		FileSizeBytes:  sql.NullInt64{Int64: params.FileSizeBytes, Valid: true},
+		ChecksumSHA256: sql.NullString{String: params.ChecksumSHA256, Valid: true},
+		UploadedBy:     params.UploadedBy,
+	}
+
+	if existingID == "" {
+		const insert = `
+            INSERT INTO adm_document_submissions (
+                id, student_session_id, document_requirement_id, revision_number, status,
+                storage_key, file_name, file_size_bytes, checksum_sha256,
+                uploaded_at, uploaded_by_login, created_at, updated_at
+            ) VALUES ($1,$2,$3,$4,$10,$5,$6,$7,$8,NOW(),$9,NOW(),NOW())
+            RETURNING uploaded_at;
+        `
+		if err := tx.QueryRowContext(
+			ctx,
+			insert,
+			params.SubmissionID,
+			params.StudentSessionID,
+			params.RequirementID,
+			params.Revision,
+			params.StorageKey,
+			params.FileName,
+			params.FileSizeBytes,
+			params.ChecksumSHA256,
+			params.UploadedBy,
+			newStatus,
+		).Scan(&submission.UploadedAt); err != nil {
+			return DocumentSubmission{}, fmt.Errorf("insert submission: %w", err)
+		}
+	} else {
+		const update = `
+            UPDATE adm_document_submissions
+            SET status = $7,
+                storage_key = $2,
+                file_name = $3,
+                file_size_bytes = $4,
+                checksum_sha256 = $5,
+                uploaded_at = NOW(),
+                uploaded_by_login = $6,
+                decision_by_login = NULL,
+                decision_at = NULL,
+                admin_comment = NULL
+            WHERE id = $1
+            RETURNING uploaded_at;
+        `
+		if err := tx.QueryRowContext(
+			ctx,
+			update,
+			existingID,
+			params.StorageKey,
+			params.FileName,
+			params.FileSizeBytes,
+			params.ChecksumSHA256,
+			params.UploadedBy,
+			newStatus,
+		).Scan(&submission.UploadedAt); err != nil {
+			return DocumentSubmission{}, fmt.Errorf("replace submission: %w", err)
+		}
+		submission.ID = existingID
+
+		// Carried-over and reopened submissions share the key of the row they were
+		// copied from, whose file must survive the replacement.
+		if existingKey != params.StorageKey {
+			if err := releaseStorageKey(ctx, tx, existingKey, params.StudentSessionID); err != nil {
+				return DocumentSubmission{}, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return DocumentSubmission{}, fmt.Errorf("commit upload: %w", err)
+	}
+	return submission, nil
+}
+
+// SubmitForValidation locks the student session and hands it over to admins once every
+// mandatory requirement of its category has a submission in the current revision.
+// It returns a *workflow.MissingRequirementsError listing the requirement codes still missing.
+func (s *StudentSessionStore) SubmitForValidation(ctx context.Context, studentSessionID string, revision int, submittedBy string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockStudentSession(ctx, tx, studentSessionID)
+	if err != nil {
+		return err
+	}
+	if locked.CurrentRevision != revision {
+		return ErrStaleRevision
+	}
+	if !locked.CategoryID.Valid {
+		return &workflow.TransitionError{Machine: "student session", From: string(locked.Status), Event: workflow.SubmitDocuments}
+	}
+
+	const missingQuery = `
+        SELECT r.code
+        FROM adm_category_requirements cr
+        JOIN adm_document_requirements r ON r.id = cr.document_requirement_id
+        WHERE cr.category_id = $1
+          AND r.is_mandatory
+          AND NOT EXISTS (
+              SELECT 1 FROM adm_document_submissions d
+              WHERE d.student_session_id = $2
+                AND d.document_requirement_id = r.id
+                AND d.revision_number = $3
+                AND d.status IN ('under_review', 'valid')
+          )
+        ORDER BY r.reminder_order NULLS LAST, r.code;
+    `
+	rows, err := tx.QueryContext(ctx, missingQuery, locked.CategoryID.String, studentSessionID, revision)
+	if err != nil {
+		return fmt.Errorf("query missing requirements: %w", err)
+	}
+	var missing []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan missing requirement: %w", err)
+		}
+		missing = append(missing, code)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("iterate missing requirements: %w", err)
+	}
+	rows.Close()
+
+	outcome, err := workflow.Fire(locked.Status, workflow.SubmitDocuments, workflow.Input{
+		LockedByStudent:     locked.LockedByStudent,
+		MissingRequirements: missing,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.applyTransition(ctx, tx, studentSessionID, outcome, map[string]any{"revision": revision}, submittedBy); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit submission: %w", err)
+	}
+	return nil
+}
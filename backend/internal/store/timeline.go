@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"adm-backend/internal/ids"
+	"adm-backend/internal/workflow"
+
+	"github.com/lib/pq"
+)
+
+// TimelineEventType and its values are defined by the workflow package, whose
+// transitions emit most of them.
+type TimelineEventType = workflow.TimelineEventType
+
+const (
+	TimelineQuestionnaireStarted     = workflow.TimelineQuestionnaireStarted
+	TimelineQuestionnaireCompleted   = workflow.TimelineQuestionnaireCompleted
+	TimelineFilesSubmitted           = workflow.TimelineFilesSubmitted
+	TimelineAdminReviewStarted       = workflow.TimelineAdminReviewStarted
+	TimelineDocumentValidated        = workflow.TimelineDocumentValidated
+	TimelineDocumentInvalidated      = workflow.TimelineDocumentInvalidated
+	TimelineReviewReplied            = workflow.TimelineReviewReplied
+	TimelineSessionValidated         = workflow.TimelineSessionValidated
+	TimelineSessionInvalidated       = workflow.TimelineSessionInvalidated
+	TimelineSessionReopened          = workflow.TimelineSessionReopened
+	TimelineDeadlineExpired          = workflow.TimelineDeadlineExpired
+	TimelineDocumentDeleted          = workflow.TimelineDocumentDeleted
+	TimelineGeneratedDocumentCreated = workflow.TimelineGeneratedDocumentCreated
+	TimelineRequirementsChanged      = workflow.TimelineRequirementsChanged
+)
+
+// TimelineEventTypes lists every value of adm_timeline_event_type.
+var TimelineEventTypes = []TimelineEventType{
+	TimelineQuestionnaireStarted,
+	TimelineQuestionnaireCompleted,
+	TimelineFilesSubmitted,
+	TimelineAdminReviewStarted,
+	TimelineDocumentValidated,
+	TimelineDocumentInvalidated,
+	TimelineReviewReplied,
+	TimelineSessionValidated,
+	TimelineSessionInvalidated,
+	TimelineSessionReopened,
+	TimelineDeadlineExpired,
+	TimelineDocumentDeleted,
+	TimelineGeneratedDocumentCreated,
+	TimelineRequirementsChanged,
+}
+
+// ErrInvalidCursor is returned when a history cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TimelineEntry is what an operation appends to a student session's history.
+type TimelineEntry struct {
+	StudentSessionID string
+	Type             TimelineEventType
+	// Payload is encoded as JSON; nil stores SQL NULL.
+	Payload   any
+	CreatedBy string
+}
+
+type TimelineEvent struct {
+	ID               string
+	StudentSessionID string
+	Type             TimelineEventType
+	Payload          json.RawMessage
+	CreatedBy        sql.NullString
+	CreatedAt        time.Time
+}
+
+type ListTimelineParams struct {
+	StudentSessionID string
+	Types            []TimelineEventType
+	Cursor           string
+	Limit            int
+}
+
+type TimelinePage struct {
+	Events []TimelineEvent
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+type TimelineStore struct {
+	db *sql.DB
+}
+
+func NewTimelineStore(db *sql.DB) *TimelineStore {
+	return &TimelineStore{db: db}
+}
+
+// Record appends entry inside the caller's transaction. Timeline rows are never
+// updated or deleted by the application.
+func (s *TimelineStore) Record(ctx context.Context, tx *sql.Tx, entry TimelineEntry) error {
+	id, err := ids.New("adm_timeline_event")
+	if err != nil {
+		return fmt.Errorf("generate timeline event id: %w", err)
+	}
+
+	var rawPayload sql.NullString
+	if entry.Payload != nil {
+		encoded, err := json.Marshal(entry.Payload)
+		if err != nil {
+			return fmt.Errorf("encode timeline payload: %w", err)
+		}
+		rawPayload = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	const insert = `
+        INSERT INTO adm_timeline_events (
+            id, student_session_id, event_type, payload, created_by_login, created_at
+        ) VALUES ($1,$2,$3,$4,NULLIF($5,''),NOW());
+    `
+	if _, err := tx.ExecContext(ctx, insert, id, entry.StudentSessionID, entry.Type, rawPayload, entry.CreatedBy); err != nil {
+		return fmt.Errorf("insert %s timeline event: %w", entry.Type, err)
+	}
+	return nil
+}
+
+// List returns the history of a student session, newest first. It returns
+// sql.ErrNoRows when the student session does not exist.
+func (s *TimelineStore) List(ctx context.Context, params ListTimelineParams) (TimelinePage, error) {
+	var (
+		afterAt sql.NullTime
+		afterID sql.NullString
+	)
+	if params.Cursor != "" {
+		at, id, err := decodeTimelineCursor(params.Cursor)
+		if err != nil {
+			return TimelinePage{}, err
+		}
+		afterAt = sql.NullTime{Time: at, Valid: true}
+		afterID = sql.NullString{String: id, Valid: true}
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM adm_student_sessions WHERE id = $1)`, params.StudentSessionID).Scan(&exists); err != nil {
+		return TimelinePage{}, fmt.Errorf("check student session: %w", err)
+	}
+	if !exists {
+		return TimelinePage{}, sql.ErrNoRows
+	}
+
+	types := make([]string, 0, len(params.Types))
+	for _, t := range params.Types {
+		types = append(types, string(t))
+	}
+
+	const query = `
+        SELECT id, student_session_id, event_type, payload, created_by_login, created_at
+        FROM adm_timeline_events
+        WHERE student_session_id = $1
+          AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+          AND (cardinality($4::text[]) = 0 OR event_type::text = ANY($4))
+        ORDER BY created_at DESC, id DESC
+        LIMIT $5;
+    `
+	rows, err := s.db.QueryContext(ctx, query, params.StudentSessionID, afterAt, afterID, pq.Array(types), params.Limit+1)
+	if err != nil {
+		return TimelinePage{}, fmt.Errorf("query timeline: %w", err)
+	}
+	defer rows.Close()
+
+	var page TimelinePage
+	for rows.Next() {
+		var (
+			event   TimelineEvent
+			payload []byte
+		)
+		if err := rows.Scan(&event.ID, &event.StudentSessionID, &event.Type, &payload, &event.CreatedBy, &event.CreatedAt); err != nil {
+			return TimelinePage{}, fmt.Errorf("scan timeline event: %w", err)
+		}
+		if payload != nil {
+			event.Payload = json.RawMessage(payload)
+		}
+		page.Events = append(page.Events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return TimelinePage{}, fmt.Errorf("iterate timeline: %w", err)
+	}
+
+	if len(page.Events) > params.Limit {
+		page.Events = page.Events[:params.Limit]
+		last := page.Events[len(page.Events)-1]
+		page.NextCursor = encodeTimelineCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func encodeTimelineCursor(at time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+func decodeTimelineCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	stamp, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return at, id, nil
+}
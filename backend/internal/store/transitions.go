@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"adm-backend/internal/workflow"
+)
+
+// lockedStudentSession is the state read under FOR UPDATE before firing a transition.
+type lockedStudentSession struct {
+	ID              string
+	AdmSessionID    string
+	Status          StudentSessionStatus
+	LockedByStudent bool
+	CurrentRevision int
+	CategoryID      sql.NullString
+}
+
+func lockStudentSession(ctx context.Context, tx *sql.Tx, studentSessionID string) (lockedStudentSession, error) {
+	const query = `
+        SELECT id, adm_session_id, status, locked_by_student, current_revision, category_id
+        FROM adm_student_sessions
+        WHERE id = $1
+        FOR UPDATE;
+    `
+	var locked lockedStudentSession
+	err := tx.QueryRowContext(ctx, query, studentSessionID).Scan(
+		&locked.ID,
+		&locked.AdmSessionID,
+		&locked.Status,
+		&locked.LockedByStudent,
+		&locked.CurrentRevision,
+		&locked.CategoryID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return lockedStudentSession{}, err
+		}
+		return lockedStudentSession{}, fmt.Errorf("lock student session: %w", err)
+	}
+	return locked, nil
+}
+
+// applyTransition persists a workflow outcome on the student session row and records
+// its timeline event, both inside tx.
+func (s *StudentSessionStore) applyTransition(ctx context.Context, tx *sql.Tx, studentSessionID string, outcome workflow.Outcome, payload map[string]any, actor string) error {
+	effects := outcome.Effects
+	const update = `
+        UPDATE adm_student_sessions
+        SET status = $2,
+            locked_by_student = $3,
+            locked_by_admin = $4,
+            current_revision = current_revision + CASE WHEN $5 THEN 1 ELSE 0 END,
+            last_questionnaire_at = CASE WHEN $6 THEN NOW() ELSE last_questionnaire_at END,
+            last_submitted_at = CASE WHEN $7 THEN NOW() ELSE last_submitted_at END,
+            last_reviewed_at = CASE WHEN $8 THEN NOW() ELSE last_reviewed_at END,
+            invalidation_reason = CASE
+                WHEN $9 <> '' THEN $9
+                WHEN $10 THEN NULL
+                ELSE invalidation_reason
+            END
+        WHERE id = $1;
+    `
+	if _, err := tx.ExecContext(
+		ctx,
+		update,
+		studentSessionID,
+		outcome.To,
+		effects.LockedByStudent,
+		effects.LockedByAdmin,
+		effects.NewRevision,
+		effects.StampQuestionnaire,
+		effects.StampSubmitted,
+		effects.StampReviewed,
+		effects.InvalidationReason,
+		effects.ClearInvalidationReason,
+	); err != nil {
+		return fmt.Errorf("apply %s: %w", outcome.Event, err)
+	}
+
+	if effects.TimelineEvent == "" {
+		return nil
+	}
+	if payload == nil {
+		payload = make(map[string]any)
+	}
+	payload["from_status"] = outcome.From
+	payload["to_status"] = outcome.To
+	return s.timeline.Record(ctx, tx, TimelineEntry{
+		StudentSessionID: studentSessionID,
+		Type:             effects.TimelineEvent,
+		Payload:          payload,
+		CreatedBy:        actor,
+	})
+}
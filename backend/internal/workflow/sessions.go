@@ -0,0 +1,54 @@
+package workflow
+
+import "sort"
+
+// AdmSessionStatus is the lifecycle of an ADM session (adm_session_status).
+type AdmSessionStatus string
+
+const (
+	SessionDraft  AdmSessionStatus = "draft"
+	SessionActive AdmSessionStatus = "active"
+	SessionClosed AdmSessionStatus = "closed"
+)
+
+// SessionEffects are the timestamps a store stamps with an ADM session status change.
+type SessionEffects struct {
+	StampPublished bool
+	StampClosed    bool
+}
+
+var sessionEdges = map[AdmSessionStatus]map[AdmSessionStatus]SessionEffects{
+	SessionDraft: {
+		SessionActive: {StampPublished: true},
+		// A draft that never opened can be abandoned.
+		SessionClosed: {StampClosed: true},
+	},
+	SessionActive: {
+		SessionClosed: {StampClosed: true},
+	},
+}
+
+// SessionSources lists the statuses an ADM session may move to `to` from, sorted.
+func SessionSources(to AdmSessionStatus) []AdmSessionStatus {
+	var sources []AdmSessionStatus
+	for from, edges := range sessionEdges {
+		if _, ok := edges[to]; ok {
+			sources = append(sources, from)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i] < sources[j] })
+	return sources
+}
+
+// MoveSession validates an ADM session status change. Staying in the same status is
+// allowed except for closed sessions, which accept no change at all.
+func MoveSession(from, to AdmSessionStatus) (SessionEffects, error) {
+	if from == to && from != SessionClosed {
+		return SessionEffects{}, nil
+	}
+	effects, ok := sessionEdges[from][to]
+	if !ok {
+		return SessionEffects{}, &TransitionError{Machine: "ADM session", From: string(from), Event: Event("move_to_" + string(to))}
+	}
+	return effects, nil
+}
@@ -0,0 +1,330 @@
+// Package workflow encodes the student-session and document-submission state
+// machines described in docs/architecture.md. Stores ask it whether a move is
+// legal and which side effects to apply; they never change a status on their own.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type StudentSessionStatus string
+
+const (
+	NotStarted           StudentSessionStatus = "not_started"
+	WaitingForDocuments  StudentSessionStatus = "waiting_for_documents"
+	WaitingForValidation StudentSessionStatus = "waiting_for_validation"
+	Validated            StudentSessionStatus = "validated"
+	Invalidated          StudentSessionStatus = "invalidated"
+)
+
+type SubmissionStatus string
+
+const (
+	SubmissionPending     SubmissionStatus = "pending"
+	SubmissionUnderReview SubmissionStatus = "under_review"
+	SubmissionValid       SubmissionStatus = "valid"
+	SubmissionInvalid     SubmissionStatus = "invalid"
+)
+
+// Event names what triggers a transition.
+type Event string
+
+// Student session events.
+const (
+	CompleteQuestionnaire Event = "complete_questionnaire"
+	UploadDocument        Event = "upload_document"
+	SubmitDocuments       Event = "submit_documents"
+	ValidateSession       Event = "validate_session"
+	InvalidateSession     Event = "invalidate_session"
+	ReopenSession         Event = "reopen_session"
+	ExpireSession         Event = "expire_session"
+	ChangeRequirements    Event = "change_requirements"
+	GenerateDocuments     Event = "generate_documents"
+)
+
+// Submission events.
+const (
+	UploadFile     Event = "upload_file"
+	ReplaceFile    Event = "replace_file"
+	MarkValid      Event = "mark_valid"
+	MarkInvalid    Event = "mark_invalid"
+	ReopenDocument Event = "reopen_document"
+)
+
+// TimelineEventType is a value of adm_timeline_event_type. Transitions emit some of
+// them through Effects; the store records the others directly.
+type TimelineEventType string
+
+const (
+	TimelineQuestionnaireStarted     TimelineEventType = "questionnaire_started"
+	TimelineQuestionnaireCompleted   TimelineEventType = "questionnaire_completed"
+	TimelineFilesSubmitted           TimelineEventType = "files_submitted"
+	TimelineAdminReviewStarted       TimelineEventType = "admin_review_started"
+	TimelineDocumentValidated        TimelineEventType = "document_validated"
+	TimelineDocumentInvalidated      TimelineEventType = "document_invalidated"
+	TimelineReviewReplied            TimelineEventType = "review_replied"
+	TimelineSessionValidated         TimelineEventType = "session_validated"
+	TimelineSessionInvalidated       TimelineEventType = "session_invalidated"
+	TimelineSessionReopened          TimelineEventType = "session_reopened"
+	TimelineDeadlineExpired          TimelineEventType = "deadline_expired"
+	TimelineDocumentDeleted          TimelineEventType = "document_deleted"
+	TimelineGeneratedDocumentCreated TimelineEventType = "generated_document_created"
+	TimelineRequirementsChanged      TimelineEventType = "requirements_changed"
+)
+
+// ExpiredReason is stored as invalidation_reason when the ADM session ends first.
+const ExpiredReason = "The ADM session ended without validation"
+
+// ErrIllegalTransition matches every *TransitionError.
+var ErrIllegalTransition = errors.New("illegal state transition")
+
+// TransitionError reports a move the state machine does not allow from the current state.
+type TransitionError struct {
+	Machine string
+	From    string
+	Event   Event
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s cannot %s while %s", e.Machine, strings.ReplaceAll(string(e.Event), "_", " "), e.From)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+// GuardError reports a transition that exists but whose precondition does not hold.
+type GuardError struct {
+	Event  Event
+	Reason string
+}
+
+func (e *GuardError) Error() string {
+	return e.Reason
+}
+
+func (e *GuardError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+// MissingRequirementsError is the guard failure of SubmitDocuments.
+type MissingRequirementsError struct {
+	Codes []string
+}
+
+func (e *MissingRequirementsError) Error() string {
+	return "missing mandatory documents: " + strings.Join(e.Codes, ", ")
+}
+
+// Input carries the facts guards need to decide.
+type Input struct {
+	LockedByStudent     bool
+	MissingRequirements []string
+	InvalidDocuments    int
+	Reason              string
+}
+
+// Effects are the side effects a store applies together with the status change.
+type Effects struct {
+	LockedByStudent    bool
+	LockedByAdmin      bool
+	NewRevision        bool
+	StampQuestionnaire bool
+	StampSubmitted     bool
+	StampReviewed      bool
+	// InvalidationReason replaces invalidation_reason when non-empty.
+	InvalidationReason string
+	// ClearInvalidationReason resets invalidation_reason once the session is accepted again.
+	ClearInvalidationReason bool
+	// TimelineEvent is recorded in the same transaction when non-empty.
+	TimelineEvent TimelineEventType
+}
+
+// Outcome is the result of a legal student-session transition.
+type Outcome struct {
+	From    StudentSessionStatus
+	To      StudentSessionStatus
+	Event   Event
+	Effects Effects
+}
+
+type studentEdge struct {
+	from  StudentSessionStatus
+	event Event
+	to    StudentSessionStatus
+	guard func(Input) error
+	// effects may derive values from the input.
+	effects func(Input) Effects
+}
+
+var studentEdges = []studentEdge{
+	{
+		from: NotStarted, event: CompleteQuestionnaire, to: WaitingForDocuments,
+		effects: func(Input) Effects {
+			return Effects{StampQuestionnaire: true, TimelineEvent: TimelineQuestionnaireCompleted}
+		},
+	},
+	{from: WaitingForDocuments, event: UploadDocument, to: WaitingForDocuments, guard: notLockedByStudent, effects: keepUnlocked},
+	{from: Invalidated, event: UploadDocument, to: Invalidated, guard: notLockedByStudent, effects: keepUnlocked},
+	{from: WaitingForDocuments, event: SubmitDocuments, to: WaitingForValidation, guard: readyToSubmit, effects: submitted},
+	{from: Invalidated, event: SubmitDocuments, to: WaitingForValidation, guard: readyToSubmit, effects: submitted},
+	{
+		from: WaitingForValidation, event: ValidateSession, to: Validated,
+		guard: func(in Input) error {
+			if in.InvalidDocuments > 0 {
+				return &GuardError{Event: ValidateSession, Reason: "a session with invalid documents cannot be validated"}
+			}
+			return nil
+		},
+		effects: func(Input) Effects {
+			return Effects{StampReviewed: true, ClearInvalidationReason: true, TimelineEvent: TimelineSessionValidated}
+		},
+	},
+	{
+		from: WaitingForValidation, event: InvalidateSession, to: Invalidated,
+		guard: func(in Input) error {
+			if in.InvalidDocuments == 0 {
+				return &GuardError{Event: InvalidateSession, Reason: "invalidating a session requires at least one invalid document"}
+			}
+			return nil
+		},
+		effects: func(in Input) Effects {
+			return Effects{
+				LockedByAdmin:      true,
+				NewRevision:        true,
+				StampReviewed:      true,
+				InvalidationReason: in.Reason,
+				TimelineEvent:      TimelineSessionInvalidated,
+			}
+		},
+	},
+	{
+		from: Validated, event: ReopenSession, to: WaitingForDocuments,
+		guard: requireReason(ReopenSession),
+		effects: func(Input) Effects {
+			return Effects{NewRevision: true, ClearInvalidationReason: true, TimelineEvent: TimelineSessionReopened}
+		},
+	},
+	// Official documents are only issued for a validated file; one event per document.
+	{
+		from: Validated, event: GenerateDocuments, to: Validated,
+		effects: func(Input) Effects {
+			return Effects{TimelineEvent: TimelineGeneratedDocumentCreated}
+		},
+	},
+	// A new mandatory requirement on the category of a student who already uploaded
+	// or was reviewed opens a new revision so the missing document is asked for.
+	{from: WaitingForDocuments, event: ChangeRequirements, to: WaitingForDocuments, effects: requirementsChanged},
+	{from: WaitingForValidation, event: ChangeRequirements, to: WaitingForDocuments, effects: requirementsChanged},
+	{from: Validated, event: ChangeRequirements, to: WaitingForDocuments, effects: requirementsChanged},
+	{from: NotStarted, event: ExpireSession, to: Invalidated, effects: expired},
+	{from: WaitingForDocuments, event: ExpireSession, to: Invalidated, effects: expired},
+	{from: WaitingForValidation, event: ExpireSession, to: Invalidated, effects: expired},
+}
+
+func notLockedByStudent(in Input) error {
+	if in.LockedByStudent {
+		return &GuardError{Event: UploadDocument, Reason: "the session is locked until an administrator answers"}
+	}
+	return nil
+}
+
+func readyToSubmit(in Input) error {
+	if in.LockedByStudent {
+		return &GuardError{Event: SubmitDocuments, Reason: "the session was already submitted"}
+	}
+	if len(in.MissingRequirements) > 0 {
+		return &MissingRequirementsError{Codes: in.MissingRequirements}
+	}
+	return nil
+}
+
+func requireReason(event Event) func(Input) error {
+	return func(in Input) error {
+		if strings.TrimSpace(in.Reason) == "" {
+			return &GuardError{Event: event, Reason: "a reason is required"}
+		}
+		return nil
+	}
+}
+
+// keepUnlocked marks UploadDocument as a guard-only self loop: the session row is not rewritten.
+func keepUnlocked(Input) Effects { return Effects{} }
+
+func submitted(Input) Effects {
+	return Effects{LockedByStudent: true, StampSubmitted: true, TimelineEvent: TimelineFilesSubmitted}
+}
+
+func requirementsChanged(Input) Effects {
+	return Effects{NewRevision: true, ClearInvalidationReason: true, TimelineEvent: TimelineRequirementsChanged}
+}
+
+func expired(Input) Effects {
+	return Effects{
+		LockedByStudent:    true,
+		LockedByAdmin:      true,
+		InvalidationReason: ExpiredReason,
+		TimelineEvent:      TimelineDeadlineExpired,
+	}
+}
+
+// Fire checks that event is allowed from the current status and that its guard
+// holds, returning the target status and side effects.
+func Fire(from StudentSessionStatus, event Event, in Input) (Outcome, error) {
+	for _, edge := range studentEdges {
+		if edge.from != from || edge.event != event {
+			continue
+		}
+		if edge.guard != nil {
+			if err := edge.guard(in); err != nil {
+				return Outcome{}, err
+			}
+		}
+		return Outcome{From: from, To: edge.to, Event: event, Effects: edge.effects(in)}, nil
+	}
+	return Outcome{}, &TransitionError{Machine: "student session", From: string(from), Event: event}
+}
+
+// Sources lists the statuses from which event may fire, in declaration order.
+func Sources(event Event) []StudentSessionStatus {
+	var sources []StudentSessionStatus
+	for _, edge := range studentEdges {
+		if edge.event == event {
+			sources = append(sources, edge.from)
+		}
+	}
+	return sources
+}
+
+type submissionEdge struct {
+	from  SubmissionStatus
+	event Event
+	to    SubmissionStatus
+	guard func(Input) error
+}
+
+var submissionEdges = []submissionEdge{
+	{from: SubmissionPending, event: UploadFile, to: SubmissionUnderReview, guard: notLockedByStudent},
+	{from: SubmissionUnderReview, event: ReplaceFile, to: SubmissionUnderReview, guard: notLockedByStudent},
+	{from: SubmissionUnderReview, event: MarkValid, to: SubmissionValid},
+	{from: SubmissionUnderReview, event: MarkInvalid, to: SubmissionInvalid, guard: requireReason(MarkInvalid)},
+	{from: SubmissionValid, event: ReopenDocument, to: SubmissionPending},
+}
+
+// FireSubmission validates a document-submission transition and returns the target status.
+func FireSubmission(from SubmissionStatus, event Event, in Input) (SubmissionStatus, error) {
+	for _, edge := range submissionEdges {
+		if edge.from != from || edge.event != event {
+			continue
+		}
+		if edge.guard != nil {
+			if err := edge.guard(in); err != nil {
+				return "", err
+			}
+		}
+		return edge.to, nil
+	}
+	return "", &TransitionError{Machine: "submission", From: string(from), Event: event}
+}
@@ -0,0 +1,326 @@
+package workflow
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var allStudentStatuses = []StudentSessionStatus{NotStarted, WaitingForDocuments, WaitingForValidation, Validated, Invalidated}
+
+var allSubmissionStatuses = []SubmissionStatus{SubmissionPending, SubmissionUnderReview, SubmissionValid, SubmissionInvalid}
+
+type studentEdgeCase struct {
+	name    string
+	from    StudentSessionStatus
+	event   Event
+	input   Input
+	to      StudentSessionStatus
+	effects Effects
+}
+
+func TestFireDocumentedEdges(t *testing.T) {
+	tests := []studentEdgeCase{
+		{
+			name:    "questionnaire completed",
+			from:    NotStarted,
+			event:   CompleteQuestionnaire,
+			to:      WaitingForDocuments,
+			effects: Effects{StampQuestionnaire: true, TimelineEvent: TimelineQuestionnaireCompleted},
+		},
+		{
+			name:  "upload while waiting for documents",
+			from:  WaitingForDocuments,
+			event: UploadDocument,
+			to:    WaitingForDocuments,
+		},
+		{
+			name:  "upload after invalidation",
+			from:  Invalidated,
+			event: UploadDocument,
+			to:    Invalidated,
+		},
+		{
+			name:    "first submission",
+			from:    WaitingForDocuments,
+			event:   SubmitDocuments,
+			to:      WaitingForValidation,
+			effects: Effects{LockedByStudent: true, StampSubmitted: true, TimelineEvent: TimelineFilesSubmitted},
+		},
+		{
+			name:    "resubmission after invalidation",
+			from:    Invalidated,
+			event:   SubmitDocuments,
+			to:      WaitingForValidation,
+			effects: Effects{LockedByStudent: true, StampSubmitted: true, TimelineEvent: TimelineFilesSubmitted},
+		},
+		{
+			name:    "admin validates",
+			from:    WaitingForValidation,
+			event:   ValidateSession,
+			to:      Validated,
+			effects: Effects{StampReviewed: true, ClearInvalidationReason: true, TimelineEvent: TimelineSessionValidated},
+		},
+		{
+			name:  "admin invalidates",
+			from:  WaitingForValidation,
+			event: InvalidateSession,
+			input: Input{InvalidDocuments: 2, Reason: "2 document(s) were rejected"},
+			to:    Invalidated,
+			effects: Effects{
+				LockedByAdmin:      true,
+				NewRevision:        true,
+				StampReviewed:      true,
+				InvalidationReason: "2 document(s) were rejected",
+				TimelineEvent:      TimelineSessionInvalidated,
+			},
+		},
+		{
+			name:    "admin reopens",
+			from:    Validated,
+			event:   ReopenSession,
+			input:   Input{Reason: "wrong diploma"},
+			to:      WaitingForDocuments,
+			effects: Effects{NewRevision: true, ClearInvalidationReason: true, TimelineEvent: TimelineSessionReopened},
+		},
+		{
+			name:    "documents generated",
+			from:    Validated,
+			event:   GenerateDocuments,
+			to:      Validated,
+			effects: Effects{TimelineEvent: TimelineGeneratedDocumentCreated},
+		},
+	}
+	expired := Effects{LockedByStudent: true, LockedByAdmin: true, InvalidationReason: ExpiredReason, TimelineEvent: TimelineDeadlineExpired}
+	for _, from := range []StudentSessionStatus{NotStarted, WaitingForDocuments, WaitingForValidation} {
+		tests = append(tests, studentEdgeCase{name: "expire from " + string(from), from: from, event: ExpireSession, to: Invalidated, effects: expired})
+	}
+	resynced := Effects{NewRevision: true, ClearInvalidationReason: true, TimelineEvent: TimelineRequirementsChanged}
+	for _, from := range []StudentSessionStatus{WaitingForDocuments, WaitingForValidation, Validated} {
+		tests = append(tests, studentEdgeCase{name: "requirements changed while " + string(from), from: from, event: ChangeRequirements, to: WaitingForDocuments, effects: resynced})
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			outcome, err := Fire(tc.from, tc.event, tc.input)
+			if err != nil {
+				t.Fatalf("Fire: %v", err)
+			}
+			if outcome.From != tc.from || outcome.To != tc.to || outcome.Event != tc.event {
+				t.Fatalf("outcome = %s -%s-> %s, want %s -%s-> %s", outcome.From, outcome.Event, outcome.To, tc.from, tc.event, tc.to)
+			}
+			if outcome.Effects != tc.effects {
+				t.Fatalf("effects = %+v, want %+v", outcome.Effects, tc.effects)
+			}
+		})
+	}
+}
+
+func TestFireRejectsUndocumentedMoves(t *testing.T) {
+	allowed := map[Event][]StudentSessionStatus{
+		CompleteQuestionnaire: {NotStarted},
+		UploadDocument:        {WaitingForDocuments, Invalidated},
+		SubmitDocuments:       {WaitingForDocuments, Invalidated},
+		ValidateSession:       {WaitingForValidation},
+		InvalidateSession:     {WaitingForValidation},
+		ReopenSession:         {Validated},
+		ExpireSession:         {NotStarted, WaitingForDocuments, WaitingForValidation},
+		ChangeRequirements:    {WaitingForDocuments, WaitingForValidation, Validated},
+		GenerateDocuments:     {Validated},
+	}
+	// Inputs that satisfy every guard, so only the edge table decides.
+	permissive := map[Event]Input{
+		InvalidateSession: {InvalidDocuments: 1, Reason: "rejected"},
+		ReopenSession:     {Reason: "reopened"},
+	}
+
+	for event, sources := range allowed {
+		if got := Sources(event); !reflect.DeepEqual(got, sources) {
+			t.Errorf("Sources(%s) = %v, want %v", event, got, sources)
+		}
+		for _, from := range allStudentStatuses {
+			_, err := Fire(from, event, permissive[event])
+			legal := false
+			for _, source := range sources {
+				legal = legal || source == from
+			}
+			switch {
+			case legal && err != nil:
+				t.Errorf("%s from %s: unexpected error %v", event, from, err)
+			case !legal:
+				var transitionErr *TransitionError
+				if !errors.As(err, &transitionErr) || !errors.Is(err, ErrIllegalTransition) {
+					t.Errorf("%s from %s: error = %v, want *TransitionError", event, from, err)
+				}
+			}
+		}
+	}
+}
+
+func TestFireGuards(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        StudentSessionStatus
+		event       Event
+		input       Input
+		wantMissing []string
+	}{
+		{name: "upload while locked by student", from: WaitingForDocuments, event: UploadDocument, input: Input{LockedByStudent: true}},
+		{name: "submit twice", from: Invalidated, event: SubmitDocuments, input: Input{LockedByStudent: true}},
+		{
+			name:        "submit with missing documents",
+			from:        WaitingForDocuments,
+			event:       SubmitDocuments,
+			input:       Input{MissingRequirements: []string{"id_card", "diploma"}},
+			wantMissing: []string{"id_card", "diploma"},
+		},
+		{name: "validate with invalid documents", from: WaitingForValidation, event: ValidateSession, input: Input{InvalidDocuments: 1}},
+		{name: "invalidate without invalid documents", from: WaitingForValidation, event: InvalidateSession},
+		{name: "reopen without reason", from: Validated, event: ReopenSession, input: Input{Reason: "   "}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Fire(tc.from, tc.event, tc.input)
+			if err == nil {
+				t.Fatal("expected guard error")
+			}
+			if tc.wantMissing != nil {
+				var missing *MissingRequirementsError
+				if !errors.As(err, &missing) {
+					t.Fatalf("error = %v, want *MissingRequirementsError", err)
+				}
+				if !reflect.DeepEqual(missing.Codes, tc.wantMissing) {
+					t.Fatalf("missing = %v, want %v", missing.Codes, tc.wantMissing)
+				}
+				if errors.Is(err, ErrIllegalTransition) {
+					t.Fatal("missing requirements must not be reported as an illegal transition")
+				}
+				return
+			}
+			var guardErr *GuardError
+			if !errors.As(err, &guardErr) || guardErr.Event != tc.event {
+				t.Fatalf("error = %v, want *GuardError for %s", err, tc.event)
+			}
+			if !errors.Is(err, ErrIllegalTransition) {
+				t.Fatal("guard errors must match ErrIllegalTransition")
+			}
+		})
+	}
+}
+
+func TestFireSubmission(t *testing.T) {
+	edges := []struct {
+		from  SubmissionStatus
+		event Event
+		input Input
+		to    SubmissionStatus
+	}{
+		{from: SubmissionPending, event: UploadFile, to: SubmissionUnderReview},
+		{from: SubmissionUnderReview, event: ReplaceFile, to: SubmissionUnderReview},
+		{from: SubmissionUnderReview, event: MarkValid, to: SubmissionValid},
+		{from: SubmissionUnderReview, event: MarkInvalid, input: Input{Reason: "blurry scan"}, to: SubmissionInvalid},
+		{from: SubmissionValid, event: ReopenDocument, to: SubmissionPending},
+	}
+
+	for _, edge := range edges {
+		for _, from := range allSubmissionStatuses {
+			name := string(edge.event) + " from " + string(from)
+			t.Run(name, func(t *testing.T) {
+				to, err := FireSubmission(from, edge.event, edge.input)
+				if from == edge.from {
+					if err != nil {
+						t.Fatalf("FireSubmission: %v", err)
+					}
+					if to != edge.to {
+						t.Fatalf("to = %s, want %s", to, edge.to)
+					}
+					return
+				}
+				var transitionErr *TransitionError
+				if !errors.As(err, &transitionErr) || !errors.Is(err, ErrIllegalTransition) {
+					t.Fatalf("error = %v, want *TransitionError", err)
+				}
+			})
+		}
+	}
+}
+
+func TestFireSubmissionGuards(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  SubmissionStatus
+		event Event
+		input Input
+	}{
+		{name: "upload while locked", from: SubmissionPending, event: UploadFile, input: Input{LockedByStudent: true}},
+		{name: "replace while locked", from: SubmissionUnderReview, event: ReplaceFile, input: Input{LockedByStudent: true}},
+		{name: "invalid without reason", from: SubmissionUnderReview, event: MarkInvalid},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := FireSubmission(tc.from, tc.event, tc.input)
+			var guardErr *GuardError
+			if !errors.As(err, &guardErr) || !errors.Is(err, ErrIllegalTransition) {
+				t.Fatalf("error = %v, want *GuardError", err)
+			}
+		})
+	}
+}
+
+func TestMoveSession(t *testing.T) {
+	tests := []struct {
+		from    AdmSessionStatus
+		to      AdmSessionStatus
+		effects SessionEffects
+		illegal bool
+	}{
+		{from: SessionDraft, to: SessionDraft},
+		{from: SessionDraft, to: SessionActive, effects: SessionEffects{StampPublished: true}},
+		{from: SessionDraft, to: SessionClosed, effects: SessionEffects{StampClosed: true}},
+		{from: SessionActive, to: SessionActive},
+		{from: SessionActive, to: SessionClosed, effects: SessionEffects{StampClosed: true}},
+		{from: SessionActive, to: SessionDraft, illegal: true},
+		{from: SessionClosed, to: SessionClosed, illegal: true},
+		{from: SessionClosed, to: SessionActive, illegal: true},
+		{from: SessionClosed, to: SessionDraft, illegal: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.from)+" to "+string(tc.to), func(t *testing.T) {
+			effects, err := MoveSession(tc.from, tc.to)
+			if tc.illegal {
+				if !errors.Is(err, ErrIllegalTransition) {
+					t.Fatalf("error = %v, want ErrIllegalTransition", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MoveSession: %v", err)
+			}
+			if effects != tc.effects {
+				t.Fatalf("effects = %+v, want %+v", effects, tc.effects)
+			}
+		})
+	}
+}
+
+func TestSessionSources(t *testing.T) {
+	tests := map[AdmSessionStatus][]AdmSessionStatus{
+		SessionDraft:  nil,
+		SessionActive: {SessionDraft},
+		SessionClosed: {SessionActive, SessionDraft},
+	}
+	for to, want := range tests {
+		got := SessionSources(to)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sources of %s = %v, want %v", to, got, want)
+		}
+		for _, from := range got {
+			if _, err := MoveSession(from, to); err != nil {
+				t.Errorf("MoveSession(%s, %s): %v", from, to, err)
+			}
+		}
+	}
+}